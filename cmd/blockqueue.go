@@ -0,0 +1,341 @@
+package cmd
+
+import (
+	"fmt"
+	htmlpkg "html"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// fioSchedulers are the blk-mq I/O schedulers tried by --fio-scheduler-sweep, in order
+var fioSchedulers = []string{"none", "mq-deadline", "kyber", "bfq"}
+
+// BlockQueueSettings holds the block-layer queue settings of one device from /sys/block/<dev>/queue
+type BlockQueueSettings struct {
+	Device              string   // Kernel name (e.g., nvme0n1, dm-0)
+	Scheduler           string   // Active I/O scheduler
+	AvailableSchedulers []string // Schedulers the kernel offers for this queue
+	NrRequests          int      // Requests per hardware queue, -1 if unknown
+	ReadAheadKB         int      // Read-ahead window, -1 if unknown
+	MaxSectorsKB        int      // Largest request the block layer issues, -1 if unknown
+	Rotational          bool     // Whether the kernel treats the device as a spinning disk
+	WriteCache          string   // "write back" or "write through"
+	NoMerges            int      // 0 = merging enabled, 1 = only simple merges, 2 = no merges; -1 if unknown
+	DiscardGranularity  int64    // Smallest range the device discards, in bytes; -1 if unknown
+	DiscardMaxBytes     int64    // Largest discard the kernel issues in one request, 0 if discard is unsupported; -1 if unknown
+}
+
+// FioSchedulerSweep holds one scenario run under each available I/O scheduler of a target
+type FioSchedulerSweep struct {
+	TestName     string               // Scenario that was repeated
+	Devices      []string             // Disks whose scheduler was switched
+	Original     []string             // Scheduler of each device before the sweep
+	OriginalNr   []int                // nr_requests of each device before the sweep; switching schedulers resets it
+	Results      []FioSchedulerResult // One per scheduler, in fioSchedulers order
+	Best         string               // Scheduler with the highest IOPS
+	Restored     bool                 // Whether every device got its original scheduler and nr_requests back
+	ErrorMessage string               // Error message if switching or restoring failed
+}
+
+// FioSchedulerResult is the scenario result under one scheduler
+type FioSchedulerResult struct {
+	Scheduler string
+	Result    FioTestResult
+}
+
+// blockQueueDir returns the queue directory of a device; partitions use their disk's queue
+func blockQueueDir(topology *storageTopology, name string) (string, string) {
+	if dev, ok := topology.devices[name]; ok && dev.kind == "partition" {
+		name = dev.parent
+	}
+	return name, filepath.Join("/sys/class/block", name, "queue")
+}
+
+// readBlockQueueSettings reads the queue settings of a device
+func readBlockQueueSettings(topology *storageTopology, name string) (BlockQueueSettings, bool) {
+	name, dir := blockQueueDir(topology, name)
+	settings := BlockQueueSettings{Device: name, NrRequests: -1, ReadAheadKB: -1, MaxSectorsKB: -1, NoMerges: -1, DiscardGranularity: -1, DiscardMaxBytes: -1}
+	if !fileExists(filepath.Join(dir, "nr_requests")) {
+		return settings, false
+	}
+	settings.Scheduler, settings.AvailableSchedulers = parseBlockScheduler(readSysfsValue(filepath.Join(dir, "scheduler")))
+	for file, value := range map[string]*int{
+		"nr_requests":    &settings.NrRequests,
+		"read_ahead_kb":  &settings.ReadAheadKB,
+		"max_sectors_kb": &settings.MaxSectorsKB,
+		"nomerges":       &settings.NoMerges,
+	} {
+		if n, err := strconv.Atoi(readSysfsValue(filepath.Join(dir, file))); err == nil {
+			*value = n
+		}
+	}
+	for file, value := range map[string]*int64{
+		"discard_granularity": &settings.DiscardGranularity,
+		"discard_max_bytes":   &settings.DiscardMaxBytes,
+	} {
+		if n, err := strconv.ParseInt(readSysfsValue(filepath.Join(dir, file)), 10, 64); err == nil {
+			*value = n
+		}
+	}
+	settings.Rotational = readSysfsValue(filepath.Join(dir, "rotational")) == "1"
+	settings.WriteCache = readSysfsValue(filepath.Join(dir, "write_cache"))
+	return settings, true
+}
+
+// parseBlockScheduler parses a scheduler file such as "[mq-deadline] kyber bfq none"
+func parseBlockScheduler(value string) (string, []string) {
+	var active string
+	var available []string
+	for _, field := range strings.Fields(value) {
+		if strings.HasPrefix(field, "[") && strings.HasSuffix(field, "]") {
+			field = strings.Trim(field, "[]")
+			active = field
+		}
+		available = append(available, field)
+	}
+	return active, available
+}
+
+// targetQueueSettings reads the queue settings of every layer under a target, from the device that
+// holds the filesystem (dm, md or disk) down to the physical disks
+func targetQueueSettings(topology *storageTopology, mountPoint, devicePath string) []BlockQueueSettings {
+	var top []string
+	if mountPoint == "direct" || mountPoint == "raw" {
+		if name := topology.deviceName(devicePath); name != "" {
+			top = []string{name}
+		}
+	} else if m, ok := topology.mountFor(mountPoint); ok {
+		top = topology.mountDevices(m)
+	}
+
+	var results []BlockQueueSettings
+	seen := make(map[string]bool)
+	var walk func(string)
+	walk = func(name string) {
+		dev, ok := topology.devices[name]
+		if !ok {
+			return
+		}
+		if settings, ok := readBlockQueueSettings(topology, name); ok && !seen[settings.Device] {
+			seen[settings.Device] = true
+			results = append(results, settings)
+		}
+		for _, slave := range dev.slaves {
+			walk(slave)
+		}
+	}
+	for _, name := range top {
+		walk(name)
+	}
+	return results
+}
+
+// findFioScenario looks up a scenario by name among the built-in and custom scenarios
+func findFioScenario(name string, custom []fioScenario) (fioScenario, bool) {
+	for _, scenario := range append(append([]fioScenario{}, defaultFioScenarios...), custom...) {
+		if scenario.name == name {
+			return scenario, true
+		}
+	}
+	return fioScenario{}, false
+}
+
+// runFioSchedulerSweep reruns a scenario under each scheduler available on all the disks of a target,
+// then puts the original schedulers back
+func runFioSchedulerSweep(topology *storageTopology, scenario fioScenario, engine fioEngine, disks []string, testFilePath, testSize string, readOnly bool, targetArgs []string) (sweep FioSchedulerSweep) {
+	sweep = FioSchedulerSweep{TestName: scenario.name, Devices: disks}
+
+	// Only schedulers every disk offers can be compared
+	offered := make(map[string]int)
+	for _, disk := range disks {
+		settings, ok := readBlockQueueSettings(topology, disk)
+		if !ok || settings.Scheduler == "" {
+			sweep.ErrorMessage = fmt.Sprintf("%s has no I/O scheduler setting", disk)
+			return sweep
+		}
+		sweep.Original = append(sweep.Original, settings.Scheduler)
+		sweep.OriginalNr = append(sweep.OriginalNr, settings.NrRequests)
+		for _, scheduler := range settings.AvailableSchedulers {
+			offered[scheduler]++
+		}
+	}
+
+	// Restore the scheduler first: the kernel resets nr_requests to the new scheduler's default
+	defer func() {
+		sweep.Restored = true
+		for i, disk := range disks {
+			err := setBlockScheduler(disk, sweep.Original[i])
+			if err == nil && sweep.OriginalNr[i] > 0 {
+				err = setBlockNrRequests(disk, sweep.OriginalNr[i])
+			}
+			if err == nil {
+				if settings, _ := readBlockQueueSettings(topology, disk); settings.Scheduler != sweep.Original[i] || settings.NrRequests != sweep.OriginalNr[i] {
+					err = fmt.Errorf("queue reads back scheduler %s, nr_requests %d", settings.Scheduler, settings.NrRequests)
+				}
+			}
+			if err != nil {
+				sweep.Restored = false
+				sweep.ErrorMessage = strings.TrimPrefix(sweep.ErrorMessage+"; restoring "+disk+": "+err.Error(), "; ")
+				fmt.Printf("        %sCould not restore scheduler %s, nr_requests %d on %s: %v%s\n", colorRed, sweep.Original[i], sweep.OriginalNr[i], disk, err, colorReset)
+			}
+		}
+	}()
+
+	fmt.Printf("      Scheduler sweep on %s with %s (engine=%s)\n", strings.Join(disks, ", "), scenario.name, engine.label)
+	var bestIOPS float64
+	for _, scheduler := range fioSchedulers {
+		if offered[scheduler] != len(disks) {
+			continue
+		}
+		for _, disk := range disks {
+			if err := setBlockScheduler(disk, scheduler); err != nil {
+				sweep.ErrorMessage = fmt.Sprintf("setting %s on %s: %v", scheduler, disk, err)
+				return sweep
+			}
+		}
+
+		result := newFioTestResult(scenario, engine)
+		job, err := runFioJob(fioScenarioArgs(scenario, engine, testFilePath, testSize, readOnly, targetArgs))
+		if err != nil {
+			result.IOPS, result.BandwidthMB, result.LatencyUs = -1, -1, -1
+			fmt.Printf("        %-12s FAIL: %v\n", scheduler, err)
+		} else {
+			setFioJobResult(&result, job)
+			fmt.Printf("        %-12s %10.0f IOPS %10.2f MB/s  p99 %s\n", scheduler, result.IOPS, result.BandwidthMB, formatLatencyNs(fioCompletionP99Ns(result)))
+			if result.IOPS > bestIOPS {
+				bestIOPS = result.IOPS
+				sweep.Best = scheduler
+			}
+		}
+		sweep.Results = append(sweep.Results, FioSchedulerResult{Scheduler: scheduler, Result: result})
+	}
+	return sweep
+}
+
+// setBlockScheduler switches the I/O scheduler of a disk
+func setBlockScheduler(disk, scheduler string) error {
+	return os.WriteFile(filepath.Join("/sys/class/block", disk, "queue/scheduler"), []byte(scheduler), 0)
+}
+
+// setBlockNrRequests sets the number of requests per hardware queue of a disk
+func setBlockNrRequests(disk string, nrRequests int) error {
+	return os.WriteFile(filepath.Join("/sys/class/block", disk, "queue/nr_requests"), []byte(strconv.Itoa(nrRequests)), 0)
+}
+
+// schedulerSweepOriginal lists the settings the sweep restores, e.g. "nvme0n1: none, nr_requests 1023"
+func schedulerSweepOriginal(sweep FioSchedulerSweep) string {
+	var original []string
+	for i, disk := range sweep.Devices {
+		if i < len(sweep.Original) && i < len(sweep.OriginalNr) {
+			original = append(original, fmt.Sprintf("%s: %s, nr_requests %d", disk, sweep.Original[i], sweep.OriginalNr[i]))
+		}
+	}
+	return strings.Join(original, "; ")
+}
+
+// blockQueueSummary formats queue settings on one line
+func blockQueueSummary(settings BlockQueueSettings) string {
+	media := "SSD"
+	if settings.Rotational {
+		media = "rotational"
+	}
+	return fmt.Sprintf("scheduler %s, nr_requests %d, read_ahead_kb %d, max_sectors_kb %d, %s, write cache %s, nomerges %d, discard %s",
+		settings.Scheduler, settings.NrRequests, settings.ReadAheadKB, settings.MaxSectorsKB, media, settings.WriteCache, settings.NoMerges, blockQueueDiscard(settings))
+}
+
+// blockQueueDiscard describes the discard support of a queue, e.g. "granularity 4.0 KiB, max 2.0 GiB"
+func blockQueueDiscard(settings BlockQueueSettings) string {
+	switch {
+	case settings.DiscardMaxBytes < 0 || settings.DiscardGranularity < 0:
+		return "unknown"
+	case settings.DiscardMaxBytes == 0:
+		return "unsupported"
+	}
+	return fmt.Sprintf("granularity %s, max %s", humanReadableBytes(uint64(settings.DiscardGranularity)), humanReadableBytes(uint64(settings.DiscardMaxBytes)))
+}
+
+// printBlockQueue prints the queue settings and scheduler sweep of a device
+func printBlockQueue(indent string, device FioDeviceResult) {
+	if len(device.QueueSettings) > 0 {
+		fmt.Printf("%sBlock queue settings:\n", indent)
+		for _, settings := range device.QueueSettings {
+			fmt.Printf("%s  %-10s %s\n", indent, settings.Device, blockQueueSummary(settings))
+		}
+	}
+	sweep := device.SchedulerSweep
+	if len(sweep.Results) == 0 && sweep.ErrorMessage == "" {
+		return
+	}
+	fmt.Printf("%sScheduler sweep (%s on %s):\n", indent, sweep.TestName, strings.Join(sweep.Devices, ", "))
+	for _, result := range sweep.Results {
+		marker := ""
+		if result.Scheduler == sweep.Best {
+			marker = colorGreen + " <- best" + colorReset
+		}
+		if result.Result.IOPS < 0 {
+			fmt.Printf("%s  %-12s FAIL\n", indent, result.Scheduler)
+			continue
+		}
+		fmt.Printf("%s  %-12s %10.0f IOPS %10.2f MB/s  p99 %-10s%s\n", indent, result.Scheduler, result.Result.IOPS,
+			result.Result.BandwidthMB, formatLatencyNs(fioCompletionP99Ns(result.Result)), marker)
+	}
+	if !sweep.Restored {
+		fmt.Printf("%s  %sOriginal queue settings (%s) were NOT restored%s\n", indent, colorRed, schedulerSweepOriginal(sweep), colorReset)
+	}
+	if sweep.ErrorMessage != "" {
+		fmt.Printf("%s  Error: %s\n", indent, sweep.ErrorMessage)
+	}
+}
+
+// blockQueueHTML renders the queue settings and scheduler sweep of a device
+func blockQueueHTML(device FioDeviceResult) string {
+	html := ""
+	if len(device.QueueSettings) > 0 {
+		html += `
+        <h4>Block Queue Settings</h4>
+        <table>
+            <tr><th>Device</th><th>Scheduler</th><th>Available</th><th>nr_requests</th><th>read_ahead_kb</th><th>max_sectors_kb</th><th>Rotational</th><th>Write Cache</th><th>nomerges</th><th>Discard</th></tr>`
+		for _, s := range device.QueueSettings {
+			html += fmt.Sprintf(`
+            <tr><td>%s</td><td>%s</td><td>%s</td><td>%d</td><td>%d</td><td>%d</td><td>%t</td><td>%s</td><td>%d</td><td>%s</td></tr>`,
+				s.Device, s.Scheduler, strings.Join(s.AvailableSchedulers, ", "), s.NrRequests, s.ReadAheadKB, s.MaxSectorsKB, s.Rotational, s.WriteCache, s.NoMerges, blockQueueDiscard(s))
+		}
+		html += `
+        </table>`
+	}
+	sweep := device.SchedulerSweep
+	if len(sweep.Results) == 0 && sweep.ErrorMessage == "" {
+		return html
+	}
+	html += `
+        <h4>I/O Scheduler Sweep: ` + htmlpkg.EscapeString(sweep.TestName) + `</h4>
+        <table>
+            <tr><th>Scheduler</th><th>IOPS</th><th>Bandwidth (MB/s)</th><th>p99 Latency</th></tr>`
+	for _, result := range sweep.Results {
+		name := result.Scheduler
+		if name == sweep.Best {
+			name = `<span class="highlight">` + name + ` (best)</span>`
+		}
+		if result.Result.IOPS < 0 {
+			html += `
+            <tr><td>` + name + `</td><td colspan="3">FAIL</td></tr>`
+			continue
+		}
+		html += fmt.Sprintf(`
+            <tr><td>%s</td><td>%.0f</td><td>%.2f</td><td>%s</td></tr>`, name, result.Result.IOPS, result.Result.BandwidthMB,
+			formatLatencyNs(fioCompletionP99Ns(result.Result)))
+	}
+	html += `
+        </table>`
+	if !sweep.Restored {
+		html += `
+        <p class="warning">Original queue settings (` + schedulerSweepOriginal(sweep) + `) were not restored.</p>`
+	}
+	if sweep.ErrorMessage != "" {
+		html += `
+        <p class="warning">` + htmlpkg.EscapeString(sweep.ErrorMessage) + `</p>`
+	}
+	return html
+}
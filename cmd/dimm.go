@@ -0,0 +1,339 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// DIMMInfo describes one memory slot as reported by SMBIOS type 17 (dmidecode -t memory)
+type DIMMInfo struct {
+	Locator            string // Slot name (e.g., "DIMM_A1")
+	BankLocator        string // Bank/channel name reported by the firmware (e.g., "P0 CHANNEL A")
+	Channel            string // Channel key derived from the locators, used for balance checks
+	Populated          bool   // Whether a module is installed in this slot
+	SizeBytes          uint64 // Module size in bytes (0 if empty)
+	Size               string // Human-readable module size
+	Type               string // Memory type (e.g., "DDR4", "DDR5")
+	TypeDetail         string // Type detail (e.g., "Synchronous Registered (Buffered)")
+	RatedSpeedMTs      int    // Maximum speed the module is rated for, in MT/s
+	ConfiguredSpeedMTs int    // Speed the module is currently running at, in MT/s
+	Manufacturer       string // Module manufacturer
+	PartNumber         string // Module part number
+	Rank               int    // Number of ranks (0 if unknown)
+	ECC                bool   // Whether the module carries ECC bits (total width > data width)
+}
+
+// dmiSpeedRegex matches speeds such as "3200 MT/s" or "2933 MHz"
+var dmiSpeedRegex = regexp.MustCompile(`^(\d+)\s*(MT/s|MHz)`)
+
+// dmiChannelRegex extracts a channel name from bank locators such as "P0 CHANNEL A" or "ChannelB"
+var dmiChannelRegex = regexp.MustCompile(`(?i)channel\s*([A-Z0-9]+)`)
+
+// gatherDIMMInventory fills the per-slot DIMM list, RAM type/speed and memory configuration warnings
+func gatherDIMMInventory(sysInfo *SystemInfo) {
+	output, err := runCommand("dmidecode", "-t", "memory")
+	dimms, eccType := parseDmidecodeMemory(output)
+	if err != nil || len(dimms) == 0 {
+		// Some firmware only answers for the raw type number
+		output, err = runCommand("dmidecode", "-t", "17")
+		if err != nil {
+			fmt.Printf("    Warning: could not run dmidecode -t memory: %v\n", err)
+			sysInfo.RAMType = "N/A (dmidecode failed)"
+			sysInfo.RAMSpeed = "N/A (dmidecode failed)"
+			return
+		}
+		dimms, _ = parseDmidecodeMemory(output)
+	}
+
+	sysInfo.DIMMs = dimms
+	sysInfo.MemoryECCType = eccType
+	sysInfo.RAMType, sysInfo.RAMSpeed = summarizeDIMMs(dimms)
+	sysInfo.MemoryWarnings = checkDIMMConfiguration(dimms)
+}
+
+// parseDmidecodeMemory parses "dmidecode -t memory" output into DIMM slots and the array's ECC type
+func parseDmidecodeMemory(output string) ([]DIMMInfo, string) {
+	var dimms []DIMMInfo
+	eccType := ""
+
+	var current map[string]string
+	var currentType string
+	flush := func() {
+		if current == nil {
+			return
+		}
+		switch currentType {
+		case "16":
+			if v := current["Error Correction Type"]; v != "" && eccType == "" {
+				eccType = v
+			}
+		case "17":
+			dimms = append(dimms, dimmFromFields(current))
+		}
+		current = nil
+	}
+
+	handleRegex := regexp.MustCompile(`^Handle 0x[0-9A-Fa-f]+, DMI type (\d+)`)
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if matches := handleRegex.FindStringSubmatch(line); len(matches) > 1 {
+			flush()
+			current = make(map[string]string)
+			currentType = matches[1]
+			continue
+		}
+		if current == nil || !strings.HasPrefix(line, "\t") || strings.HasPrefix(line, "\t\t") {
+			continue
+		}
+		key, value, found := strings.Cut(strings.TrimSpace(line), ":")
+		if found {
+			current[strings.TrimSpace(key)] = strings.TrimSpace(value)
+		}
+	}
+	flush()
+
+	return dimms, eccType
+}
+
+// dimmFromFields converts the key/value pairs of one type 17 record into a DIMMInfo
+func dimmFromFields(fields map[string]string) DIMMInfo {
+	dimm := DIMMInfo{
+		Locator:      fields["Locator"],
+		BankLocator:  fields["Bank Locator"],
+		Type:         fields["Type"],
+		TypeDetail:   fields["Type Detail"],
+		Manufacturer: fields["Manufacturer"],
+		PartNumber:   fields["Part Number"],
+	}
+
+	sizeStr := fields["Size"]
+	if sizeStr != "" && !strings.Contains(sizeStr, "No Module") && !strings.EqualFold(sizeStr, "Unknown") {
+		if size, err := parseSizeBytes(strings.ReplaceAll(sizeStr, " ", "")); err == nil && size > 0 {
+			dimm.Populated = true
+			dimm.SizeBytes = size
+			dimm.Size = humanReadableBytes(size)
+		}
+	}
+
+	dimm.RatedSpeedMTs = parseDmiSpeed(fields["Speed"])
+	// dmidecode renamed this field in 3.2
+	configured := fields["Configured Memory Speed"]
+	if configured == "" {
+		configured = fields["Configured Clock Speed"]
+	}
+	dimm.ConfiguredSpeedMTs = parseDmiSpeed(configured)
+
+	if rank, err := strconv.Atoi(fields["Rank"]); err == nil {
+		dimm.Rank = rank
+	}
+
+	totalWidth, totalErr := parseIntStrict(fields["Total Width"])
+	dataWidth, dataErr := parseIntStrict(fields["Data Width"])
+	if totalErr == nil && dataErr == nil && totalWidth > dataWidth {
+		dimm.ECC = true
+	}
+
+	dimm.Channel = dimmChannelKey(dimm.Locator, dimm.BankLocator)
+
+	if !dimm.Populated {
+		// Empty slots report placeholders such as "Unknown" or "NO DIMM"
+		dimm.Type = ""
+		dimm.Manufacturer = ""
+		dimm.PartNumber = ""
+	}
+	return dimm
+}
+
+// parseDmiSpeed extracts the MT/s value from a dmidecode speed string (0 if unknown)
+func parseDmiSpeed(s string) int {
+	matches := dmiSpeedRegex.FindStringSubmatch(strings.TrimSpace(s))
+	if len(matches) < 2 {
+		return 0
+	}
+	speed, _ := strconv.Atoi(matches[1])
+	return speed
+}
+
+// dimmChannelKey derives a channel identifier from the slot locators.
+// Vendors encode it differently, so prefer an explicit "Channel X" in the bank locator and
+// otherwise strip the slot number from the locator (e.g., "CPU1_DIMM_A2" -> "CPU1_DIMM_A").
+func dimmChannelKey(locator, bankLocator string) string {
+	if matches := dmiChannelRegex.FindStringSubmatch(bankLocator); len(matches) > 1 {
+		// Keep the socket/node part of the bank locator so channels on different CPUs stay distinct
+		prefix := strings.TrimSpace(bankLocator[:strings.Index(strings.ToLower(bankLocator), "channel")])
+		return strings.TrimSpace(prefix + " Channel " + strings.ToUpper(matches[1]))
+	}
+	trimmed := strings.TrimRight(locator, "0123456789")
+	trimmed = strings.TrimRight(trimmed, "_- ")
+	if trimmed == "" {
+		return locator
+	}
+	return trimmed
+}
+
+// summarizeDIMMs produces the RAMType and RAMSpeed strings shown in the system information
+func summarizeDIMMs(dimms []DIMMInfo) (string, string) {
+	types := make(map[string]bool)
+	var typeNames []string
+	registered, ecc := false, false
+	minConfigured, maxRated := 0, 0
+	populated := 0
+
+	for _, d := range dimms {
+		if !d.Populated {
+			continue
+		}
+		populated++
+		if d.Type != "" && !types[d.Type] {
+			types[d.Type] = true
+			typeNames = append(typeNames, d.Type)
+		}
+		if strings.Contains(d.TypeDetail, "Registered") {
+			registered = true
+		}
+		if d.ECC {
+			ecc = true
+		}
+		if d.ConfiguredSpeedMTs > 0 && (minConfigured == 0 || d.ConfiguredSpeedMTs < minConfigured) {
+			minConfigured = d.ConfiguredSpeedMTs
+		}
+		if d.RatedSpeedMTs > maxRated {
+			maxRated = d.RatedSpeedMTs
+		}
+	}
+
+	if populated == 0 {
+		return "N/A (no DIMMs reported by dmidecode)", "N/A (no DIMMs reported by dmidecode)"
+	}
+
+	ramType := strings.Join(typeNames, "/")
+	if ramType == "" {
+		ramType = "Unknown"
+	}
+	var attrs []string
+	if registered {
+		attrs = append(attrs, "Registered")
+	}
+	if ecc {
+		attrs = append(attrs, "ECC")
+	}
+	if len(attrs) > 0 {
+		ramType += " (" + strings.Join(attrs, ", ") + ")"
+	}
+
+	ramSpeed := "Unknown"
+	switch {
+	case minConfigured > 0 && maxRated > minConfigured:
+		ramSpeed = fmt.Sprintf("%d MT/s (rated %d MT/s)", minConfigured, maxRated)
+	case minConfigured > 0:
+		ramSpeed = fmt.Sprintf("%d MT/s", minConfigured)
+	case maxRated > 0:
+		ramSpeed = fmt.Sprintf("%d MT/s (rated)", maxRated)
+	}
+
+	return ramType, ramSpeed
+}
+
+// checkDIMMConfiguration flags the memory misconfigurations that typically explain low bandwidth:
+// empty or unevenly populated channels, mixed modules, and modules running below their rated speed.
+func checkDIMMConfiguration(dimms []DIMMInfo) []string {
+	var warnings []string
+
+	channelCapacity := make(map[string]uint64)
+	var channels []string
+	var populated []DIMMInfo
+	for _, d := range dimms {
+		if _, seen := channelCapacity[d.Channel]; !seen {
+			channels = append(channels, d.Channel)
+			channelCapacity[d.Channel] = 0
+		}
+		if d.Populated {
+			channelCapacity[d.Channel] += d.SizeBytes
+			populated = append(populated, d)
+		}
+	}
+	if len(populated) == 0 {
+		return warnings
+	}
+	sort.Strings(channels)
+
+	// Channel balance
+	var emptyChannels []string
+	capacities := make(map[uint64][]string)
+	for _, ch := range channels {
+		if channelCapacity[ch] == 0 {
+			emptyChannels = append(emptyChannels, ch)
+		} else {
+			capacities[channelCapacity[ch]] = append(capacities[channelCapacity[ch]], ch)
+		}
+	}
+	if len(emptyChannels) > 0 && len(channels) > 1 {
+		warnings = append(warnings, fmt.Sprintf("Unpopulated memory channels: %s (%d of %d channels empty, bandwidth is reduced)",
+			strings.Join(emptyChannels, ", "), len(emptyChannels), len(channels)))
+	}
+	if len(capacities) > 1 {
+		var parts []string
+		for capacity, chs := range capacities {
+			parts = append(parts, fmt.Sprintf("%s on %s", humanReadableBytes(capacity), strings.Join(chs, ", ")))
+		}
+		sort.Strings(parts)
+		warnings = append(warnings, "Mismatched channel capacities: "+strings.Join(parts, "; "))
+	}
+
+	// Mixed modules
+	sizes := make(map[string]bool)
+	parts := make(map[string]bool)
+	ranks := make(map[int]bool)
+	for _, d := range populated {
+		sizes[d.Size] = true
+		if d.PartNumber != "" {
+			parts[d.PartNumber] = true
+		}
+		if d.Rank > 0 {
+			ranks[d.Rank] = true
+		}
+	}
+	if len(sizes) > 1 {
+		warnings = append(warnings, fmt.Sprintf("Mixed DIMM sizes installed: %s", strings.Join(sortedKeys(sizes), ", ")))
+	}
+	if len(parts) > 1 {
+		warnings = append(warnings, fmt.Sprintf("Mixed DIMM part numbers installed: %s", strings.Join(sortedKeys(parts), ", ")))
+	}
+	if len(ranks) > 1 {
+		warnings = append(warnings, "Mixed DIMM rank counts installed")
+	}
+
+	// Below rated speed, grouped so a fully populated board doesn't produce one line per slot
+	slowSlots := make(map[[2]int][]string)
+	var slowKeys [][2]int
+	for _, d := range populated {
+		if d.RatedSpeedMTs > 0 && d.ConfiguredSpeedMTs > 0 && d.ConfiguredSpeedMTs < d.RatedSpeedMTs {
+			key := [2]int{d.ConfiguredSpeedMTs, d.RatedSpeedMTs}
+			if _, seen := slowSlots[key]; !seen {
+				slowKeys = append(slowKeys, key)
+			}
+			slowSlots[key] = append(slowSlots[key], d.Locator)
+		}
+	}
+	for _, key := range slowKeys {
+		warnings = append(warnings, fmt.Sprintf("Memory running below rated speed: %s at %d MT/s (rated %d MT/s)",
+			strings.Join(slowSlots[key], ", "), key[0], key[1]))
+	}
+
+	return warnings
+}
+
+// sortedKeys returns the keys of a string set in sorted order
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for k := range set {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
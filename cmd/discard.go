@@ -0,0 +1,378 @@
+package cmd
+
+import (
+	"fmt"
+	htmlpkg "html"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// discardChunkBytes is the range of each discard request; every request is timed for the latency distribution
+const discardChunkBytes = 1 << 30
+
+// discardRecoveredPercent is how close to the fully written average the per-second IOPS after the
+// discard must fall before the drive counts as back to its written state
+const discardRecoveredPercent = 10
+
+// FioDiscardResult holds the discard test of a raw device (if --fio-discard)
+type FioDiscardResult struct {
+	Device           string          // Device that was discarded
+	GranularityBytes int64           // discard_granularity from /sys/block/<dev>/queue
+	MaxBytes         int64           // discard_max_bytes: largest discard the kernel issues in one request
+	MaxHWBytes       int64           // discard_max_hw_bytes: largest discard the device accepts
+	Bytes            uint64          // Bytes discarded by the first discard
+	ChunkBytes       uint64          // Range of each discard request
+	Seconds          float64         // Time to discard Bytes
+	ThroughputGBs    float64         // Bytes / Seconds in GiB/s
+	Latency          FioLatencyStats // Latency of the discard requests
+	Written          FioDiscardPhase // Writes, each on a freshly filled device
+	Discarded        FioDiscardPhase // The same writes, each right after a full discard
+	ErrorMessage     string          // Error message if the test failed
+}
+
+// FioDiscardPhase holds sequential and random write results with the device in one state
+type FioDiscardPhase struct {
+	SeqWrite      FioTestResult // 128K sequential writes, QD=32, one pass over the test area
+	RandWrite     FioTestResult // 4K random writes, QD=32, 4 jobs
+	SeqPerSecond  []float64     // Sequential write IOPS logged every second
+	RandPerSecond []float64     // Random write IOPS logged every second
+}
+
+// discardWriteScenarios are the writes measured before and after the discard. The sequential writes make
+// exactly one pass over the test area; the random writes run for runtime seconds.
+func discardWriteScenarios(runtime int) (seq, rand fioScenario) {
+	seq = fioScenario{name: "Discard_SeqWrite_128K_QD32", rw: "write", bs: "128k", iodepth: 32, numjobs: 1,
+		description: "128K Sequential Write (QD=32, 1 pass)", category: "discard",
+		extraArgs: []string{"--runtime=0"}} // No time limit: the pass ends when the whole area is written
+	rand = fioScenario{name: "Discard_RandWrite_4K_QD32", rw: "randwrite", bs: "4k", iodepth: 32, numjobs: 4,
+		description: "4K Random Write (QD=32, 4 jobs)", category: "discard", runtime: runtime,
+		extraArgs: []string{"--time_based", "--norandommap", "--randrepeat=0"}}
+	return seq, rand
+}
+
+// runFioDiscardTest measures sequential and random writes on the fully written device and on the freshly
+// discarded one. Each workload starts from its state: the device is refilled before each written workload
+// and discarded in timed chunks, like blkdiscard, before each discarded one. ALL DATA ON THE DEVICE IS LOST.
+func runFioDiscardTest(topology *storageTopology, devicePath string, sizeBytes uint64, testSize string, runtime int, engine fioEngine) FioDiscardResult {
+	result := FioDiscardResult{Device: devicePath, ChunkBytes: discardChunkBytes, GranularityBytes: -1, MaxBytes: -1, MaxHWBytes: -1}
+	name := topology.deviceName(devicePath)
+	if settings, ok := readBlockQueueSettings(topology, name); ok {
+		result.GranularityBytes, result.MaxBytes = settings.DiscardGranularity, settings.DiscardMaxBytes
+	}
+	_, queueDir := blockQueueDir(topology, name)
+	if n, err := strconv.ParseInt(readSysfsValue(filepath.Join(queueDir, "discard_max_hw_bytes")), 10, 64); err == nil {
+		result.MaxHWBytes = n
+	}
+	fmt.Printf("      Discard test on %s: granularity %s, max %s per request (device max %s)\n", devicePath,
+		discardBytesLabel(result.GranularityBytes), discardBytesLabel(result.MaxBytes), discardBytesLabel(result.MaxHWBytes))
+	if result.MaxBytes == 0 {
+		result.ErrorMessage = devicePath + " does not support discard"
+		fmt.Printf("        %s\n", result.ErrorMessage)
+		return result
+	}
+
+	logDir, err := os.MkdirTemp("", "hyprbench_discard_")
+	if err != nil {
+		result.ErrorMessage = fmt.Sprintf("could not create IOPS log directory: %v", err)
+		return result
+	}
+	defer os.RemoveAll(logDir)
+
+	seq, rand := discardWriteScenarios(runtime)
+	for _, run := range []struct {
+		state     string
+		scenario  fioScenario
+		result    *FioTestResult
+		perSecond *[]float64
+	}{
+		{"written", seq, &result.Written.SeqWrite, &result.Written.SeqPerSecond},
+		{"written", rand, &result.Written.RandWrite, &result.Written.RandPerSecond},
+		{"discarded", seq, &result.Discarded.SeqWrite, &result.Discarded.SeqPerSecond},
+		{"discarded", rand, &result.Discarded.RandWrite, &result.Discarded.RandPerSecond},
+	} {
+		if run.state == "written" {
+			if err := discardFill(devicePath, testSize, engine); err != nil {
+				result.ErrorMessage = fmt.Sprintf("sequential fill failed: %v", err)
+				fmt.Printf("        %s\n", result.ErrorMessage)
+				return result
+			}
+		} else if err := discardTimed(&result, devicePath, sizeBytes); err != nil {
+			fmt.Printf("        %s\n", result.ErrorMessage)
+			return result
+		}
+		*run.result, *run.perSecond = runDiscardWorkload(run.state, run.scenario, logDir, devicePath, testSize, engine)
+	}
+	return result
+}
+
+// discardFill brings the test area to the fully written state with one sequential pass
+func discardFill(devicePath, testSize string, engine fioEngine) error {
+	fmt.Printf("        Filling %s with sequential writes...\n", testSize)
+	start := time.Now()
+	fill := append([]string{
+		"--name=discard_fill",
+		"--filename=" + devicePath,
+		"--direct=1",
+		"--rw=write",
+		"--bs=128k",
+		"--iodepth=32",
+		"--size=" + testSize,
+		"--output-format=json",
+	}, fioEngineArgs(engine)...)
+	if _, err := runFioJob(fill); err != nil {
+		return err
+	}
+	fmt.Printf("        Fill finished in %s\n", time.Since(start).Round(time.Second))
+	return nil
+}
+
+// discardTimed discards the test area in chunks, timing each request. The first discard sets the
+// throughput and latency of the result; the later ones are printed only.
+func discardTimed(result *FioDiscardResult, devicePath string, sizeBytes uint64) error {
+	file, err := os.OpenFile(devicePath, os.O_WRONLY, 0)
+	if err != nil {
+		result.ErrorMessage = err.Error()
+		return err
+	}
+	defer file.Close()
+
+	var latency nativeLatencyHistogram
+	start := time.Now()
+	for offset := uint64(0); offset < sizeBytes; offset += discardChunkBytes {
+		length := min64(discardChunkBytes, sizeBytes-offset)
+		requestStart := time.Now()
+		if err = discardRange(file, offset, length); err != nil {
+			result.ErrorMessage = fmt.Sprintf("discard failed after %s: %v", humanReadableBytes(latency.bytes), err)
+			return err
+		}
+		latency.record(uint64(time.Since(requestStart).Nanoseconds()), int(length))
+	}
+	seconds := time.Since(start).Seconds()
+	var throughput float64
+	if seconds > 0 {
+		throughput = float64(latency.bytes) / seconds / (1024 * 1024 * 1024)
+	}
+	stats := latency.stats()
+	fmt.Printf("        Discarded %s in %.2fs (%.2f GiB/s), %d requests of %s: mean %s, p99 %s, max %s\n",
+		humanReadableBytes(latency.bytes), seconds, throughput, stats.Samples, humanReadableBytes(discardChunkBytes),
+		formatLatencyNs(stats.MeanNs), formatLatencyNs(stats.P99Ns), formatLatencyNs(stats.MaxNs))
+	if result.Bytes == 0 {
+		result.Bytes, result.Seconds, result.ThroughputGBs, result.Latency = latency.bytes, seconds, throughput, stats
+	}
+	return nil
+}
+
+// runDiscardWorkload runs one write workload, logging IOPS every second
+func runDiscardWorkload(state string, scenario fioScenario, logDir, devicePath, testSize string, engine fioEngine) (FioTestResult, []float64) {
+	result := newFioTestResult(scenario, engine)
+	logPrefix := filepath.Join(logDir, state+"_"+scenario.rw)
+	args := append(fioScenarioArgs(scenario, engine, devicePath, testSize, false, nil),
+		"--write_iops_log="+logPrefix, "--log_avg_msec=1000")
+	job, err := runFioJob(args)
+	if err != nil {
+		result.IOPS, result.BandwidthMB, result.LatencyUs = -1, -1, -1
+		fmt.Printf("        %-10s %-38s FAIL: %v\n", state, scenario.description, err)
+		return result, nil
+	}
+	setFioJobResult(&result, job)
+	fmt.Printf("        %-10s %-38s %10.0f IOPS %10.2f MB/s  p99 %s\n", state, scenario.description,
+		result.IOPS, result.BandwidthMB, formatLatencyNs(fioCompletionP99Ns(result)))
+	return result, readFioIOPSLogs(logPrefix)
+}
+
+// min64 returns the smaller of two uint64 values
+func min64(a, b uint64) uint64 {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// discardBytesLabel formats a discard limit from sysfs
+func discardBytesLabel(n int64) string {
+	if n < 0 {
+		return "unknown"
+	}
+	return humanReadableBytes(uint64(n))
+}
+
+// discardChange formats the discarded result relative to the written one, e.g. "+35%"
+func discardChange(written, discarded FioTestResult) string {
+	if written.IOPS <= 0 || discarded.IOPS < 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%+.0f%%", (discarded.IOPS/written.IOPS-1)*100)
+}
+
+// discardRecoverySeconds returns how many seconds after the discard the per-second IOPS first came
+// within discardRecoveredPercent of the written average, or -1 if they never did
+func discardRecoverySeconds(written FioTestResult, perSecond []float64) int {
+	if written.IOPS <= 0 {
+		return -1
+	}
+	for i, iops := range perSecond {
+		if math.Abs(iops-written.IOPS)/written.IOPS*100 <= discardRecoveredPercent {
+			return i
+		}
+	}
+	return -1
+}
+
+// discardRecoveryLabel describes when the writes after the discard settled at the written level
+func discardRecoveryLabel(written FioTestResult, perSecond []float64) string {
+	switch seconds := discardRecoverySeconds(written, perSecond); {
+	case len(perSecond) == 0:
+		return "-"
+	case seconds < 0:
+		return fmt.Sprintf("not within %d%% after %ds", discardRecoveredPercent, len(perSecond))
+	default:
+		return fmt.Sprintf("within %d%% after %ds", discardRecoveredPercent, seconds)
+	}
+}
+
+// discardRows returns the written and discarded results of each workload
+func discardRows(result FioDiscardResult) [][]string {
+	rows := [][]string{}
+	for _, pair := range []struct {
+		written, discarded FioTestResult
+		perSecond          []float64
+	}{
+		{result.Written.SeqWrite, result.Discarded.SeqWrite, result.Discarded.SeqPerSecond},
+		{result.Written.RandWrite, result.Discarded.RandWrite, result.Discarded.RandPerSecond},
+	} {
+		if pair.written.TestName == "" {
+			continue
+		}
+		rows = append(rows, []string{pair.written.Description, discardIOPSLabel(pair.written), discardIOPSLabel(pair.discarded),
+			discardChange(pair.written, pair.discarded), formatLatencyNs(fioCompletionP99Ns(pair.written)),
+			formatLatencyNs(fioCompletionP99Ns(pair.discarded)), discardRecoveryLabel(pair.written, pair.perSecond)})
+	}
+	return rows
+}
+
+// discardIOPSLabel formats the IOPS of a result, "FAIL" if it failed and "-" if it did not run
+func discardIOPSLabel(test FioTestResult) string {
+	switch {
+	case test.TestName == "":
+		return "-"
+	case test.IOPS < 0:
+		return "FAIL"
+	}
+	return fmt.Sprintf("%.0f", test.IOPS)
+}
+
+// printFioDiscard prints the discard throughput and latency and the writes before and after it
+func printFioDiscard(indent string, result FioDiscardResult) {
+	if result.Device == "" {
+		return
+	}
+	fmt.Printf("%sDiscard (%s, granularity %s, max %s per request):\n", indent, result.Device,
+		discardBytesLabel(result.GranularityBytes), discardBytesLabel(result.MaxBytes))
+	if result.Latency.Samples > 0 {
+		fmt.Printf("%s  Discarded %s in %.2fs (%.2f GiB/s); per %s request: mean %s, p50 %s, p99 %s, max %s\n", indent,
+			humanReadableBytes(result.Bytes), result.Seconds, result.ThroughputGBs, humanReadableBytes(result.ChunkBytes),
+			formatLatencyNs(result.Latency.MeanNs), formatLatencyNs(result.Latency.P50Ns), formatLatencyNs(result.Latency.P99Ns), formatLatencyNs(result.Latency.MaxNs))
+	}
+	if rows := discardRows(result); len(rows) > 0 {
+		fmt.Printf("%s  %-38s | %-10s | %-10s | %-7s | %-13s | %-13s | %s\n", indent, "Workload", "Written", "Discarded", "Change", "Written p99", "Discarded p99", "Back to written level")
+		for _, row := range rows {
+			fmt.Printf("%s  %-38s | %-10s | %-10s | %-7s | %-13s | %-13s | %s\n", indent, row[0], row[1], row[2], row[3], row[4], row[5], row[6])
+		}
+	}
+	if result.ErrorMessage != "" {
+		fmt.Printf("%s  Error: %s\n", indent, result.ErrorMessage)
+	}
+}
+
+// fioDiscardHTML renders the discard test with per-second IOPS charts of both write workloads
+func fioDiscardHTML(result FioDiscardResult) string {
+	if result.Device == "" {
+		return ""
+	}
+	html := `
+        <h4>Discard and Write Recovery</h4>
+        <p>` + fmt.Sprintf("Discard granularity %s, up to %s per request (device limit %s).", discardBytesLabel(result.GranularityBytes),
+		discardBytesLabel(result.MaxBytes), discardBytesLabel(result.MaxHWBytes)) + `</p>`
+	if result.Latency.Samples > 0 {
+		html += fmt.Sprintf(`
+        <table>
+            <tr><th>Discarded</th><th>Seconds</th><th>Throughput</th><th>Requests</th><th>Mean</th><th>p50</th><th>p99</th><th>Max</th></tr>
+            <tr><td>%s</td><td>%.2f</td><td class="highlight">%.2f GiB/s</td><td>%d x %s</td><td>%s</td><td>%s</td><td>%s</td><td>%s</td></tr>
+        </table>`, humanReadableBytes(result.Bytes), result.Seconds, result.ThroughputGBs, result.Latency.Samples, humanReadableBytes(result.ChunkBytes),
+			formatLatencyNs(result.Latency.MeanNs), formatLatencyNs(result.Latency.P50Ns), formatLatencyNs(result.Latency.P99Ns), formatLatencyNs(result.Latency.MaxNs))
+	}
+	if rows := discardRows(result); len(rows) > 0 {
+		html += `
+        <table>
+            <tr><th>Workload</th><th>Written IOPS</th><th>Discarded IOPS</th><th>Change</th><th>Written p99</th><th>Discarded p99</th><th>Back to Written Level</th></tr>`
+		for _, row := range rows {
+			html += `
+            <tr><td>` + strings.Join(row, `</td><td>`) + `</td></tr>`
+		}
+		html += `
+        </table>`
+	}
+	html += fioDiscardSVG("128K sequential write", result.Written.SeqPerSecond, result.Discarded.SeqPerSecond) +
+		fioDiscardSVG("4K random write", result.Written.RandPerSecond, result.Discarded.RandPerSecond)
+	if result.ErrorMessage != "" {
+		html += `
+        <p class="warning">` + htmlpkg.EscapeString(result.ErrorMessage) + `</p>`
+	}
+	return html
+}
+
+// fioDiscardSVG plots the per-second IOPS of one workload on the written and on the discarded device
+func fioDiscardSVG(workload string, written, discarded []float64) string {
+	const width, height = 760.0, 260.0
+	const left, right, top, bottom = 70.0, 20.0, 20.0, 40.0
+	plotW, plotH := width-left-right, height-top-bottom
+
+	maxIOPS, seconds := 0.0, max(len(written), len(discarded))
+	for _, v := range append(append([]float64{}, written...), discarded...) {
+		maxIOPS = math.Max(maxIOPS, v)
+	}
+	if maxIOPS <= 0 || seconds < 2 {
+		return ""
+	}
+	maxIOPS *= 1.1
+	x := func(second int) float64 { return left + float64(second)/float64(seconds-1)*plotW }
+	y := func(v float64) float64 { return top + plotH - v/maxIOPS*plotH }
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `
+        <svg width="%.0f" height="%.0f" xmlns="http://www.w3.org/2000/svg" style="font-family: Arial, sans-serif; font-size: 11px;">`, width, height+20)
+	for i := 0; i <= 4; i++ {
+		v := maxIOPS * float64(i) / 4
+		fmt.Fprintf(&b, `<line x1="%.1f" y1="%.1f" x2="%.1f" y2="%.1f" stroke="#ddd"/><text x="%.1f" y="%.1f" text-anchor="end">%.0f</text>`,
+			left, y(v), left+plotW, y(v), left-6, y(v)+4, v)
+	}
+	for i := 0; i <= 4; i++ {
+		second := (seconds - 1) * i / 4
+		fmt.Fprintf(&b, `<text x="%.1f" y="%.1f" text-anchor="middle">%ds</text>`, x(second), top+plotH+16, second)
+	}
+	fmt.Fprintf(&b, `<text x="%.1f" y="%.1f" text-anchor="middle">%s IOPS per second</text>`, left+plotW/2, top+plotH+32, workload)
+
+	for i, s := range []struct {
+		label  string
+		values []float64
+	}{
+		{"Fully written", written},
+		{"After discard", discarded},
+	} {
+		color := fioChartColors[i%len(fioChartColors)]
+		var points []string
+		for second, v := range s.values {
+			points = append(points, fmt.Sprintf("%.1f,%.1f", x(second), y(v)))
+		}
+		fmt.Fprintf(&b, `<polyline fill="none" stroke="%s" stroke-width="2" points="%s"/>`, color, strings.Join(points, " "))
+		legendX := left + float64(i)*plotW/2
+		fmt.Fprintf(&b, `<rect x="%.1f" y="%.1f" width="12" height="4" fill="%s"/><text x="%.1f" y="%.1f">%s</text>`,
+			legendX, height+6, color, legendX+16, height+11, s.label)
+	}
+	b.WriteString(`</svg>`)
+	return b.String()
+}
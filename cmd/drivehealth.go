@@ -0,0 +1,423 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	htmlpkg "html"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// nvmeDataUnitBytes is the size of one NVMe "data unit" (1000 x 512-byte sectors)
+const nvmeDataUnitBytes = 512 * 1000
+
+// DriveHealthSnapshot is the health state of a drive at one point in time. Unknown values are -1 (or "" / 0 for
+// counters that are only ever reported by the drive).
+type DriveHealthSnapshot struct {
+	Source              string    // "nvme-cli", "smartctl" or "sysfs"
+	CapturedAt          time.Time // When the snapshot was taken
+	TemperatureC        float64   // Composite temperature in Celsius
+	PercentageUsed      int       // Vendor estimate of endurance used (may exceed 100)
+	BytesWritten        uint64    // Host writes over the drive's life (NVMe data units or ATA LBAs written)
+	MediaErrors         int64     // Unrecovered data integrity errors (ATA: reported uncorrectable)
+	WarningTempMinutes  int64     // Minutes above the warning composite temperature threshold (NVMe)
+	CriticalTempMinutes int64     // Minutes above the critical composite temperature threshold (NVMe)
+	ThrottleSeconds     int64     // Seconds spent in host-controlled thermal management (NVMe TMT1 + TMT2)
+	FirmwareRevision    string
+}
+
+// DriveHealth is the health of one tested drive before and after the disk benchmarks
+type DriveHealth struct {
+	DevicePath   string              // Whole-disk path (e.g., /dev/nvme0n1)
+	Model        string              // Model from the drive or sysfs
+	Serial       string              // Serial from the drive or sysfs
+	Before       DriveHealthSnapshot // Before the first FIO test
+	After        DriveHealthSnapshot // After the last FIO test
+	Change       DriveHealthChange   // After minus Before
+	ErrorMessage string              // Why no snapshot could be taken
+}
+
+// DriveHealthChange is what changed on a drive during the benchmarks. Counters are -1 when either
+// snapshot lacks them.
+type DriveHealthChange struct {
+	TemperatureRiseC    float64 // After minus before temperature; 0 if either is unknown
+	PercentageUsed      int     // Endurance used during the tests, in percentage points
+	BytesWritten        int64   // Host writes during the tests
+	MediaErrors         int64   // New media errors
+	WarningTempMinutes  int64   // Minutes above the warning temperature during the tests
+	CriticalTempMinutes int64   // Minutes above the critical temperature during the tests
+	ThrottleSeconds     int64   // Seconds of thermal throttling during the tests
+}
+
+// captureDriveHealth takes a health snapshot of each disk, trying nvme-cli, then smartctl, then sysfs
+func captureDriveHealth(devicePaths []string) []DriveHealth {
+	var results []DriveHealth
+	for _, devicePath := range devicePaths {
+		health := DriveHealth{DevicePath: devicePath}
+		snapshot, model, serial, err := readDriveHealth(devicePath)
+		if err != nil {
+			health.ErrorMessage = err.Error()
+		}
+		health.Model, health.Serial, health.Before = model, serial, snapshot
+		health.Change = driveHealthChange(snapshot, DriveHealthSnapshot{})
+		results = append(results, health)
+	}
+	return results
+}
+
+// finishDriveHealth takes the after snapshot of every drive captured by captureDriveHealth
+func finishDriveHealth(results []DriveHealth) {
+	for i := range results {
+		snapshot, _, _, err := readDriveHealth(results[i].DevicePath)
+		if err != nil && results[i].ErrorMessage == "" {
+			results[i].ErrorMessage = err.Error()
+		}
+		results[i].After = snapshot
+		results[i].Change = driveHealthChange(results[i].Before, snapshot)
+	}
+}
+
+// driveHealthChange computes the change between two snapshots; an after snapshot that was never
+// taken leaves every counter unknown
+func driveHealthChange(before, after DriveHealthSnapshot) DriveHealthChange {
+	change := DriveHealthChange{PercentageUsed: -1, BytesWritten: -1, MediaErrors: -1, WarningTempMinutes: -1,
+		CriticalTempMinutes: -1, ThrottleSeconds: -1}
+	if after.CapturedAt.IsZero() {
+		return change
+	}
+	counter := func(before, after int64) int64 {
+		if before < 0 || after < before {
+			return -1
+		}
+		return after - before
+	}
+	change = DriveHealthChange{
+		PercentageUsed:      -1,
+		BytesWritten:        -1,
+		MediaErrors:         counter(before.MediaErrors, after.MediaErrors),
+		WarningTempMinutes:  counter(before.WarningTempMinutes, after.WarningTempMinutes),
+		CriticalTempMinutes: counter(before.CriticalTempMinutes, after.CriticalTempMinutes),
+		ThrottleSeconds:     counter(before.ThrottleSeconds, after.ThrottleSeconds),
+	}
+	if before.TemperatureC >= 0 && after.TemperatureC >= 0 {
+		change.TemperatureRiseC = after.TemperatureC - before.TemperatureC
+	}
+	if before.PercentageUsed >= 0 && after.PercentageUsed >= before.PercentageUsed {
+		change.PercentageUsed = after.PercentageUsed - before.PercentageUsed
+	}
+	if before.BytesWritten > 0 && after.BytesWritten >= before.BytesWritten {
+		change.BytesWritten = int64(after.BytesWritten - before.BytesWritten)
+	}
+	return change
+}
+
+// emptyDriveHealthSnapshot returns a snapshot with every value marked unknown
+func emptyDriveHealthSnapshot() DriveHealthSnapshot {
+	return DriveHealthSnapshot{
+		CapturedAt:          time.Now(),
+		TemperatureC:        -1,
+		PercentageUsed:      -1,
+		MediaErrors:         -1,
+		WarningTempMinutes:  -1,
+		CriticalTempMinutes: -1,
+		ThrottleSeconds:     -1,
+	}
+}
+
+// readDriveHealth reads the health of a drive from the best available source
+func readDriveHealth(devicePath string) (DriveHealthSnapshot, string, string, error) {
+	isNVMe := strings.HasPrefix(filepath.Base(devicePath), "nvme")
+	if isNVMe {
+		if snapshot, model, serial, err := readNVMeCLIHealth(devicePath); err == nil {
+			return snapshot, model, serial, nil
+		}
+	}
+	if snapshot, model, serial, err := readSmartctlHealth(devicePath); err == nil {
+		return snapshot, model, serial, nil
+	}
+	if isNVMe {
+		return readNVMeSysfsHealth(devicePath)
+	}
+	return emptyDriveHealthSnapshot(), "", "", fmt.Errorf("no health data for %s (install nvme-cli or smartmontools)", devicePath)
+}
+
+// runJSONCommand runs a command and parses its JSON output. smartctl sets status bits in its exit
+// code for drive warnings, so output that parses is used even when the command fails.
+func runJSONCommand(name string, arg ...string) (map[string]interface{}, error) {
+	output, err := runCommand(name, arg...)
+	if start := strings.Index(output, "{"); start >= 0 {
+		var data map[string]interface{}
+		if jsonErr := json.Unmarshal([]byte(output[start:]), &data); jsonErr == nil {
+			return data, nil
+		}
+	}
+	if err == nil {
+		err = fmt.Errorf("could not parse %s JSON output", name)
+	}
+	return nil, err
+}
+
+// jsonNumber reads a number that may be encoded as a JSON number or string (nvme-cli prints
+// 128-bit counters as strings), returning -1 if the key is missing or not numeric
+func jsonNumber(data map[string]interface{}, keys ...string) float64 {
+	for _, key := range keys {
+		switch v := data[key].(type) {
+		case float64:
+			return v
+		case string:
+			if f, err := strconv.ParseFloat(strings.TrimSpace(strings.ReplaceAll(v, ",", "")), 64); err == nil {
+				return f
+			}
+		}
+	}
+	return -1
+}
+
+// jsonCounter converts a jsonNumber to an int64 counter, keeping -1 for unknown
+func jsonCounter(data map[string]interface{}, keys ...string) int64 {
+	return int64(jsonNumber(data, keys...))
+}
+
+// readNVMeCLIHealth reads `nvme smart-log` and `nvme id-ctrl`
+func readNVMeCLIHealth(devicePath string) (DriveHealthSnapshot, string, string, error) {
+	snapshot := emptyDriveHealthSnapshot()
+	log, err := runJSONCommand("nvme", "smart-log", devicePath, "-o", "json")
+	if err != nil {
+		return snapshot, "", "", err
+	}
+	snapshot.Source = "nvme-cli"
+
+	// nvme-cli reports the composite temperature in Kelvin; some builds print Celsius
+	if temperature := jsonNumber(log, "temperature"); temperature > 200 {
+		snapshot.TemperatureC = temperature - 273
+	} else if temperature >= 0 {
+		snapshot.TemperatureC = temperature
+	}
+	snapshot.PercentageUsed = int(jsonNumber(log, "percent_used", "percentage_used"))
+	if units := jsonNumber(log, "data_units_written"); units >= 0 {
+		snapshot.BytesWritten = uint64(units) * nvmeDataUnitBytes
+	}
+	snapshot.MediaErrors = jsonCounter(log, "media_errors")
+	snapshot.WarningTempMinutes = jsonCounter(log, "warning_temp_time")
+	snapshot.CriticalTempMinutes = jsonCounter(log, "critical_comp_time")
+	tmt1, tmt2 := jsonCounter(log, "thm_temp1_total_time"), jsonCounter(log, "thm_temp2_total_time")
+	if tmt1 >= 0 && tmt2 >= 0 {
+		snapshot.ThrottleSeconds = tmt1 + tmt2
+	}
+
+	var model, serial string
+	if ctrl, err := runJSONCommand("nvme", "id-ctrl", devicePath, "-o", "json"); err == nil {
+		snapshot.FirmwareRevision, _ = ctrl["fr"].(string)
+		model, _ = ctrl["mn"].(string)
+		serial, _ = ctrl["sn"].(string)
+	}
+	return snapshot, strings.TrimSpace(model), strings.TrimSpace(serial), nil
+}
+
+// readSmartctlHealth reads `smartctl -j -a`, which covers NVMe as well as SATA/SAS drives
+func readSmartctlHealth(devicePath string) (DriveHealthSnapshot, string, string, error) {
+	snapshot := emptyDriveHealthSnapshot()
+	data, err := runJSONCommand("smartctl", "-j", "-a", devicePath)
+	if err != nil {
+		return snapshot, "", "", err
+	}
+	if _, ok := data["device"]; !ok {
+		return snapshot, "", "", fmt.Errorf("smartctl could not open %s", devicePath)
+	}
+	snapshot.Source = "smartctl"
+
+	if temperature, ok := data["temperature"].(map[string]interface{}); ok {
+		snapshot.TemperatureC = jsonNumber(temperature, "current")
+	}
+	snapshot.FirmwareRevision, _ = data["firmware_version"].(string)
+	model, _ := data["model_name"].(string)
+	serial, _ := data["serial_number"].(string)
+
+	if log, ok := data["nvme_smart_health_information_log"].(map[string]interface{}); ok {
+		snapshot.PercentageUsed = int(jsonNumber(log, "percentage_used"))
+		if units := jsonNumber(log, "data_units_written"); units >= 0 {
+			snapshot.BytesWritten = uint64(units) * nvmeDataUnitBytes
+		}
+		snapshot.MediaErrors = jsonCounter(log, "media_errors")
+		snapshot.WarningTempMinutes = jsonCounter(log, "warning_temp_time")
+		snapshot.CriticalTempMinutes = jsonCounter(log, "critical_comp_time")
+		// Thermal management times are only in the JSON of smartmontools 7.4 and later
+		tmt1, tmt2 := jsonCounter(log, "thermal_temp1_total_time"), jsonCounter(log, "thermal_temp2_total_time")
+		if tmt1 >= 0 && tmt2 >= 0 {
+			snapshot.ThrottleSeconds = tmt1 + tmt2
+		}
+		return snapshot, model, serial, nil
+	}
+
+	// ATA: endurance from the device statistics, writes and errors from the attribute table
+	if endurance, ok := data["endurance_used"].(map[string]interface{}); ok {
+		snapshot.PercentageUsed = int(jsonNumber(endurance, "current_percent"))
+	}
+	sectorSize := uint64(512)
+	if size := jsonNumber(data, "logical_block_size"); size > 0 {
+		sectorSize = uint64(size)
+	}
+	if attributes, ok := data["ata_smart_attributes"].(map[string]interface{}); ok {
+		table, _ := attributes["table"].([]interface{})
+		for _, entry := range table {
+			attribute, ok := entry.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			raw, _ := attribute["raw"].(map[string]interface{})
+			value := jsonNumber(raw, "value")
+			if value < 0 {
+				continue
+			}
+			switch int(jsonNumber(attribute, "id")) {
+			case 187: // Reported_Uncorrect
+				snapshot.MediaErrors = int64(value)
+			case 241: // Total_LBAs_Written
+				snapshot.BytesWritten = uint64(value) * sectorSize
+			}
+		}
+	}
+	return snapshot, model, serial, nil
+}
+
+// readNVMeSysfsHealth reads what the kernel exposes for an NVMe controller: firmware, model, serial and
+// the hwmon composite temperature. Wear and error counters need the SMART log.
+func readNVMeSysfsHealth(devicePath string) (DriveHealthSnapshot, string, string, error) {
+	snapshot := emptyDriveHealthSnapshot()
+	// /sys/class/block/nvme0n1/device is the controller (nvme0)
+	ctrlDir := filepath.Join("/sys/class/block", filepath.Base(devicePath), "device")
+	if !fileExists(ctrlDir) {
+		return snapshot, "", "", fmt.Errorf("no sysfs controller for %s", devicePath)
+	}
+	snapshot.Source = "sysfs"
+	snapshot.FirmwareRevision = readSysfsValue(filepath.Join(ctrlDir, "firmware_rev"))
+
+	hwmons, _ := filepath.Glob(filepath.Join(ctrlDir, "hwmon*", "temp1_input"))
+	if len(hwmons) == 0 {
+		hwmons, _ = filepath.Glob(filepath.Join(ctrlDir, "device", "hwmon", "hwmon*", "temp1_input"))
+	}
+	if len(hwmons) > 0 {
+		if milli, err := strconv.ParseFloat(readSysfsValue(hwmons[0]), 64); err == nil {
+			snapshot.TemperatureC = milli / 1000
+		}
+	}
+	return snapshot, readSysfsValue(filepath.Join(ctrlDir, "model")), readSysfsValue(filepath.Join(ctrlDir, "serial")), nil
+}
+
+// driveHealthDelta formats the change of a counter between the snapshots, or "N/A" if it is unknown
+func driveHealthDelta(change int64, unit string) string {
+	if change < 0 {
+		return "N/A"
+	}
+	return fmt.Sprintf("%+d%s", change, unit)
+}
+
+// driveHealthWritten formats the host writes between the snapshots
+func driveHealthWritten(health DriveHealth) string {
+	if health.Change.BytesWritten < 0 {
+		return "N/A"
+	}
+	return humanReadableBytes(uint64(health.Change.BytesWritten))
+}
+
+// driveHealthTemperature formats the temperature before and after, e.g. "38 -> 61 C (+23)"
+func driveHealthTemperature(health DriveHealth) string {
+	before, after := health.Before.TemperatureC, health.After.TemperatureC
+	switch {
+	case before < 0 && after < 0:
+		return "N/A"
+	case before < 0:
+		return fmt.Sprintf("%.0f C", after)
+	case after < 0:
+		return fmt.Sprintf("%.0f C", before)
+	}
+	return fmt.Sprintf("%.0f -> %.0f C (%+.0f)", before, after, after-before)
+}
+
+// driveHealthPercent formats a percentage, or "N/A" if unknown
+func driveHealthPercent(value int) string {
+	if value < 0 {
+		return "N/A"
+	}
+	return fmt.Sprintf("%d%%", value)
+}
+
+// driveHealthConcerns lists what changed for the worse during the benchmarks
+func driveHealthConcerns(health DriveHealth) []string {
+	var concerns []string
+	before, after, change := health.Before, health.After, health.Change
+	if change.ThrottleSeconds > 0 {
+		concerns = append(concerns, fmt.Sprintf("thermal throttling for %ds", change.ThrottleSeconds))
+	}
+	if change.WarningTempMinutes > 0 {
+		concerns = append(concerns, fmt.Sprintf("%d min above warning temperature", change.WarningTempMinutes))
+	}
+	if change.CriticalTempMinutes > 0 {
+		concerns = append(concerns, fmt.Sprintf("%d min above critical temperature", change.CriticalTempMinutes))
+	}
+	if change.MediaErrors > 0 {
+		concerns = append(concerns, fmt.Sprintf("%d new media errors", change.MediaErrors))
+	}
+	if before.FirmwareRevision != "" && after.FirmwareRevision != "" && before.FirmwareRevision != after.FirmwareRevision {
+		concerns = append(concerns, "firmware changed from "+before.FirmwareRevision+" to "+after.FirmwareRevision)
+	}
+	return concerns
+}
+
+// printDriveHealth prints the before/after health of the tested drives
+func printDriveHealth(indent string, results []DriveHealth) {
+	for _, health := range results {
+		name := health.DevicePath
+		if health.Model != "" {
+			name += " (" + health.Model + ")"
+		}
+		if health.After.FirmwareRevision != "" {
+			name += ", firmware " + health.After.FirmwareRevision
+		}
+		if health.After.Source != "" {
+			name += " [" + health.After.Source + "]"
+		}
+		fmt.Printf("%s%s\n", indent, name)
+		fmt.Printf("%s  Temperature: %s, Used: %s -> %s, Written during tests: %s, Media errors: %s, Throttling: %s\n", indent,
+			driveHealthTemperature(health), driveHealthPercent(health.Before.PercentageUsed), driveHealthPercent(health.After.PercentageUsed),
+			driveHealthWritten(health), driveHealthDelta(health.Change.MediaErrors, ""),
+			driveHealthDelta(health.Change.ThrottleSeconds, "s"))
+		if concerns := driveHealthConcerns(health); len(concerns) > 0 {
+			fmt.Printf("%s  %sWarning: %s%s\n", indent, colorYellow, strings.Join(concerns, ", "), colorReset)
+		}
+		if health.ErrorMessage != "" {
+			fmt.Printf("%s  Note: %s\n", indent, health.ErrorMessage)
+		}
+	}
+}
+
+// driveHealthHTML renders the before/after health of the tested drives
+func driveHealthHTML(results []DriveHealth) string {
+	if len(results) == 0 {
+		return ""
+	}
+	html := `
+        <h3>Drive Health (before / after benchmarks)</h3>
+        <table>
+            <tr><th>Drive</th><th>Source</th><th>Firmware</th><th>Temperature</th><th>Percentage Used</th><th>Written During Tests</th><th>Media Errors</th><th>Throttling</th><th>Notes</th></tr>`
+	for _, health := range results {
+		name := htmlpkg.EscapeString(health.DevicePath)
+		if health.Model != "" {
+			name += "<br>" + htmlpkg.EscapeString(health.Model)
+		}
+		notes := htmlpkg.EscapeString(health.ErrorMessage)
+		if concerns := driveHealthConcerns(health); len(concerns) > 0 {
+			notes = `<span class="warning">` + htmlpkg.EscapeString(strings.Join(concerns, ", ")) + `</span>`
+		}
+		html += `
+            <tr><td>` + name + `</td><td>` + health.After.Source + `</td><td>` + htmlpkg.EscapeString(health.After.FirmwareRevision) + `</td><td>` +
+			driveHealthTemperature(health) + `</td><td>` + driveHealthPercent(health.Before.PercentageUsed) + ` / ` +
+			driveHealthPercent(health.After.PercentageUsed) + `</td><td>` + driveHealthWritten(health) + `</td><td>` +
+			driveHealthDelta(health.Change.MediaErrors, "") + `</td><td>` +
+			driveHealthDelta(health.Change.ThrottleSeconds, "s") + `</td><td>` + notes + `</td></tr>`
+	}
+	return html + `
+        </table>`
+}
@@ -0,0 +1,174 @@
+package cmd
+
+import (
+	"fmt"
+	htmlpkg "html"
+	"strings"
+)
+
+// FioBufferedResult compares one scenario with O_DIRECT, through a cold page cache and, for reads,
+// with the file already cached
+type FioBufferedResult struct {
+	TestName      string        // Scenario name (e.g., "1M_SeqRead_QD32")
+	Description   string        // Scenario description
+	Direct        FioTestResult // O_DIRECT result from the scenario matrix
+	Buffered      FioTestResult // Buffered I/O after dropping caches; writes include the final fsync
+	Cached        FioTestResult // Reads only: buffered re-read with the whole file in the page cache
+	CachesDropped bool          // Whether /proc/sys/vm/drop_caches was written before the buffered run
+	ErrorMessage  string        // Error message if a buffered run failed
+}
+
+// fioBufferedScenario reports whether a scenario gets a buffered pass: the plain sequential and random
+// read/write tests of the standard matrix
+func fioBufferedScenario(scenario fioScenario) bool {
+	switch scenario.rw {
+	case "read", "write", "randread", "randwrite":
+		return scenario.category == "standard"
+	}
+	return false
+}
+
+// fioBufferedSizeWarning returns a warning when the test file fits in RAM, so buffered numbers would
+// mostly measure the page cache
+func fioBufferedSizeWarning(testSizeBytes uint64) string {
+	meminfo, err := readMeminfo()
+	if err != nil {
+		return ""
+	}
+	memTotal := meminfo["MemTotal"] * 1024
+	if testSizeBytes >= memTotal {
+		return ""
+	}
+	return fmt.Sprintf("test size %s is smaller than RAM (%s): buffered results largely measure the page cache; use --fio-test-size of at least %s",
+		humanReadableBytes(testSizeBytes), humanReadableBytes(memTotal), humanReadableBytes(memTotal*2))
+}
+
+// runFioBuffered runs the buffered pass for the scenarios that have one. Caches are dropped before
+// every buffered run; cached re-reads first read the whole file so it is resident.
+func runFioBuffered(scenarios []fioScenario, engine fioEngine, testFilePath, testSize string, readOnly bool, targetArgs []string, direct []FioTestResult) []FioBufferedResult {
+	var results []FioBufferedResult
+	for _, scenario := range scenarios {
+		if !fioBufferedScenario(scenario) {
+			continue
+		}
+		result := FioBufferedResult{TestName: scenario.name, Description: scenario.description}
+		for _, test := range direct {
+			if test.TestName == scenario.name && test.Engine == engine.label {
+				result.Direct = test
+			}
+		}
+
+		// fio takes the last value of a repeated option, so --direct=0 overrides the matrix default
+		args := append(fioScenarioArgs(scenario, engine, testFilePath, testSize, readOnly, targetArgs), "--direct=0")
+		if fioScenarioWrites(scenario) {
+			args = append(args, "--end_fsync=1") // Count writeback, not just copying into the page cache
+		}
+
+		fmt.Printf("      Running buffered FIO test: %s (engine=%s)\n", scenario.name, engine.label)
+		err := dropPageCache()
+		result.CachesDropped = err == nil
+		if err != nil {
+			fmt.Printf("        Could not drop caches, buffered result may include cached data: %v\n", err)
+		}
+		result.Buffered = newFioTestResult(scenario, engine)
+		job, err := runFioJob(args)
+		if err != nil {
+			result.Buffered.IOPS, result.Buffered.BandwidthMB, result.Buffered.LatencyUs = -1, -1, -1
+			result.ErrorMessage = err.Error()
+			results = append(results, result)
+			continue
+		}
+		setFioJobResult(&result.Buffered, job)
+
+		if !fioScenarioWrites(scenario) {
+			// fio invalidates the file's cache at job start by default; keep it for the re-read
+			warm := []string{"--name=warm", "--filename=" + testFilePath, "--rw=read", "--bs=1m", "--size=" + testSize,
+				"--direct=0", "--invalidate=0", "--ioengine=psync", "--output-format=json"}
+			if readOnly {
+				warm = append(warm, "--readonly")
+			}
+			result.Cached = newFioTestResult(scenario, engine)
+			if _, err := runFioJob(warm); err != nil {
+				result.Cached.IOPS, result.Cached.BandwidthMB, result.Cached.LatencyUs = -1, -1, -1
+				result.ErrorMessage = "warming the page cache: " + err.Error()
+			} else if job, err := runFioJob(append(args, "--invalidate=0")); err != nil {
+				result.Cached.IOPS, result.Cached.BandwidthMB, result.Cached.LatencyUs = -1, -1, -1
+				result.ErrorMessage = err.Error()
+			} else {
+				setFioJobResult(&result.Cached, job)
+			}
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+// fioBufferedCell formats a result as IOPS for small blocks and MB/s for large ones
+func fioBufferedCell(test FioTestResult) string {
+	switch {
+	case test.TestName == "":
+		return "-"
+	case test.IOPS < 0:
+		return "FAIL"
+	case strings.HasSuffix(strings.ToLower(test.BlockSize), "m"):
+		return fmt.Sprintf("%.1f MB/s", test.BandwidthMB)
+	}
+	return fmt.Sprintf("%.0f IOPS", test.IOPS)
+}
+
+// printFioBuffered prints direct, buffered and cached results side by side
+func printFioBuffered(indent string, device FioDeviceResult) {
+	if len(device.Buffered) == 0 {
+		return
+	}
+	fmt.Printf("%sDirect vs buffered I/O:\n", indent)
+	if device.BufferedWarning != "" {
+		fmt.Printf("%s  %sWarning: %s%s\n", indent, colorYellow, device.BufferedWarning, colorReset)
+	}
+	fmt.Printf("%s  %-32s | %-16s | %-16s | %-16s\n", indent, "Test Type", "Direct", "Buffered (cold)", "Cached re-read")
+	for _, result := range device.Buffered {
+		buffered := fioBufferedCell(result.Buffered)
+		if !result.CachesDropped && result.Buffered.IOPS >= 0 {
+			buffered += " *"
+		}
+		fmt.Printf("%s  %-32s | %-16s | %-16s | %-16s\n", indent, result.TestName,
+			fioBufferedCell(result.Direct), buffered, fioBufferedCell(result.Cached))
+		if result.ErrorMessage != "" {
+			fmt.Printf("%s    Error: %s\n", indent, result.ErrorMessage)
+		}
+	}
+	for _, result := range device.Buffered {
+		if !result.CachesDropped {
+			fmt.Printf("%s  * caches could not be dropped (needs root)\n", indent)
+			break
+		}
+	}
+}
+
+// fioBufferedHTML renders the direct vs buffered comparison of a device
+func fioBufferedHTML(device FioDeviceResult) string {
+	if len(device.Buffered) == 0 {
+		return ""
+	}
+	html := `
+        <h4>Direct vs Buffered I/O</h4>
+        <p>Buffered runs start after dropping the page cache; buffered writes include the final fsync. Cached re-reads run with the whole file in the page cache.</p>`
+	if device.BufferedWarning != "" {
+		html += `
+        <p class="warning">Warning: ` + device.BufferedWarning + `</p>`
+	}
+	html += `
+        <table>
+            <tr><th>Test</th><th>Direct</th><th>Buffered (cold)</th><th>Cached Re-read</th><th>Notes</th></tr>`
+	for _, result := range device.Buffered {
+		notes := result.ErrorMessage
+		if !result.CachesDropped && result.Buffered.IOPS >= 0 {
+			notes = strings.TrimPrefix(notes+"; caches could not be dropped", "; ")
+		}
+		html += `
+            <tr><td>` + htmlpkg.EscapeString(result.Description) + `</td><td>` + fioBufferedCell(result.Direct) + `</td><td>` +
+			fioBufferedCell(result.Buffered) + `</td><td>` + fioBufferedCell(result.Cached) + `</td><td>` + htmlpkg.EscapeString(notes) + `</td></tr>`
+	}
+	return html + `
+        </table>`
+}
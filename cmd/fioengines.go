@@ -0,0 +1,362 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	htmlpkg "html"
+	"strconv"
+	"strings"
+)
+
+// fioEngine is one I/O engine configuration that every scenario runs with
+type fioEngine struct {
+	name    string   // fio ioengine (e.g., "io_uring")
+	options []string // Engine options from the spec (e.g., "fixedbufs")
+	label   string   // Engine and options in effect, used in reports (e.g., "io_uring+fixedbufs")
+}
+
+// FioEngineSupport records whether an engine could be used on this system, and why not
+type FioEngineSupport struct {
+	Engine    string   // Engine label (e.g., "io_uring+sqthread_poll")
+	Available bool     // Whether the scenarios ran with this engine
+	Options   []string // Engine options in effect
+	Dropped   []string // Requested options that are not supported here
+	Notes     string   // Why the engine or an option is unavailable, or caveats such as psync ignoring iodepth
+}
+
+// fioUringOptions maps the io_uring options accepted in --fio-engines to fio arguments
+var fioUringOptions = map[string]string{
+	"fixedbufs":     "--fixedbufs",
+	"registerfiles": "--registerfiles",
+	"sqthread_poll": "--sqthread_poll=1",
+	"hipri":         "--hipri",
+	"nonvectored":   "--nonvectored=1",
+}
+
+// fioSyncEngines issue one I/O at a time per job, so iodepth has no effect
+var fioSyncEngines = map[string]bool{"psync": true, "sync": true, "pvsync": true, "pvsync2": true, "vsync": true}
+
+// parseFioEngines parses --fio-engines entries such as "libaio", "io_uring+fixedbufs+hipri" or "psync"
+func parseFioEngines(specs []string) ([]fioEngine, error) {
+	var engines []fioEngine
+	seen := make(map[string]bool)
+	for _, spec := range specs {
+		spec = strings.TrimSpace(spec)
+		if spec == "" {
+			continue
+		}
+		parts := strings.Split(spec, "+")
+		engine := fioEngine{name: parts[0], options: parts[1:], label: spec}
+		if engine.name == "" {
+			return nil, fmt.Errorf("invalid engine '%s'", spec)
+		}
+		for _, option := range engine.options {
+			if engine.name != "io_uring" {
+				return nil, fmt.Errorf("engine '%s': options are only supported for io_uring", spec)
+			}
+			if _, ok := fioUringOptions[option]; !ok {
+				return nil, fmt.Errorf("engine '%s': unknown io_uring option '%s' (supported: fixedbufs, registerfiles, sqthread_poll, hipri, nonvectored)", spec, option)
+			}
+		}
+		if seen[spec] {
+			continue
+		}
+		seen[spec] = true
+		engines = append(engines, engine)
+	}
+	if len(engines) == 0 {
+		return nil, fmt.Errorf("no FIO engines given")
+	}
+	return engines, nil
+}
+
+// detectFioEngineSupport checks each engine against the engines compiled into fio and, for io_uring,
+// against the running kernel. Unsupported io_uring options are dropped rather than failing every test.
+// Without fio, the built-in native engine takes the place of the fio engines.
+func detectFioEngineSupport(engines []fioEngine, kernelVersion string) ([]fioEngine, []FioEngineSupport) {
+	haveFio := fioInstalled()
+	compiled := fioCompiledEngines()
+	var usable []fioEngine
+	var support []FioEngineSupport
+	native := false
+	for _, engine := range engines {
+		status := FioEngineSupport{Engine: engine.label, Available: true}
+		switch {
+		case engine.name == nativeDiskEngine:
+			native = true
+			status.Notes = "built-in Go engine (synchronous O_DIRECT I/O, one worker per queue slot), not fio"
+		case !haveFio:
+			status.Available = false
+			status.Notes = "fio is not installed"
+		case compiled != nil && !compiled[engine.name]:
+			status.Available = false
+			status.Notes = "not compiled into this fio build"
+		case engine.name == "io_uring":
+			if reason := ioUringUnavailable(kernelVersion); reason != "" {
+				status.Available = false
+				status.Notes = reason
+				break
+			}
+			for _, option := range engine.options {
+				if option == "hipri" && !nvmePollQueuesEnabled() {
+					// Polled completions need dedicated poll queues; without them fio falls back or fails
+					status.Dropped = append(status.Dropped, option)
+					status.Notes = "hipri dropped: the nvme driver has no poll queues (set nvme.poll_queues)"
+					continue
+				}
+				status.Options = append(status.Options, option)
+			}
+			// Results are labelled with the options that were actually used
+			engine.options = status.Options
+			engine.label = strings.Join(append([]string{engine.name}, engine.options...), "+")
+			status.Engine = engine.label
+		case fioSyncEngines[engine.name]:
+			status.Notes = "synchronous engine: one I/O in flight per job, iodepth is ignored"
+		}
+		support = append(support, status)
+		if status.Available {
+			usable = append(usable, engine)
+		}
+	}
+	if !haveFio && !native {
+		support = append(support, FioEngineSupport{Engine: nativeDiskEngine, Available: true,
+			Notes: "fallback because fio is not installed; results are not comparable with fio engines"})
+		usable = append(usable, fioEngine{name: nativeDiskEngine, label: nativeDiskEngine})
+	}
+	return usable, support
+}
+
+// fioCompiledEngines lists the engines reported by `fio --enghelp`, or nil if that fails
+func fioCompiledEngines() map[string]bool {
+	output, err := runCommand("fio", "--enghelp")
+	if err != nil {
+		return nil
+	}
+	engines := make(map[string]bool)
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasSuffix(line, ":") {
+			continue
+		}
+		engines[line] = true
+	}
+	if len(engines) == 0 {
+		return nil
+	}
+	return engines
+}
+
+// ioUringUnavailable returns why io_uring cannot be used on this kernel, or "" if it can
+func ioUringUnavailable(kernelVersion string) string {
+	if major, minor, ok := parseKernelVersion(kernelVersion); ok && (major < 5 || (major == 5 && minor < 1)) {
+		return fmt.Sprintf("kernel %s predates io_uring (5.1)", kernelVersion)
+	}
+	// 0 = enabled, 1 = restricted to io_uring_group (root is still allowed), 2 = disabled
+	if disabled := readSysfsValue("/proc/sys/kernel/io_uring_disabled"); disabled == "2" {
+		return "disabled by kernel.io_uring_disabled=2"
+	}
+	return ""
+}
+
+// nvmePollQueuesEnabled reports whether the nvme driver was loaded with polled I/O queues
+func nvmePollQueuesEnabled() bool {
+	queues, err := strconv.Atoi(readSysfsValue("/sys/module/nvme/parameters/poll_queues"))
+	return err == nil && queues > 0
+}
+
+// parseKernelVersion extracts the major and minor version from a release such as "6.8.0-45-generic"
+func parseKernelVersion(release string) (major, minor int, ok bool) {
+	fields := strings.SplitN(strings.TrimSpace(release), ".", 3)
+	if len(fields) < 2 {
+		return 0, 0, false
+	}
+	major, err1 := strconv.Atoi(fields[0])
+	minorDigits := strings.TrimRightFunc(fields[1], func(r rune) bool { return r < '0' || r > '9' })
+	minor, err2 := strconv.Atoi(minorDigits)
+	return major, minor, err1 == nil && err2 == nil
+}
+
+// fioEngineArgs returns the fio arguments that select an engine and its options
+func fioEngineArgs(engine fioEngine) []string {
+	args := []string{"--ioengine=" + engine.name}
+	for _, option := range engine.options {
+		args = append(args, fioUringOptions[option])
+	}
+	return args
+}
+
+// fioTestLabel names a test in tables; the engine is added when several engines were compared, and
+// always for the native engine so its numbers are not mistaken for fio's
+func fioTestLabel(test FioTestResult) string {
+	if test.Engine == "" || (len(fioEngineSpecs) < 2 && test.Engine != nativeDiskEngine) {
+		return test.TestName
+	}
+	return test.TestName + " [" + test.Engine + "]"
+}
+
+// fioEngineGroup is one scenario with its result for every engine, in --fio-engines order
+type fioEngineGroup struct {
+	testName string
+	results  []FioTestResult
+}
+
+// fioEngineComparison groups the results of a device by scenario. Only scenarios that ran with
+// more than one engine are returned.
+func fioEngineComparison(device FioDeviceResult) []fioEngineGroup {
+	var groups []fioEngineGroup
+	index := make(map[string]int)
+	for _, test := range device.TestResults {
+		i, ok := index[test.TestName]
+		if !ok {
+			i = len(groups)
+			index[test.TestName] = i
+			groups = append(groups, fioEngineGroup{testName: test.TestName})
+		}
+		groups[i].results = append(groups[i].results, test)
+	}
+	var compared []fioEngineGroup
+	for _, group := range groups {
+		if len(group.results) > 1 {
+			compared = append(compared, group)
+		}
+	}
+	return compared
+}
+
+// fioEngineColumns returns the engine columns of the comparison: the engines that were used, in
+// --fio-engines order, then any other engine a scenario pinned
+func fioEngineColumns(groups []fioEngineGroup, support []FioEngineSupport) []string {
+	var columns []string
+	seen := make(map[string]bool)
+	for _, engine := range support {
+		if engine.Available && !seen[engine.Engine] {
+			seen[engine.Engine] = true
+			columns = append(columns, engine.Engine)
+		}
+	}
+	for _, group := range groups {
+		for _, test := range group.results {
+			if !seen[test.Engine] {
+				seen[test.Engine] = true
+				columns = append(columns, test.Engine)
+			}
+		}
+	}
+	return columns
+}
+
+// fioEngineResult returns the result of a group for one engine
+func fioEngineResult(group fioEngineGroup, engine string) (FioTestResult, bool) {
+	for _, test := range group.results {
+		if test.Engine == engine {
+			return test, true
+		}
+	}
+	return FioTestResult{}, false
+}
+
+// fioEngineDelta formats a result relative to the first engine column (e.g., "+23.5%")
+func fioEngineDelta(test, baseline FioTestResult) string {
+	if test.IOPS <= 0 || baseline.IOPS <= 0 {
+		return ""
+	}
+	return fmt.Sprintf("%+.1f%%", (test.IOPS/baseline.IOPS-1)*100)
+}
+
+// printFioEngineComparison prints the engine-by-engine comparison of a device
+func printFioEngineComparison(indent string, device FioDeviceResult, support []FioEngineSupport) {
+	groups := fioEngineComparison(device)
+	if len(groups) == 0 {
+		return
+	}
+	columns := fioEngineColumns(groups, support)
+	fmt.Printf("%sEngine comparison (IOPS, p99, change vs %s):\n", indent, columns[0])
+	for _, group := range groups {
+		baseline, _ := fioEngineResult(group, columns[0])
+		var cells []string
+		for _, engine := range columns {
+			test, ok := fioEngineResult(group, engine)
+			switch {
+			case !ok:
+				cells = append(cells, engine+" N/A")
+				continue
+			case test.IOPS < 0:
+				cells = append(cells, engine+" FAIL")
+				continue
+			}
+			cell := fmt.Sprintf("%s %.0f (p99 %s)", engine, test.IOPS, formatLatencyNs(fioCompletionP99Ns(test)))
+			if delta := fioEngineDelta(test, baseline); delta != "" && engine != columns[0] {
+				cell += " " + delta
+			}
+			cells = append(cells, cell)
+		}
+		fmt.Printf("%s  %-32s %s\n", indent, group.testName, strings.Join(cells, " | "))
+	}
+}
+
+// fioEngineComparisonHTML renders the engine comparison of a device as a scenario-by-engine table
+func fioEngineComparisonHTML(device FioDeviceResult, support []FioEngineSupport) string {
+	groups := fioEngineComparison(device)
+	if len(groups) == 0 {
+		return ""
+	}
+	columns := fioEngineColumns(groups, support)
+	html := `
+        <h4>Engine Comparison</h4>
+        <table>
+            <tr><th>Test</th>`
+	for _, engine := range columns {
+		html += `<th>` + engine + `</th>`
+	}
+	html += `</tr>`
+	for _, group := range groups {
+		baseline, _ := fioEngineResult(group, columns[0])
+		html += `
+            <tr><td>` + htmlpkg.EscapeString(group.testName) + `</td>`
+		for _, engine := range columns {
+			test, ok := fioEngineResult(group, engine)
+			switch {
+			case !ok:
+				html += `<td>N/A</td>`
+				continue
+			case test.IOPS < 0:
+				html += `<td>FAIL</td>`
+				continue
+			}
+			cell := fmt.Sprintf("%.0f IOPS<br>p99 %s", test.IOPS, formatLatencyNs(fioCompletionP99Ns(test)))
+			if delta := fioEngineDelta(test, baseline); delta != "" && engine != columns[0] {
+				class := "highlight"
+				if test.IOPS < baseline.IOPS {
+					class = "warning"
+				}
+				cell += `<br><span class="` + class + `">` + delta + `</span>`
+			}
+			html += `<td>` + cell + `</td>`
+		}
+		html += `</tr>`
+	}
+	return html + `
+        </table>`
+}
+
+// fioEngineSupportHTML lists the engines and whether they could be used
+func fioEngineSupportHTML(support []FioEngineSupport) string {
+	if len(support) == 0 {
+		return ""
+	}
+	html := `
+        <h3>I/O Engines</h3>
+        <table>
+            <tr><th>Engine</th><th>Status</th><th>Options</th><th>Notes</th></tr>`
+	for _, engine := range support {
+		status := `<span class="highlight">Used</span>`
+		if !engine.Available {
+			status = `<span class="warning">Unavailable</span>`
+		}
+		html += `
+            <tr><td>` + engine.Engine + `</td><td>` + status + `</td><td>` + strings.Join(engine.Options, ", ") + `</td><td>` + htmlpkg.EscapeString(engine.Notes) + `</td></tr>`
+	}
+	return html + `
+        </table>`
+}
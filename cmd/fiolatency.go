@@ -0,0 +1,261 @@
+package cmd
+
+import (
+	"fmt"
+	"math"
+	"math/bits"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// FioLatencyStats holds one fio latency measurement (slat, clat or lat), all values in nanoseconds
+type FioLatencyStats struct {
+	Samples     uint64             // Number of I/Os measured
+	MinNs       float64            // Minimum latency
+	MaxNs       float64            // Maximum latency
+	MeanNs      float64            // Mean latency
+	StdDevNs    float64            // Standard deviation
+	P50Ns       float64            // Median
+	P90Ns       float64            // 90th percentile
+	P99Ns       float64            // 99th percentile
+	P999Ns      float64            // 99.9th percentile
+	P9999Ns     float64            // 99.99th percentile
+	Percentiles []FioPercentile    // Full percentile list reported by fio, ascending
+	Histogram   []FioLatencyBucket // Power-of-two buckets built from the json+ latency bins
+}
+
+// FioPercentile is one point of a latency distribution
+type FioPercentile struct {
+	Percentile float64 // e.g., 99.9
+	Ns         float64 // Latency at that percentile
+}
+
+// FioLatencyBucket counts I/Os that completed in (previous bucket, UpperNs]
+type FioLatencyBucket struct {
+	UpperNs uint64 // Upper bound of the bucket (a power of two)
+	Count   uint64 // I/Os in the bucket
+}
+
+// FioLatencyBreakdown holds the submission, completion and total latency of one I/O direction
+type FioLatencyBreakdown struct {
+	Submission FioLatencyStats // slat_ns: time to submit the I/O
+	Completion FioLatencyStats // clat_ns: submission to completion
+	Total      FioLatencyStats // lat_ns: slat + clat
+}
+
+// parseFioLatencyBreakdown extracts slat/clat/lat from one direction ("read", "write", "trim") of a fio job
+func parseFioLatencyBreakdown(direction map[string]interface{}) FioLatencyBreakdown {
+	var breakdown FioLatencyBreakdown
+	if slat, ok := direction["slat_ns"].(map[string]interface{}); ok {
+		breakdown.Submission = parseFioLatencyStats(slat)
+	}
+	if clat, ok := direction["clat_ns"].(map[string]interface{}); ok {
+		breakdown.Completion = parseFioLatencyStats(clat)
+	}
+	if lat, ok := direction["lat_ns"].(map[string]interface{}); ok {
+		breakdown.Total = parseFioLatencyStats(lat)
+	}
+	return breakdown
+}
+
+// parseFioLatencyStats extracts one latency object (e.g., clat_ns) from fio JSON output
+func parseFioLatencyStats(m map[string]interface{}) FioLatencyStats {
+	var stats FioLatencyStats
+	if n, ok := m["N"].(float64); ok {
+		stats.Samples = uint64(n)
+	}
+	stats.MinNs, _ = m["min"].(float64)
+	stats.MaxNs, _ = m["max"].(float64)
+	stats.MeanNs, _ = m["mean"].(float64)
+	stats.StdDevNs, _ = m["stddev"].(float64)
+
+	if percentiles, ok := m["percentile"].(map[string]interface{}); ok {
+		for key, value := range percentiles {
+			p, err := strconv.ParseFloat(key, 64)
+			ns, ok := value.(float64)
+			if err != nil || !ok {
+				continue
+			}
+			stats.Percentiles = append(stats.Percentiles, FioPercentile{Percentile: p, Ns: ns})
+		}
+		sort.Slice(stats.Percentiles, func(i, j int) bool {
+			return stats.Percentiles[i].Percentile < stats.Percentiles[j].Percentile
+		})
+		stats.P50Ns = fioPercentileAt(stats.Percentiles, 50)
+		stats.P90Ns = fioPercentileAt(stats.Percentiles, 90)
+		stats.P99Ns = fioPercentileAt(stats.Percentiles, 99)
+		stats.P999Ns = fioPercentileAt(stats.Percentiles, 99.9)
+		stats.P9999Ns = fioPercentileAt(stats.Percentiles, 99.99)
+	}
+
+	// json+ output adds the raw latency bins ("<ns>": count); fold them into power-of-two buckets
+	if bins, ok := m["bins"].(map[string]interface{}); ok {
+		counts := make(map[uint64]uint64)
+		for key, value := range bins {
+			ns, err := strconv.ParseUint(key, 10, 64)
+			count, ok := value.(float64)
+			if err != nil || !ok || count <= 0 {
+				continue
+			}
+			upper := uint64(1)
+			if ns > 1 {
+				upper = 1 << bits.Len64(ns-1)
+			}
+			counts[upper] += uint64(count)
+		}
+		for upper, count := range counts {
+			stats.Histogram = append(stats.Histogram, FioLatencyBucket{UpperNs: upper, Count: count})
+		}
+		sort.Slice(stats.Histogram, func(i, j int) bool { return stats.Histogram[i].UpperNs < stats.Histogram[j].UpperNs })
+	}
+	return stats
+}
+
+// fioPercentileAt returns the latency at percentile p, or 0 if fio did not report it
+func fioPercentileAt(percentiles []FioPercentile, p float64) float64 {
+	for _, point := range percentiles {
+		if math.Abs(point.Percentile-p) < 1e-6 {
+			return point.Ns
+		}
+	}
+	return 0
+}
+
+// formatLatencyNs formats a latency with a unit that keeps it readable (ns, us, ms or s)
+func formatLatencyNs(ns float64) string {
+	switch {
+	case ns < 0:
+		return "FAIL"
+	case ns == 0:
+		return "N/A"
+	case ns < 1000:
+		return fmt.Sprintf("%.0f ns", ns)
+	case ns < 1000*1000:
+		return fmt.Sprintf("%.2f us", ns/1000)
+	case ns < 1000*1000*1000:
+		return fmt.Sprintf("%.2f ms", ns/(1000*1000))
+	default:
+		return fmt.Sprintf("%.2f s", ns/(1000*1000*1000))
+	}
+}
+
+// fioLatencySeries is one line of the percentile chart
+type fioLatencySeries struct {
+	label  string
+	points []FioPercentile
+}
+
+// fioChartColors is the palette used for chart series
+var fioChartColors = []string{"#3498db", "#e74c3c", "#2ecc71", "#9b59b6", "#f39c12", "#1abc9c", "#34495e", "#e67e22", "#7f8c8d", "#c0392b"}
+
+// fioLatencySeriesForDevice collects the completion latency percentiles of every test and direction on a device
+func fioLatencySeriesForDevice(device FioDeviceResult) []fioLatencySeries {
+	var series []fioLatencySeries
+	for _, test := range device.TestResults {
+		for _, dir := range fioActiveDirections(test) {
+			if percentiles := dir.result.Latency.Completion.Percentiles; len(percentiles) > 0 {
+				series = append(series, fioLatencySeries{label: fioTestLabel(test) + " (" + dir.name + ")", points: percentiles})
+			}
+		}
+	}
+	return series
+}
+
+// fioPercentileChartSVG draws completion latency against percentile. The x axis is in "nines"
+// (p90 = 1, p99 = 2, p99.9 = 3) and the y axis is logarithmic so tail latency stays visible.
+func fioPercentileChartSVG(series []fioLatencySeries) string {
+	if len(series) == 0 {
+		return ""
+	}
+	const width, height = 760.0, 340.0
+	const left, right, top, bottom = 70.0, 20.0, 20.0, 40.0
+	plotW, plotH := width-left-right, height-top-bottom
+
+	nines := func(p float64) float64 { return -math.Log10(1 - math.Min(p, 99.999)/100) }
+	minNs, maxNs := math.MaxFloat64, 0.0
+	maxNines := nines(99.99)
+	for _, s := range series {
+		for _, point := range s.points {
+			if point.Ns > 0 {
+				minNs = math.Min(minNs, point.Ns)
+				maxNs = math.Max(maxNs, point.Ns)
+			}
+		}
+	}
+	if maxNs == 0 {
+		return ""
+	}
+	logMin, logMax := math.Floor(math.Log10(minNs)), math.Ceil(math.Log10(maxNs))
+	if logMax == logMin {
+		logMax++
+	}
+	x := func(p float64) float64 { return left + nines(p)/maxNines*plotW }
+	y := func(ns float64) float64 { return top + plotH - (math.Log10(ns)-logMin)/(logMax-logMin)*plotH }
+
+	// The legend goes below the plot, three entries per row
+	totalHeight := height + 10 + 18*float64((len(series)+2)/3)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `
+        <svg width="%.0f" height="%.0f" viewBox="0 0 %.0f %.0f" xmlns="http://www.w3.org/2000/svg" style="font-family: Arial, sans-serif; font-size: 11px;">`, width, totalHeight, width, totalHeight)
+	// Grid and axes
+	for e := logMin; e <= logMax; e++ {
+		fmt.Fprintf(&b, `<line x1="%.1f" y1="%.1f" x2="%.1f" y2="%.1f" stroke="#ddd"/><text x="%.1f" y="%.1f" text-anchor="end">%s</text>`,
+			left, y(math.Pow(10, e)), left+plotW, y(math.Pow(10, e)), left-6, y(math.Pow(10, e))+4, formatLatencyNs(math.Pow(10, e)))
+	}
+	for _, p := range []float64{0, 50, 90, 99, 99.9, 99.99} {
+		fmt.Fprintf(&b, `<line x1="%.1f" y1="%.1f" x2="%.1f" y2="%.1f" stroke="#ddd"/><text x="%.1f" y="%.1f" text-anchor="middle">p%g</text>`,
+			x(p), top, x(p), top+plotH, x(p), top+plotH+16, p)
+	}
+	fmt.Fprintf(&b, `<text x="%.1f" y="%.1f" text-anchor="middle">Completion latency percentile</text>`, left+plotW/2, top+plotH+32)
+
+	// One polyline per series
+	for i, s := range series {
+		color := fioChartColors[i%len(fioChartColors)]
+		var points []string
+		for _, point := range s.points {
+			if point.Ns > 0 && point.Percentile <= 99.99 {
+				points = append(points, fmt.Sprintf("%.1f,%.1f", x(point.Percentile), y(point.Ns)))
+			}
+		}
+		fmt.Fprintf(&b, `<polyline fill="none" stroke="%s" stroke-width="2" points="%s"/>`, color, strings.Join(points, " "))
+		legendX, legendY := left+float64(i%3)*plotW/3, height+10+float64(i/3)*18
+		fmt.Fprintf(&b, `<rect x="%.1f" y="%.1f" width="12" height="4" fill="%s"/><text x="%.1f" y="%.1f">%s</text>`,
+			legendX, legendY-4, color, legendX+16, legendY+1, s.label)
+	}
+	b.WriteString(`</svg>`)
+	return b.String()
+}
+
+// fioHistogramSVG draws the power-of-two completion latency histogram of one test direction
+func fioHistogramSVG(histogram []FioLatencyBucket) string {
+	if len(histogram) == 0 {
+		return ""
+	}
+	const width, height = 360.0, 150.0
+	const left, bottom, top = 10.0, 30.0, 10.0
+	plotH := height - bottom - top
+	barW := (width - 2*left) / float64(len(histogram))
+
+	var maxCount uint64
+	for _, bucket := range histogram {
+		if bucket.Count > maxCount {
+			maxCount = bucket.Count
+		}
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg width="%.0f" height="%.0f" xmlns="http://www.w3.org/2000/svg" style="font-family: Arial, sans-serif; font-size: 9px;">`, width, height)
+	for i, bucket := range histogram {
+		barH := float64(bucket.Count) / float64(maxCount) * plotH
+		bx := left + float64(i)*barW
+		fmt.Fprintf(&b, `<rect x="%.1f" y="%.1f" width="%.1f" height="%.1f" fill="#3498db"><title>&lt;= %s: %d I/Os</title></rect>`,
+			bx+1, top+plotH-barH, math.Max(barW-2, 1), barH, formatLatencyNs(float64(bucket.UpperNs)), bucket.Count)
+		if i%max(1, len(histogram)/6) == 0 {
+			fmt.Fprintf(&b, `<text x="%.1f" y="%.1f" text-anchor="middle">%s</text>`, bx+barW/2, height-bottom+14, formatLatencyNs(float64(bucket.UpperNs)))
+		}
+	}
+	b.WriteString(`</svg>`)
+	return b.String()
+}
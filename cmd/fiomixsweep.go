@@ -0,0 +1,179 @@
+package cmd
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// fioMixSweepJobs is the number of fio jobs of every mix sweep point, like the standard mixed test
+const fioMixSweepJobs = 4
+
+// FioMixSweep holds one random I/O workload run at several read/write ratios
+type FioMixSweep struct {
+	BlockSize string        // Block size of every point
+	IODepth   int           // Queue depth per job
+	NumJobs   int           // fio jobs
+	Engine    string        // I/O engine used
+	Points    []FioMixPoint // One per ratio, from most reads to most writes
+}
+
+// FioMixPoint is the result at one read/write ratio
+type FioMixPoint struct {
+	ReadPercent int           // Share of reads (100 = randread, 0 = randwrite)
+	Result      FioTestResult // Totals and per-direction results
+}
+
+// fioMixScenario builds the scenario for one ratio; every ratio, including 100 and 0, runs as randrw
+func fioMixScenario(readPercent int, bs string, iodepth int) fioScenario {
+	scenario := fioScenario{
+		name:        fmt.Sprintf("MixSweep_%s_R%dW%d_QD%d", strings.ToUpper(bs), readPercent, 100-readPercent, iodepth),
+		rw:          "randrw",
+		bs:          bs,
+		iodepth:     iodepth,
+		numjobs:     fioMixSweepJobs,
+		rwmixread:   readPercent,
+		description: fmt.Sprintf("%s Random %d%% Read %d%% Write (QD=%d)", strings.ToUpper(bs), readPercent, 100-readPercent, iodepth),
+		category:    "mix_sweep",
+	}
+	return scenario
+}
+
+// runFioMixSweep runs random I/O at each read percentage, from most reads to most writes
+func runFioMixSweep(readPercents []int, bs string, iodepth, runtime int, engine fioEngine, testFilePath, testSize string, targetArgs []string) FioMixSweep {
+	sweep := FioMixSweep{BlockSize: bs, IODepth: iodepth, NumJobs: fioMixSweepJobs, Engine: engine.label}
+	readPercents = append([]int{}, readPercents...)
+	sort.Sort(sort.Reverse(sort.IntSlice(readPercents)))
+	fmt.Printf("      Read/write mix sweep: %s random, QD=%d, %d jobs, %ds per ratio (engine=%s)\n", bs, iodepth, fioMixSweepJobs, runtime, engine.label)
+	for _, readPercent := range readPercents {
+		scenario := fioMixScenario(readPercent, bs, iodepth)
+		scenario.runtime = runtime
+		point := FioMixPoint{ReadPercent: readPercent, Result: newFioTestResult(scenario, engine)}
+		job, err := runFioJob(fioScenarioArgs(scenario, engine, testFilePath, testSize, false, targetArgs))
+		if err != nil {
+			point.Result.IOPS, point.Result.BandwidthMB, point.Result.LatencyUs = -1, -1, -1
+			fmt.Printf("        R%3d/W%-3d FAIL: %v\n", readPercent, 100-readPercent, err)
+		} else {
+			setFioJobResult(&point.Result, job)
+			fmt.Printf("        R%3d/W%-3d %10.0f IOPS %10.2f MB/s  read p99 %-10s write p99 %s\n", readPercent, 100-readPercent,
+				point.Result.IOPS, point.Result.BandwidthMB, fioMixP99(point.Result.Read), fioMixP99(point.Result.Write))
+		}
+		sweep.Points = append(sweep.Points, point)
+	}
+	return sweep
+}
+
+// fioMixP99 formats the p99 completion latency of a direction, or "-" if it issued no I/O
+func fioMixP99(dir FioDirectionResult) string {
+	if dir.IOPS <= 0 {
+		return "-"
+	}
+	return formatLatencyNs(dir.Latency.Completion.P99Ns)
+}
+
+// printFioMixSweep prints the per-direction results of each ratio
+func printFioMixSweep(indent string, sweep FioMixSweep) {
+	if len(sweep.Points) == 0 {
+		return
+	}
+	fmt.Printf("%sRead/write mix sweep (%s random, QD=%d, %d jobs, engine=%s):\n", indent, sweep.BlockSize, sweep.IODepth, sweep.NumJobs, sweep.Engine)
+	fmt.Printf("%s  %-10s | %-10s | %-10s | %-10s | %-10s | %-10s | %-10s\n", indent, "Read/Write", "IOPS", "MB/s", "Read IOPS", "Read p99", "Write IOPS", "Write p99")
+	for _, point := range sweep.Points {
+		ratio := fmt.Sprintf("%d/%d", point.ReadPercent, 100-point.ReadPercent)
+		test := point.Result
+		if test.IOPS < 0 {
+			fmt.Printf("%s  %-10s | FAIL\n", indent, ratio)
+			continue
+		}
+		fmt.Printf("%s  %-10s | %-10.0f | %-10.2f | %-10.0f | %-10s | %-10.0f | %-10s\n", indent, ratio, test.IOPS, test.BandwidthMB,
+			test.Read.IOPS, fioMixP99(test.Read), test.Write.IOPS, fioMixP99(test.Write))
+	}
+}
+
+// fioMixSweepHTML renders the mix sweep of a device as a chart and a table
+func fioMixSweepHTML(sweep FioMixSweep) string {
+	if len(sweep.Points) == 0 {
+		return ""
+	}
+	html := `
+        <h4>Read/Write Mix Sweep</h4>
+        <p>` + fmt.Sprintf("%s random I/O, QD=%d, %d jobs, engine %s.", sweep.BlockSize, sweep.IODepth, sweep.NumJobs, sweep.Engine) + `</p>` +
+		fioMixSweepSVG(sweep) + `
+        <table>
+            <tr><th>Read / Write</th><th>Total IOPS</th><th>Bandwidth (MB/s)</th><th>Read IOPS</th><th>Read p99</th><th>Write IOPS</th><th>Write p99</th></tr>`
+	for _, point := range sweep.Points {
+		ratio := fmt.Sprintf("%d%% / %d%%", point.ReadPercent, 100-point.ReadPercent)
+		test := point.Result
+		if test.IOPS < 0 {
+			html += `
+            <tr><td>` + ratio + `</td><td colspan="6">FAIL</td></tr>`
+			continue
+		}
+		html += fmt.Sprintf(`
+            <tr><td>%s</td><td class="highlight">%.0f</td><td>%.2f</td><td>%.0f</td><td>%s</td><td>%.0f</td><td>%s</td></tr>`,
+			ratio, test.IOPS, test.BandwidthMB, test.Read.IOPS, fioMixP99(test.Read), test.Write.IOPS, fioMixP99(test.Write))
+	}
+	return html + `
+        </table>`
+}
+
+// fioMixSweepSVG plots total, read and write IOPS against the read percentage, 100% reads on the left
+func fioMixSweepSVG(sweep FioMixSweep) string {
+	const width, height = 760.0, 260.0
+	const left, right, top, bottom = 70.0, 20.0, 20.0, 40.0
+	plotW, plotH := width-left-right, height-top-bottom
+
+	maxIOPS := 0.0
+	for _, point := range sweep.Points {
+		maxIOPS = math.Max(maxIOPS, point.Result.IOPS)
+	}
+	if maxIOPS <= 0 {
+		return ""
+	}
+	maxIOPS *= 1.1
+	x := func(readPercent int) float64 { return left + float64(100-readPercent)/100*plotW }
+	y := func(v float64) float64 { return top + plotH - v/maxIOPS*plotH }
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `
+        <svg width="%.0f" height="%.0f" xmlns="http://www.w3.org/2000/svg" style="font-family: Arial, sans-serif; font-size: 11px;">`, width, height+20)
+	for i := 0; i <= 4; i++ {
+		v := maxIOPS * float64(i) / 4
+		fmt.Fprintf(&b, `<line x1="%.1f" y1="%.1f" x2="%.1f" y2="%.1f" stroke="#ddd"/><text x="%.1f" y="%.1f" text-anchor="end">%.0f</text>`,
+			left, y(v), left+plotW, y(v), left-6, y(v)+4, v)
+	}
+	for _, point := range sweep.Points {
+		fmt.Fprintf(&b, `<line x1="%.1f" y1="%.1f" x2="%.1f" y2="%.1f" stroke="#ddd"/><text x="%.1f" y="%.1f" text-anchor="middle">%d/%d</text>`,
+			x(point.ReadPercent), top, x(point.ReadPercent), top+plotH, x(point.ReadPercent), top+plotH+16, point.ReadPercent, 100-point.ReadPercent)
+	}
+	fmt.Fprintf(&b, `<text x="%.1f" y="%.1f" text-anchor="middle">Read/write mix (%%) against IOPS</text>`, left+plotW/2, top+plotH+32)
+
+	series := []struct {
+		label string
+		value func(FioTestResult) float64
+	}{
+		{"Total", func(t FioTestResult) float64 { return t.IOPS }},
+		{"Read", func(t FioTestResult) float64 { return t.Read.IOPS }},
+		{"Write", func(t FioTestResult) float64 { return t.Write.IOPS }},
+	}
+	for i, s := range series {
+		color := fioChartColors[i%len(fioChartColors)]
+		var points []string
+		for _, point := range sweep.Points {
+			if point.Result.IOPS < 0 {
+				continue
+			}
+			v := s.value(point.Result)
+			points = append(points, fmt.Sprintf("%.1f,%.1f", x(point.ReadPercent), y(v)))
+			fmt.Fprintf(&b, `<circle cx="%.1f" cy="%.1f" r="3" fill="%s"><title>%s at %d/%d: %.0f IOPS</title></circle>`,
+				x(point.ReadPercent), y(v), color, s.label, point.ReadPercent, 100-point.ReadPercent, v)
+		}
+		fmt.Fprintf(&b, `<polyline fill="none" stroke="%s" stroke-width="2" points="%s"/>`, color, strings.Join(points, " "))
+		legendX := left + float64(i)*plotW/3
+		fmt.Fprintf(&b, `<rect x="%.1f" y="%.1f" width="12" height="4" fill="%s"/><text x="%.1f" y="%.1f">%s IOPS</text>`,
+			legendX, height+6, color, legendX+16, height+11, s.label)
+	}
+	b.WriteString(`</svg>`)
+	return b.String()
+}
@@ -0,0 +1,220 @@
+package cmd
+
+import (
+	"fmt"
+	htmlpkg "html"
+	"strings"
+	"sync"
+)
+
+// fioParallelScalingWarn is the share of its solo IOPS below which a device is flagged in the parallel run
+const fioParallelScalingWarn = 90.0
+
+// fioParallelTarget is a target that passed the per-device setup and can join the parallel run
+type fioParallelTarget struct {
+	devicePath   string
+	deviceName   string
+	mountPoint   string
+	testFilePath string
+	testSize     string
+	readOnly     bool     // Auto-detected raw device, read tests only
+	fioArgs      []string // Options the target needs (network filesystems)
+	soloIndex    int      // Index of the per-device results in SystemInfo.FioResults
+}
+
+// FioParallelResult holds one scenario run on all targets at the same time
+type FioParallelResult struct {
+	TestName        string                    // Scenario name (e.g., "4K_RandRead_QD64")
+	Description     string                    // Scenario description
+	Devices         []FioParallelDeviceResult // Per-device results of the simultaneous run
+	IOPS            float64                   // Aggregate IOPS of all devices
+	BandwidthMB     float64                   // Aggregate bandwidth in MB/s
+	SoloIOPS        float64                   // Sum of the IOPS each device reached when tested alone
+	SoloBandwidthMB float64                   // Sum of the solo bandwidths
+	ScalingPercent  float64                   // Parallel IOPS as a percentage of SoloIOPS, over devices with a solo result (100 = linear scaling)
+	ErrorMessage    string                    // Error message if any device failed
+}
+
+// FioParallelDeviceResult holds one device's share of a parallel run
+type FioParallelDeviceResult struct {
+	DevicePath     string        // Path of the device or mount point
+	DeviceModel    string        // Display name of the target
+	MountPoint     string        // Mount point tested, or "direct"/"raw" for device nodes
+	Result         FioTestResult // Metrics while all devices ran together
+	SoloIOPS       float64       // IOPS of the same scenario run alone (0 if not available)
+	ScalingPercent float64       // Parallel IOPS as a percentage of SoloIOPS
+}
+
+// runFioParallel runs each scenario on all targets simultaneously, one fio process per target,
+// and compares the aggregate with the sum of the per-device results
+func runFioParallel(targets []fioParallelTarget, scenarios []fioScenario, engine fioEngine, solo []FioDeviceResult) []FioParallelResult {
+	fmt.Printf("\n    Starting parallel FIO benchmarks on %d targets (engine %s)\n", len(targets), engine.label)
+	fmt.Println("      ------------------------------------------------------------------------------------------------")
+	fmt.Printf("      %-32s | %-7s | %-12s | %-18s | %-10s\n", "Test Type", "Devices", "IOPS", "Bandwidth (MB/s)", "Scaling")
+	fmt.Println("      ------------------------------------------------------------------------------------------------")
+
+	var results []FioParallelResult
+	defaultEngine := engine
+	for _, scenario := range scenarios {
+		engine := fioScenarioEngines(scenario, []fioEngine{defaultEngine})[0]
+		var participants []fioParallelTarget
+		for _, target := range targets {
+			if !target.readOnly || !fioScenarioWrites(scenario) {
+				participants = append(participants, target)
+			}
+		}
+		if len(participants) < 2 {
+			continue
+		}
+
+		result := FioParallelResult{
+			TestName:    scenario.name,
+			Description: scenario.description,
+			Devices:     make([]FioParallelDeviceResult, len(participants)),
+		}
+		errs := make([]error, len(participants))
+
+		// Each run is bounded like its solo run (--size or --runtime, whichever comes first), so the
+		// scaling percentage compares like with like
+		var wg sync.WaitGroup
+		for i, target := range participants {
+			wg.Add(1)
+			go func(i int, target fioParallelTarget) {
+				defer wg.Done()
+				device := FioParallelDeviceResult{
+					DevicePath:  target.devicePath,
+					DeviceModel: target.deviceName,
+					MountPoint:  target.mountPoint,
+					Result:      newFioTestResult(scenario, engine),
+				}
+				job, err := runFioJob(fioScenarioArgs(scenario, engine, target.testFilePath, target.testSize, target.readOnly, target.fioArgs))
+				if err != nil {
+					errs[i] = err
+					device.Result.IOPS, device.Result.BandwidthMB, device.Result.LatencyUs = -1, -1, -1
+				} else {
+					setFioJobResult(&device.Result, job)
+				}
+				result.Devices[i] = device
+			}(i, target)
+		}
+		wg.Wait()
+
+		var failed []string
+		var comparableIOPS float64 // Parallel IOPS of the devices that also have a solo result
+		for i, target := range participants {
+			if errs[i] != nil {
+				failed = append(failed, fmt.Sprintf("%s: %v", target.devicePath, errs[i]))
+				continue
+			}
+			device := &result.Devices[i]
+			result.IOPS += device.Result.IOPS
+			result.BandwidthMB += device.Result.BandwidthMB
+
+			if target.soloIndex >= 0 && target.soloIndex < len(solo) {
+				for _, test := range solo[target.soloIndex].TestResults {
+					if test.TestName == scenario.name && test.Engine == engine.label && test.IOPS > 0 {
+						device.SoloIOPS = test.IOPS
+						device.ScalingPercent = device.Result.IOPS / test.IOPS * 100
+						result.SoloIOPS += test.IOPS
+						result.SoloBandwidthMB += test.BandwidthMB
+						comparableIOPS += device.Result.IOPS
+					}
+				}
+			}
+		}
+		if result.SoloIOPS > 0 {
+			result.ScalingPercent = comparableIOPS / result.SoloIOPS * 100
+		}
+		if len(failed) > 0 {
+			result.ErrorMessage = strings.Join(failed, "; ")
+		}
+
+		scaling := "N/A"
+		if result.ScalingPercent > 0 {
+			scaling = fmt.Sprintf("%.1f%%", result.ScalingPercent)
+		}
+		fmt.Printf("      %-32s | %-7d | %-12.0f | %-18.2f | %-10s\n",
+			scenario.name, len(participants), result.IOPS, result.BandwidthMB, scaling)
+		printFioParallelDevices("        ", result)
+		if result.ErrorMessage != "" {
+			fmt.Printf("        Error: %s\n", result.ErrorMessage)
+		}
+		results = append(results, result)
+	}
+	fmt.Println("      ------------------------------------------------------------------------------------------------")
+	return results
+}
+
+// fioParallelDeviceLabel identifies the device in the breakdown; several targets may share a device path
+func fioParallelDeviceLabel(d FioParallelDeviceResult) string {
+	if strings.HasPrefix(d.MountPoint, "/") {
+		return d.DevicePath + " (" + d.MountPoint + ")"
+	}
+	return d.DevicePath
+}
+
+// printFioParallelDevices prints the per-device breakdown of a parallel run, flagging devices that
+// lost more than (100 - fioParallelScalingWarn)% of their solo IOPS
+func printFioParallelDevices(indent string, result FioParallelResult) {
+	for _, device := range result.Devices {
+		if device.Result.IOPS < 0 {
+			fmt.Printf("%s%-30s FAIL\n", indent, fioParallelDeviceLabel(device))
+			continue
+		}
+		line := fmt.Sprintf("%s%-30s %10.0f IOPS %10.2f MB/s  p99 %-10s", indent, fioParallelDeviceLabel(device),
+			device.Result.IOPS, device.Result.BandwidthMB, formatLatencyNs(fioCompletionP99Ns(device.Result)))
+		if device.SoloIOPS > 0 {
+			color := colorGreen
+			if device.ScalingPercent < fioParallelScalingWarn {
+				color = colorYellow
+			}
+			line += fmt.Sprintf("  %s%.1f%% of solo%s", color, device.ScalingPercent, colorReset)
+		}
+		fmt.Println(line)
+	}
+}
+
+// fioParallelHTML renders the parallel results as a table with one sub-row per device
+func fioParallelHTML(results []FioParallelResult) string {
+	if len(results) == 0 {
+		return ""
+	}
+	html := `
+    <div class="section">
+        <h2>Parallel Multi-Device FIO Results</h2>
+        <p>Each scenario ran on all targets at the same time. Scaling compares the aggregate with the sum of the per-device results; a device well below 100% of its solo result points at a shared bottleneck (PCIe switch, CPU, interrupts).</p>
+        <table>
+            <tr><th>Test</th><th>Device</th><th>IOPS</th><th>Bandwidth (MB/s)</th><th>p99 Latency</th><th>Solo IOPS</th><th>Scaling</th></tr>`
+	for _, result := range results {
+		scaling := "N/A"
+		if result.ScalingPercent > 0 {
+			scaling = fmt.Sprintf("%.1f%%", result.ScalingPercent)
+		}
+		html += fmt.Sprintf(`
+            <tr><td><strong>%s</strong></td><td><strong>Aggregate (%d devices)</strong></td><td><strong>%.0f</strong></td><td><strong>%.2f</strong></td><td></td><td>%.0f</td><td><strong>%s</strong></td></tr>`,
+			htmlpkg.EscapeString(result.TestName), len(result.Devices), result.IOPS, result.BandwidthMB, result.SoloIOPS, scaling)
+		for _, device := range result.Devices {
+			if device.Result.IOPS < 0 {
+				html += fmt.Sprintf(`
+            <tr><td></td><td>%s</td><td colspan="5">FAIL</td></tr>`, htmlpkg.EscapeString(fioParallelDeviceLabel(device)))
+				continue
+			}
+			deviceScaling := "N/A"
+			if device.SoloIOPS > 0 {
+				class := "highlight"
+				if device.ScalingPercent < fioParallelScalingWarn {
+					class = "warning"
+				}
+				deviceScaling = fmt.Sprintf(`<span class="%s">%.1f%%</span>`, class, device.ScalingPercent)
+			}
+			html += fmt.Sprintf(`
+            <tr><td></td><td>%s</td><td>%.0f</td><td>%.2f</td><td>%s</td><td>%.0f</td><td>%s</td></tr>`,
+				htmlpkg.EscapeString(fioParallelDeviceLabel(device)), device.Result.IOPS, device.Result.BandwidthMB,
+				formatLatencyNs(fioCompletionP99Ns(device.Result)), device.SoloIOPS, deviceScaling)
+		}
+	}
+	html += `
+        </table>
+    </div>`
+	return html
+}
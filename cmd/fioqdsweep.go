@@ -0,0 +1,228 @@
+package cmd
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// fioQDSweepDepths are the queue depths of every curve in the queue-depth sweep
+var fioQDSweepDepths = []int{1, 2, 4, 8, 16, 32, 64, 128, 256}
+
+// FioQDSweepCurve is IOPS against p99 latency over increasing queue depth, at a fixed number of jobs
+type FioQDSweepCurve struct {
+	Workload  string            // fio rw and block size (e.g., "4k randread")
+	Engine    string            // I/O engine used
+	NumJobs   int               // fio jobs, each with the queue depth of the point
+	Points    []FioQDSweepPoint // One per queue depth, in increasing order
+	KneeIndex int               // Index of the knee point in Points, -1 if none was found
+}
+
+// FioQDSweepPoint is one queue depth of a curve
+type FioQDSweepPoint struct {
+	IODepth        int     // Queue depth per job
+	OutstandingIOs int     // IODepth x NumJobs
+	IOPS           float64 // -1 if the run failed
+	BandwidthMB    float64
+	MeanLatencyNs  float64 // Mean completion latency
+	P99LatencyNs   float64 // 99th percentile completion latency
+	IOPSGainPct    float64 // IOPS change from the previous point, in percent
+	P99GainPct     float64 // p99 latency change from the previous point, in percent
+}
+
+// runFioQDSweep runs rw at each queue depth for every job count and finds the knee of each curve
+func runFioQDSweep(rw, bs string, jobs []int, runtime int, engine fioEngine, testFilePath, testSize string, readOnly bool, targetArgs []string) []FioQDSweepCurve {
+	var curves []FioQDSweepCurve
+	for _, numjobs := range jobs {
+		curve := FioQDSweepCurve{Workload: bs + " " + rw, Engine: engine.label, NumJobs: numjobs, KneeIndex: -1}
+		fmt.Printf("      Queue-depth sweep: %s, %d job(s), QD %d-%d, %ds per point (engine=%s)\n", curve.Workload, numjobs,
+			fioQDSweepDepths[0], fioQDSweepDepths[len(fioQDSweepDepths)-1], runtime, engine.label)
+		for _, depth := range fioQDSweepDepths {
+			scenario := fioScenario{
+				name:        fmt.Sprintf("QDSweep_%s_%s_QD%d_J%d", strings.ToUpper(bs), rw, depth, numjobs),
+				rw:          rw,
+				bs:          bs,
+				iodepth:     depth,
+				numjobs:     numjobs,
+				rwmixread:   50,
+				description: fmt.Sprintf("%s %s (QD=%d, jobs=%d)", strings.ToUpper(bs), rw, depth, numjobs),
+				category:    "qd_sweep",
+				runtime:     runtime,
+				extraArgs:   []string{"--time_based"},
+			}
+			point := FioQDSweepPoint{IODepth: depth, OutstandingIOs: depth * numjobs}
+			result := newFioTestResult(scenario, engine)
+			job, err := runFioJob(fioScenarioArgs(scenario, engine, testFilePath, testSize, readOnly, targetArgs))
+			if err != nil {
+				point.IOPS, point.BandwidthMB, point.MeanLatencyNs, point.P99LatencyNs = -1, -1, -1, -1
+				fmt.Printf("        QD %-4d FAIL: %v\n", depth, err)
+				curve.Points = append(curve.Points, point)
+				continue
+			}
+			setFioJobResult(&result, job)
+			point.IOPS, point.BandwidthMB = result.IOPS, result.BandwidthMB
+			point.MeanLatencyNs, point.P99LatencyNs = result.LatencyUs*1000, fioCompletionP99Ns(result)
+			if n := len(curve.Points); n > 0 {
+				if prev := curve.Points[n-1]; prev.IOPS > 0 && prev.P99LatencyNs > 0 {
+					point.IOPSGainPct = (point.IOPS/prev.IOPS - 1) * 100
+					point.P99GainPct = (point.P99LatencyNs/prev.P99LatencyNs - 1) * 100
+				}
+			}
+			fmt.Printf("        QD %-4d %10.0f IOPS %10.2f MB/s  p99 %-10s (IOPS %+.0f%%, p99 %+.0f%%)\n", depth, point.IOPS,
+				point.BandwidthMB, formatLatencyNs(point.P99LatencyNs), point.IOPSGainPct, point.P99GainPct)
+			curve.Points = append(curve.Points, point)
+		}
+		curve.KneeIndex = fioQDSweepKnee(curve.Points)
+		if curve.KneeIndex >= 0 {
+			knee := curve.Points[curve.KneeIndex]
+			fmt.Printf("      %sKnee at QD %d x %d job(s): %.0f IOPS, p99 %s%s\n", colorGreen, knee.IODepth, numjobs,
+				knee.IOPS, formatLatencyNs(knee.P99LatencyNs), colorReset)
+		}
+		curves = append(curves, curve)
+	}
+	return curves
+}
+
+// fioQDSweepKnee returns the point with the most IOPS per unit of p99 latency. Below it, doubling the
+// queue depth adds proportionally more throughput than latency; beyond it, latency rises faster.
+func fioQDSweepKnee(points []FioQDSweepPoint) int {
+	knee, best := -1, 0.0
+	for i, point := range points {
+		if point.IOPS <= 0 || point.P99LatencyNs <= 0 {
+			continue
+		}
+		if efficiency := point.IOPS / point.P99LatencyNs; efficiency > best {
+			knee, best = i, efficiency
+		}
+	}
+	return knee
+}
+
+// printFioQDSweep prints each curve of a device with its knee marked
+func printFioQDSweep(indent string, curves []FioQDSweepCurve) {
+	for _, curve := range curves {
+		fmt.Printf("%sQueue-depth sweep (%s, %d job(s), engine=%s):\n", indent, curve.Workload, curve.NumJobs, curve.Engine)
+		fmt.Printf("%s  %-6s | %-11s | %-12s | %-12s | %-12s | %-8s | %-8s\n", indent, "QD", "Outstanding", "IOPS", "MB/s", "p99", "IOPS +", "p99 +")
+		for i, point := range curve.Points {
+			if point.IOPS < 0 {
+				fmt.Printf("%s  %-6d | %-11d | FAIL\n", indent, point.IODepth, point.OutstandingIOs)
+				continue
+			}
+			marker := ""
+			if i == curve.KneeIndex {
+				marker = colorGreen + " <- knee" + colorReset
+			}
+			fmt.Printf("%s  %-6d | %-11d | %-12.0f | %-12.2f | %-12s | %+7.0f%% | %+7.0f%%%s\n", indent, point.IODepth, point.OutstandingIOs,
+				point.IOPS, point.BandwidthMB, formatLatencyNs(point.P99LatencyNs), point.IOPSGainPct, point.P99GainPct, marker)
+		}
+	}
+}
+
+// fioQDSweepHTML renders the queue-depth curves of a device as a chart and one table per curve
+func fioQDSweepHTML(curves []FioQDSweepCurve) string {
+	if len(curves) == 0 {
+		return ""
+	}
+	html := `
+        <h4>Queue-Depth Sweep</h4>
+        <p>p99 completion latency against IOPS as the queue depth doubles from 1 to 256. The knee is the point with the most IOPS per unit of p99 latency; beyond it, latency rises faster than throughput.</p>` +
+		fioQDSweepSVG(curves)
+	for _, curve := range curves {
+		html += `
+        <table>
+            <tr><th colspan="7">` + fmt.Sprintf("%s, %d job(s), engine %s", curve.Workload, curve.NumJobs, curve.Engine) + `</th></tr>
+            <tr><th>QD</th><th>Outstanding I/Os</th><th>IOPS</th><th>Bandwidth (MB/s)</th><th>Mean Latency</th><th>p99 Latency</th><th>Change (IOPS / p99)</th></tr>`
+		for i, point := range curve.Points {
+			if point.IOPS < 0 {
+				html += fmt.Sprintf(`
+            <tr><td>%d</td><td>%d</td><td colspan="5">FAIL</td></tr>`, point.IODepth, point.OutstandingIOs)
+				continue
+			}
+			depth := fmt.Sprintf("%d", point.IODepth)
+			if i == curve.KneeIndex {
+				depth = `<span class="highlight">` + depth + ` (knee)</span>`
+			}
+			html += fmt.Sprintf(`
+            <tr><td>%s</td><td>%d</td><td>%.0f</td><td>%.2f</td><td>%s</td><td>%s</td><td>%+.0f%% / %+.0f%%</td></tr>`,
+				depth, point.OutstandingIOs, point.IOPS, point.BandwidthMB, formatLatencyNs(point.MeanLatencyNs),
+				formatLatencyNs(point.P99LatencyNs), point.IOPSGainPct, point.P99GainPct)
+		}
+		html += `
+        </table>`
+	}
+	return html
+}
+
+// fioQDSweepSVG plots p99 latency (log scale) against IOPS, one line per job count, with the knees circled
+func fioQDSweepSVG(curves []FioQDSweepCurve) string {
+	const width, height = 760.0, 320.0
+	const left, right, top, bottom = 70.0, 20.0, 20.0, 40.0
+	plotW, plotH := width-left-right, height-top-bottom
+
+	maxIOPS, minNs, maxNs := 0.0, math.MaxFloat64, 0.0
+	for _, curve := range curves {
+		for _, point := range curve.Points {
+			if point.IOPS > 0 && point.P99LatencyNs > 0 {
+				maxIOPS = math.Max(maxIOPS, point.IOPS)
+				minNs = math.Min(minNs, point.P99LatencyNs)
+				maxNs = math.Max(maxNs, point.P99LatencyNs)
+			}
+		}
+	}
+	if maxIOPS == 0 {
+		return ""
+	}
+	maxIOPS *= 1.1
+	logMin, logMax := math.Floor(math.Log10(minNs)), math.Ceil(math.Log10(maxNs))
+	if logMax == logMin {
+		logMax++
+	}
+	x := func(iops float64) float64 { return left + iops/maxIOPS*plotW }
+	y := func(ns float64) float64 { return top + plotH - (math.Log10(ns)-logMin)/(logMax-logMin)*plotH }
+
+	// The legend goes below the plot, three entries per row
+	totalHeight := height + 10 + 18*float64((len(curves)+2)/3)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `
+        <svg width="%.0f" height="%.0f" viewBox="0 0 %.0f %.0f" xmlns="http://www.w3.org/2000/svg" style="font-family: Arial, sans-serif; font-size: 11px;">`, width, totalHeight, width, totalHeight)
+	// Grid and axes
+	for e := logMin; e <= logMax; e++ {
+		fmt.Fprintf(&b, `<line x1="%.1f" y1="%.1f" x2="%.1f" y2="%.1f" stroke="#ddd"/><text x="%.1f" y="%.1f" text-anchor="end">%s</text>`,
+			left, y(math.Pow(10, e)), left+plotW, y(math.Pow(10, e)), left-6, y(math.Pow(10, e))+4, formatLatencyNs(math.Pow(10, e)))
+	}
+	for i := 0; i <= 4; i++ {
+		v := maxIOPS * float64(i) / 4
+		fmt.Fprintf(&b, `<line x1="%.1f" y1="%.1f" x2="%.1f" y2="%.1f" stroke="#ddd"/><text x="%.1f" y="%.1f" text-anchor="middle">%.0f</text>`,
+			x(v), top, x(v), top+plotH, x(v), top+plotH+16, v)
+	}
+	fmt.Fprintf(&b, `<text x="%.1f" y="%.1f" text-anchor="middle">IOPS (p99 completion latency on the vertical axis)</text>`, left+plotW/2, top+plotH+32)
+
+	// One polyline per curve, with a labelled dot per queue depth
+	for i, curve := range curves {
+		color := fioChartColors[i%len(fioChartColors)]
+		var points []string
+		for _, point := range curve.Points {
+			if point.IOPS > 0 && point.P99LatencyNs > 0 {
+				points = append(points, fmt.Sprintf("%.1f,%.1f", x(point.IOPS), y(point.P99LatencyNs)))
+			}
+		}
+		fmt.Fprintf(&b, `<polyline fill="none" stroke="%s" stroke-width="2" points="%s"/>`, color, strings.Join(points, " "))
+		for j, point := range curve.Points {
+			if point.IOPS <= 0 || point.P99LatencyNs <= 0 {
+				continue
+			}
+			px, py := x(point.IOPS), y(point.P99LatencyNs)
+			fmt.Fprintf(&b, `<circle cx="%.1f" cy="%.1f" r="3" fill="%s"><title>QD %d x %d: %.0f IOPS, p99 %s</title></circle><text x="%.1f" y="%.1f" font-size="9">%d</text>`,
+				px, py, color, point.IODepth, curve.NumJobs, point.IOPS, formatLatencyNs(point.P99LatencyNs), px+5, py-5, point.IODepth)
+			if j == curve.KneeIndex {
+				fmt.Fprintf(&b, `<circle cx="%.1f" cy="%.1f" r="8" fill="none" stroke="%s" stroke-width="2"><title>Knee</title></circle>`, px, py, color)
+			}
+		}
+		legendX, legendY := left+float64(i%3)*plotW/3, height+10+float64(i/3)*18
+		fmt.Fprintf(&b, `<rect x="%.1f" y="%.1f" width="12" height="4" fill="%s"/><text x="%.1f" y="%.1f">%s, %d job(s)</text>`,
+			legendX, legendY-4, color, legendX+16, legendY+1, curve.Workload, curve.NumJobs)
+	}
+	b.WriteString(`</svg>`)
+	return b.String()
+}
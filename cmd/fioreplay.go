@@ -0,0 +1,473 @@
+package cmd
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	htmlpkg "html"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// fioReplayIODepth is the queue depth fio replays with; the trace timing decides how much of it is used
+const fioReplayIODepth = 32
+
+// blktrace record layout (struct blk_io_trace in include/uapi/linux/blktrace_api.h)
+const (
+	blkTraceMagic      = 0x65617400 // "eat" + version byte
+	blkTraceHeaderSize = 48
+	blkTraceActIssue   = 7                     // __BLK_TA_ISSUE: request sent to the driver (D)
+	blkTraceActDone    = 8                     // __BLK_TA_COMPLETE: request completed (C)
+	blkTraceCatRead    = 1 << 16               // BLK_TC_READ << BLK_TC_SHIFT
+	blkTraceCatWrite   = 1 << 17               // BLK_TC_WRITE << BLK_TC_SHIFT
+	blkTraceCatSkip    = 1<<25 | 1<<26 | 1<<29 // BLK_TC_PC, BLK_TC_NOTIFY and BLK_TC_DISCARD events are not data I/O
+)
+
+// FioReplayResult compares a recorded I/O trace with its replay on one target
+type FioReplayResult struct {
+	TraceFile     string         // Trace given with --fio-replay
+	Format        string         // "blktrace", "iolog v2" or "iolog v3"
+	Speed         float64        // Replay speed relative to the recorded timing, 0 = as fast as possible
+	Remap         string         // How trace offsets were mapped onto the target: "wrap" or "scale"
+	Original      FioReplayTrace // What the trace recorded
+	Replayed      int            // I/Os replayed
+	Remapped      int            // I/Os whose offset was changed to fit the target
+	SkippedWrites int            // Writes left out because the target is read-only
+	Replay        FioTestResult  // Achieved IOPS, bandwidth and latency
+	ReplaySeconds float64        // Duration of the replay
+	TimingRatio   float64        // ReplaySeconds over the recorded duration divided by Speed; above 1 means the target fell behind
+	ErrorMessage  string         // Error message if the replay failed
+}
+
+// FioReplayTrace summarizes a recorded trace
+type FioReplayTrace struct {
+	IOs         int             // Read and write I/Os
+	Reads       int             // Read I/Os
+	Writes      int             // Write I/Os
+	Bytes       uint64          // Bytes transferred
+	Span        uint64          // Highest offset + length, per device or file
+	DurationSec float64         // First to last I/O, 0 if the trace carries no timing
+	IOPS        float64         // IOs / DurationSec
+	BandwidthMB float64         // Bytes / DurationSec in MiB/s
+	Latency     FioLatencyStats // Issue-to-completion latency recorded by blktrace; empty for iologs
+}
+
+// traceIO is one read or write of a trace, timed from the start of the trace
+type traceIO struct {
+	timeNs uint64
+	write  bool
+	offset uint64
+	length uint64
+}
+
+// ioTrace is a parsed trace file
+type ioTrace struct {
+	path    string
+	format  string
+	ios     []traceIO
+	summary FioReplayTrace
+}
+
+// loadIOTrace reads a binary blktrace (as written by blktrace or merged with blkparse -d) or a
+// fio iolog (version 2 or 3)
+func loadIOTrace(path string) (*ioTrace, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	reader := bufio.NewReader(file)
+	head, _ := reader.Peek(blkTraceHeaderSize)
+	trace := &ioTrace{path: path}
+	var latency *nativeLatencyHistogram
+	switch {
+	case len(head) >= 4 && (binary.LittleEndian.Uint32(head)&0xffffff00 == blkTraceMagic || binary.BigEndian.Uint32(head)&0xffffff00 == blkTraceMagic):
+		trace.format = "blktrace"
+		trace.ios, latency, err = parseBlktrace(reader)
+	case strings.HasPrefix(string(head), "fio version 2 iolog"), strings.HasPrefix(string(head), "fio version 3 iolog"):
+		trace.format = "iolog v" + string(head[12])
+		trace.ios, err = parseFioIolog(reader, head[12] == '3')
+	default:
+		return nil, fmt.Errorf("%s is neither a binary blktrace nor a fio iolog (version 2 or 3)", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	if len(trace.ios) == 0 {
+		return nil, fmt.Errorf("%s contains no read or write I/O", path)
+	}
+
+	summary := &trace.summary
+	for _, op := range trace.ios {
+		summary.IOs++
+		if op.write {
+			summary.Writes++
+		} else {
+			summary.Reads++
+		}
+		summary.Bytes += op.length
+		if end := op.offset + op.length; end > summary.Span {
+			summary.Span = end
+		}
+	}
+	summary.DurationSec = float64(trace.ios[len(trace.ios)-1].timeNs-trace.ios[0].timeNs) / 1e9
+	if summary.DurationSec > 0 {
+		summary.IOPS = float64(summary.IOs) / summary.DurationSec
+		summary.BandwidthMB = float64(summary.Bytes) / summary.DurationSec / (1024 * 1024)
+	}
+	if latency != nil {
+		summary.Latency = latency.stats()
+	}
+	return trace, nil
+}
+
+// parseBlktrace collects the issue (D) events of reads and writes and matches completions (C) to them
+// by device and sector for the recorded latency
+func parseBlktrace(r io.Reader) ([]traceIO, *nativeLatencyHistogram, error) {
+	type pending struct {
+		device uint32
+		sector uint64
+	}
+	issued := make(map[pending]uint64)
+	latency := &nativeLatencyHistogram{}
+	var ios []traceIO
+	var order binary.ByteOrder
+	var start uint64
+	header := make([]byte, blkTraceHeaderSize)
+	for {
+		if _, err := io.ReadFull(r, header); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, nil, fmt.Errorf("truncated record: %w", err)
+		}
+		if order == nil {
+			order = binary.LittleEndian
+			if binary.LittleEndian.Uint32(header)&0xffffff00 != blkTraceMagic {
+				order = binary.BigEndian
+			}
+		}
+		if order.Uint32(header)&0xffffff00 != blkTraceMagic {
+			return nil, nil, fmt.Errorf("bad record magic after %d I/Os", len(ios))
+		}
+		timeNs := order.Uint64(header[8:])
+		sector := order.Uint64(header[16:])
+		bytes := order.Uint32(header[24:])
+		action := order.Uint32(header[28:])
+		device := order.Uint32(header[36:])
+		pduLen := order.Uint16(header[46:])
+		if _, err := io.CopyN(io.Discard, r, int64(pduLen)); err != nil {
+			return nil, nil, fmt.Errorf("truncated record payload: %w", err)
+		}
+
+		if action&blkTraceCatSkip != 0 || action&(blkTraceCatRead|blkTraceCatWrite) == 0 || bytes == 0 {
+			continue
+		}
+		if start == 0 || timeNs < start {
+			start = timeNs
+		}
+		key := pending{device, sector}
+		switch action & 0xffff {
+		case blkTraceActIssue:
+			issued[key] = timeNs
+			ios = append(ios, traceIO{timeNs: timeNs, write: action&blkTraceCatWrite != 0, offset: sector * 512, length: uint64(bytes)})
+		case blkTraceActDone:
+			if issuedAt, ok := issued[key]; ok && timeNs >= issuedAt {
+				latency.record(timeNs-issuedAt, int(bytes))
+				delete(issued, key)
+			}
+		}
+	}
+	// Per-CPU buffers are merged by blkparse, but keep the issue order strict for the replay
+	sort.SliceStable(ios, func(i, j int) bool { return ios[i].timeNs < ios[j].timeNs })
+	for i := range ios {
+		ios[i].timeNs -= start
+	}
+	return ios, latency, nil
+}
+
+// parseFioIolog reads the read and write actions of a fio iolog. Version 2 lines are
+// "file action [offset length]", with "wait" actions carrying a delay in microseconds; version 3 lines
+// start with a timestamp in nanoseconds.
+func parseFioIolog(r io.Reader, v3 bool) ([]traceIO, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Scan() // Version line
+	var ios []traceIO
+	var clockNs uint64
+	for line := 2; scanner.Scan(); line++ {
+		fields := strings.Fields(scanner.Text())
+		if v3 {
+			if len(fields) == 0 {
+				continue
+			}
+			ts, err := strconv.ParseUint(fields[0], 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: bad timestamp '%s'", line, fields[0])
+			}
+			clockNs, fields = ts, fields[1:]
+		}
+		if len(fields) < 4 {
+			continue // File actions (add, open, close) and blank lines
+		}
+		offset, err1 := strconv.ParseUint(fields[2], 10, 64)
+		length, err2 := strconv.ParseUint(fields[3], 10, 64)
+		if err1 != nil || err2 != nil {
+			return nil, fmt.Errorf("line %d: bad offset or length", line)
+		}
+		switch fields[1] {
+		case "wait":
+			clockNs += offset * 1000
+		case "read", "write":
+			if length > 0 {
+				ios = append(ios, traceIO{timeNs: clockNs, write: fields[1] == "write", offset: offset, length: length})
+			}
+		}
+	}
+	return ios, scanner.Err()
+}
+
+// writeReplayIolog writes the trace as a version 2 iolog against target, for read_iolog. Offsets are
+// wrapped or scaled into targetSize, the recorded gaps become wait actions divided by speed (fio ignores
+// waits below 100us, so shorter gaps are carried over), and writes are dropped on read-only targets.
+func writeReplayIolog(trace *ioTrace, target string, targetSize uint64, speed float64, remap string, readOnly bool, result *FioReplayResult) (string, error) {
+	file, err := os.CreateTemp("", "hyprbench_replay_*.iolog")
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	w := bufio.NewWriter(file)
+	fmt.Fprintf(w, "fio version 2 iolog\n%s add\n%s open\n", target, target)
+	var emittedNs uint64
+	for _, op := range trace.ios {
+		if op.write && readOnly {
+			result.SkippedWrites++
+			continue
+		}
+		if op.length > targetSize {
+			continue
+		}
+		if speed > 0 && op.timeNs > emittedNs {
+			if waitUs := uint64(float64(op.timeNs-emittedNs) / speed / 1000); waitUs >= 100 {
+				fmt.Fprintf(w, "%s wait %d 0\n", target, waitUs)
+				emittedNs += uint64(float64(waitUs) * 1000 * speed)
+			}
+		}
+
+		offset := op.offset
+		if remap == "scale" && trace.summary.Span > targetSize {
+			offset = uint64(float64(op.offset) / float64(trace.summary.Span) * float64(targetSize-op.length))
+		} else if offset+op.length > targetSize {
+			offset %= targetSize - op.length + 1
+		}
+		offset -= offset % 512 // Keep O_DIRECT alignment after remapping
+		if offset != op.offset {
+			result.Remapped++
+		}
+
+		action := "read"
+		if op.write {
+			action = "write"
+		}
+		fmt.Fprintf(w, "%s %s %d %d\n", target, action, offset, op.length)
+		result.Replayed++
+	}
+	fmt.Fprintf(w, "%s close\n", target)
+	if err := w.Flush(); err != nil {
+		os.Remove(file.Name())
+		return "", err
+	}
+	return file.Name(), nil
+}
+
+// runFioReplay replays a trace on one target with fio read_iolog
+func runFioReplay(trace *ioTrace, speed float64, remap string, engine fioEngine, target string, targetSize uint64, readOnly bool, targetArgs []string) FioReplayResult {
+	result := FioReplayResult{TraceFile: trace.path, Format: trace.format, Speed: speed, Remap: remap, Original: trace.summary}
+	scenario := fioScenario{name: "Replay", description: "Replay of " + trace.path, category: "replay", iodepth: fioReplayIODepth, numjobs: 1}
+	result.Replay = newFioTestResult(scenario, engine)
+
+	fmt.Printf("      Replaying %s (%s, %d I/Os over %.1fs) at %s speed (engine=%s)\n", trace.path, trace.format,
+		trace.summary.IOs, trace.summary.DurationSec, fioReplaySpeedLabel(speed), engine.label)
+	if !readOnly {
+		// Reads must hit allocated blocks, as they would on the traced device
+		if err := nativeDiskLayout(target, int64(targetSize)); err != nil {
+			result.ErrorMessage = fmt.Sprintf("preparing %s: %v", target, err)
+			fmt.Printf("        %s\n", result.ErrorMessage)
+			return result
+		}
+	}
+	iolog, err := writeReplayIolog(trace, target, targetSize, speed, remap, readOnly, &result)
+	if err != nil {
+		result.ErrorMessage = err.Error()
+		return result
+	}
+	defer os.Remove(iolog)
+	if result.SkippedWrites > 0 {
+		fmt.Printf("        Skipping %d write(s): %s is read-only\n", result.SkippedWrites, target)
+	}
+	if result.Replayed == 0 {
+		result.ErrorMessage = "no I/O left to replay"
+		fmt.Printf("        %s\n", result.ErrorMessage)
+		return result
+	}
+
+	args := []string{
+		"--name=replay",
+		"--read_iolog=" + iolog,
+		"--direct=1",
+		fmt.Sprintf("--iodepth=%d", fioReplayIODepth),
+		"--output-format=json+",
+	}
+	args = append(args, fioEngineArgs(engine)...)
+	args = append(args, targetArgs...)
+	if speed == 0 {
+		args = append(args, "--replay_no_stall=1")
+	}
+	if readOnly {
+		args = append(args, "--readonly")
+	}
+	start := time.Now()
+	job, err := runFioJob(args)
+	result.ReplaySeconds = time.Since(start).Seconds()
+	if err != nil {
+		result.Replay.IOPS, result.Replay.BandwidthMB, result.Replay.LatencyUs = -1, -1, -1
+		result.ErrorMessage = err.Error()
+		fmt.Printf("        Replay failed: %v\n", err)
+		return result
+	}
+	setFioJobResult(&result.Replay, job)
+	if runtimeMs, ok := job["job_runtime"].(float64); ok && runtimeMs > 0 {
+		result.ReplaySeconds = runtimeMs / 1000
+	}
+	if expected := trace.summary.DurationSec; speed > 0 && expected > 0 {
+		result.TimingRatio = result.ReplaySeconds / (expected / speed)
+	}
+	fmt.Printf("        Replayed %d I/Os in %.1fs: %.0f IOPS, %.2f MB/s, p99 %s%s\n", result.Replayed, result.ReplaySeconds,
+		result.Replay.IOPS, result.Replay.BandwidthMB, formatLatencyNs(fioCompletionP99Ns(result.Replay)), fioReplayNote(result))
+	return result
+}
+
+// fioReplaySpeedLabel formats a replay speed ("1x", "2.5x" or "full")
+func fioReplaySpeedLabel(speed float64) string {
+	if speed == 0 {
+		return "full"
+	}
+	return strconv.FormatFloat(speed, 'g', -1, 64) + "x"
+}
+
+// fioReplayNote formats the timing note for the run-time output
+func fioReplayNote(result FioReplayResult) string {
+	if note := fioReplayTimingNote(result); note != "" {
+		return " (" + note + ")"
+	}
+	return ""
+}
+
+// fioReplayTimingNote explains whether the target kept up with the recorded timing
+func fioReplayTimingNote(result FioReplayResult) string {
+	switch {
+	case result.TimingRatio == 0:
+		return ""
+	case result.TimingRatio > 1.05:
+		return fmt.Sprintf("%.0f%% slower than the trace timing", (result.TimingRatio-1)*100)
+	}
+	return "kept the trace timing"
+}
+
+// fioReplayLatencyRow formats mean, p50, p99 and p99.9 latency
+func fioReplayLatencyRow(stats FioLatencyStats) []string {
+	if stats.Samples == 0 {
+		return []string{"-", "-", "-", "-"}
+	}
+	return []string{formatLatencyNs(stats.MeanNs), formatLatencyNs(stats.P50Ns), formatLatencyNs(stats.P99Ns), formatLatencyNs(stats.P999Ns)}
+}
+
+// fioReplayRows returns the comparison table: recorded trace first, then each replayed direction
+func fioReplayRows(result FioReplayResult) [][]string {
+	original := result.Original
+	rows := [][]string{append([]string{"Trace (recorded)", fmt.Sprintf("%d", original.IOs), fmt.Sprintf("%.1f", original.DurationSec),
+		fmt.Sprintf("%.0f", original.IOPS), fmt.Sprintf("%.2f", original.BandwidthMB)}, fioReplayLatencyRow(original.Latency)...)}
+	for _, dir := range fioActiveDirections(result.Replay) {
+		rows = append(rows, append([]string{"Replay (" + dir.name + ")", fmt.Sprintf("%d", dir.result.TotalIOs), fmt.Sprintf("%.1f", result.ReplaySeconds),
+			fmt.Sprintf("%.0f", dir.result.IOPS), fmt.Sprintf("%.2f", dir.result.BandwidthMB)}, fioReplayLatencyRow(dir.result.Latency.Completion)...))
+	}
+	return rows
+}
+
+// printFioReplay prints the recorded and replayed numbers of a target side by side
+func printFioReplay(indent string, result FioReplayResult) {
+	if result.TraceFile == "" {
+		return
+	}
+	fmt.Printf("%sTrace replay (%s, %s, %s speed, offsets %s):\n", indent, result.TraceFile, result.Format, fioReplaySpeedLabel(result.Speed), result.Remap)
+	fmt.Printf("%s  %-18s | %-9s | %-8s | %-10s | %-10s | %-10s | %-10s | %-10s | %-10s\n", indent,
+		"", "I/Os", "Seconds", "IOPS", "MB/s", "Mean", "p50", "p99", "p99.9")
+	for _, row := range fioReplayRows(result) {
+		fmt.Printf("%s  %-18s | %-9s | %-8s | %-10s | %-10s | %-10s | %-10s | %-10s | %-10s\n", indent,
+			row[0], row[1], row[2], row[3], row[4], row[5], row[6], row[7], row[8])
+	}
+	if note := fioReplayTimingNote(result); note != "" {
+		fmt.Printf("%s  Timing: %s\n", indent, note)
+	}
+	if result.Remapped > 0 {
+		fmt.Printf("%s  %d of %d I/Os were remapped to fit the target\n", indent, result.Remapped, result.Replayed)
+	}
+	if result.ErrorMessage != "" {
+		fmt.Printf("%s  Error: %s\n", indent, result.ErrorMessage)
+	}
+}
+
+// fioReplayHTML renders the replay comparison and the latency percentiles of trace and replay
+func fioReplayHTML(result FioReplayResult) string {
+	if result.TraceFile == "" {
+		return ""
+	}
+	html := `
+        <h4>Trace Replay</h4>
+        <p>` + fmt.Sprintf("%s (%s), replayed at %s speed onto the target, offsets remapped by %s.", htmlpkg.EscapeString(result.TraceFile), result.Format,
+		fioReplaySpeedLabel(result.Speed), result.Remap)
+	if result.Remapped > 0 {
+		html += fmt.Sprintf(" %d of %d I/Os were remapped to fit the target.", result.Remapped, result.Replayed)
+	}
+	if result.SkippedWrites > 0 {
+		html += fmt.Sprintf(" %d writes were skipped on this read-only target.", result.SkippedWrites)
+	}
+	html += `</p>`
+	if note := fioReplayTimingNote(result); note != "" {
+		class := "highlight"
+		if result.TimingRatio > 1.05 {
+			class = "warning"
+		}
+		html += `
+        <p class="` + class + `">Timing: ` + note + `</p>`
+	}
+	html += `
+        <table>
+            <tr><th></th><th>I/Os</th><th>Seconds</th><th>IOPS</th><th>MB/s</th><th>Mean Latency</th><th>p50</th><th>p99</th><th>p99.9</th></tr>`
+	for _, row := range fioReplayRows(result) {
+		html += `
+            <tr><td>` + strings.Join(row, `</td><td>`) + `</td></tr>`
+	}
+	html += `
+        </table>`
+
+	var series []fioLatencySeries
+	if percentiles := result.Original.Latency.Percentiles; len(percentiles) > 0 {
+		series = append(series, fioLatencySeries{label: "Trace (recorded)", points: percentiles})
+	}
+	for _, dir := range fioActiveDirections(result.Replay) {
+		if percentiles := dir.result.Latency.Completion.Percentiles; len(percentiles) > 0 {
+			series = append(series, fioLatencySeries{label: "Replay (" + dir.name + ")", points: percentiles})
+		}
+	}
+	html += fioPercentileChartSVG(series)
+	if result.ErrorMessage != "" {
+		html += `
+        <p class="warning">` + htmlpkg.EscapeString(result.ErrorMessage) + `</p>`
+	}
+	return html
+}
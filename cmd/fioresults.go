@@ -0,0 +1,169 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// FioDirectionResult holds the metrics of one I/O direction (read, write or trim) of a FIO test
+type FioDirectionResult struct {
+	IOPS        float64             // IO operations per second
+	BandwidthMB float64             // Bandwidth in MB/s
+	TotalIOs    uint64              // Number of I/Os completed
+	Latency     FioLatencyBreakdown // Submission/completion/total latency in ns
+}
+
+// fioNamedDirection pairs a direction result with its name for display
+type fioNamedDirection struct {
+	name   string
+	result FioDirectionResult
+}
+
+// parseFioDirection extracts one direction ("read", "write", "trim") of a fio job
+func parseFioDirection(stats map[string]interface{}) FioDirectionResult {
+	var result FioDirectionResult
+	result.IOPS, _ = stats["iops"].(float64)
+	if bw, ok := stats["bw"].(float64); ok {
+		result.BandwidthMB = bw / 1024 // Convert KiB/s to MiB/s
+	}
+	if ios, ok := stats["total_ios"].(float64); ok {
+		result.TotalIOs = uint64(ios)
+	}
+	result.Latency = parseFioLatencyBreakdown(stats)
+	return result
+}
+
+// fioActiveDirections returns the directions of a test that issued any I/O, in read, write, trim order
+func fioActiveDirections(test FioTestResult) []fioNamedDirection {
+	var active []fioNamedDirection
+	for _, dir := range []fioNamedDirection{{"read", test.Read}, {"write", test.Write}, {"trim", test.Trim}} {
+		if dir.result.IOPS > 0 {
+			active = append(active, dir)
+		}
+	}
+	return active
+}
+
+// setFioTotals fills the combined IOPS, bandwidth and mean latency of a test from its directions.
+// The mean completion latency is weighted by the number of I/Os in each direction.
+func setFioTotals(test *FioTestResult) {
+	test.IOPS, test.BandwidthMB, test.LatencyUs = 0, 0, 0
+	var weightedNs, ios float64
+	for _, dir := range fioActiveDirections(*test) {
+		test.IOPS += dir.result.IOPS
+		test.BandwidthMB += dir.result.BandwidthMB
+		weight := float64(dir.result.Latency.Completion.Samples)
+		if weight == 0 {
+			weight = float64(dir.result.TotalIOs)
+		}
+		weightedNs += dir.result.Latency.Completion.MeanNs * weight
+		ios += weight
+	}
+	if ios > 0 {
+		test.LatencyUs = weightedNs / ios / 1000 // Convert ns to us
+	}
+}
+
+// runFioJob runs fio with JSON output and returns the first job of the report
+func runFioJob(args []string) (map[string]interface{}, error) {
+	output, err := runCommand("fio", args...)
+	if err != nil {
+		return nil, err
+	}
+	// fio may print warnings ahead of the JSON document
+	if start := strings.Index(output, "{"); start > 0 {
+		output = output[start:]
+	}
+	var fioData map[string]interface{}
+	if err := json.Unmarshal([]byte(output), &fioData); err != nil {
+		return nil, fmt.Errorf("could not parse fio JSON output: %w", err)
+	}
+	jobs, ok := fioData["jobs"].([]interface{})
+	if !ok || len(jobs) == 0 {
+		return nil, fmt.Errorf("invalid or empty jobs array in fio output")
+	}
+	job, ok := jobs[0].(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("invalid job in fio output")
+	}
+	return job, nil
+}
+
+// fioScenarioRuntime returns the runtime of a scenario in seconds
+func fioScenarioRuntime(scenario fioScenario) int {
+	if scenario.runtime == 0 {
+		return fioDefaultRuntime
+	}
+	return scenario.runtime
+}
+
+// fioScenarioArgs builds the fio command line for one scenario and engine against one file or device.
+// targetArgs are options the target itself needs (e.g., buffered I/O on a network filesystem).
+func fioScenarioArgs(scenario fioScenario, engine fioEngine, testFilePath, testSize string, readOnly bool, targetArgs []string) []string {
+	fioArgs := []string{
+		fmt.Sprintf("--name=%s", scenario.name),
+		fmt.Sprintf("--filename=%s", testFilePath),
+		"--direct=1",
+		fmt.Sprintf("--rw=%s", scenario.rw),
+		fmt.Sprintf("--bs=%s", scenario.bs),
+		fmt.Sprintf("--iodepth=%d", scenario.iodepth),
+		fmt.Sprintf("--numjobs=%d", scenario.numjobs),
+		fmt.Sprintf("--size=%s", testSize),
+		fmt.Sprintf("--runtime=%d", fioScenarioRuntime(scenario)),
+		"--group_reporting",
+		"--output-format=json+", // json+ adds the raw latency bins used for the histograms
+	}
+	fioArgs = append(fioArgs, fioEngineArgs(engine)...)
+
+	// For direct device tests, let fio itself refuse any write as a second line of defence
+	if readOnly {
+		fioArgs = append(fioArgs,
+			"--readonly", // Read-only mode for safety
+			"--verify=0") // No verification for direct device tests
+	}
+
+	if fioMixedRW(scenario.rw) {
+		fioArgs = append(fioArgs, fmt.Sprintf("--rwmixread=%d", scenario.rwmixread))
+	}
+	fioArgs = append(fioArgs, targetArgs...)
+	return append(fioArgs, scenario.extraArgs...)
+}
+
+// fioMixedRW reports whether an rw mode mixes reads and writes, so --rwmixread applies (0 = writes only)
+func fioMixedRW(rw string) bool {
+	return rw == "rw" || rw == "readwrite" || rw == "randrw"
+}
+
+// newFioTestResult returns a result carrying the parameters of a scenario and engine and no measurements yet
+func newFioTestResult(scenario fioScenario, engine fioEngine) FioTestResult {
+	return FioTestResult{
+		TestName:     scenario.name,
+		Engine:       engine.label,
+		Description:  scenario.description,
+		Category:     scenario.category,
+		ReadWrite:    scenario.rw,
+		BlockSize:    scenario.bs,
+		IODepth:      scenario.iodepth,
+		NumJobs:      scenario.numjobs,
+		RWMixRead:    scenario.rwmixread,
+		RuntimeSec:   fioScenarioRuntime(scenario),
+		ExtraOptions: scenario.extraArgs,
+	}
+}
+
+// setFioJobResult fills the per-direction metrics and totals of a test from a fio job.
+// Mixed workloads (randrw, readwrite) report both read and write.
+func setFioJobResult(result *FioTestResult, job map[string]interface{}) {
+	if read, ok := job["read"].(map[string]interface{}); ok {
+		result.Read = parseFioDirection(read)
+	}
+	if write, ok := job["write"].(map[string]interface{}); ok {
+		result.Write = parseFioDirection(write)
+	}
+	if trim, ok := job["trim"].(map[string]interface{}); ok {
+		result.Trim = parseFioDirection(trim)
+	}
+	setFioTotals(result)
+	setFioSyncResult(result, job)
+}
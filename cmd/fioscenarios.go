@@ -0,0 +1,221 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// fioDefaultRuntime is the runtime in seconds used when a scenario does not set one
+const fioDefaultRuntime = 60
+
+// fioScenario describes one FIO job run against every test target
+type fioScenario struct {
+	name        string
+	rw          string
+	bs          string
+	iodepth     int
+	numjobs     int
+	rwmixread   int
+	description string
+	category    string
+	runtime     int      // Seconds, 0 = fioDefaultRuntime
+	extraArgs   []string // Additional fio options (e.g., "--fsync=1")
+	engine      string   // Engine the scenario always uses, overriding --fio-engines ("" = all engines)
+}
+
+// defaultFioScenarios is the built-in FIO test matrix
+var defaultFioScenarios = []fioScenario{
+	// Standard tests (always run)
+	{name: "4K_RandRead_QD64", rw: "randread", bs: "4k", iodepth: 64, numjobs: 4, description: "4K Random Read (QD=64)", category: "standard"},
+	{name: "4K_RandWrite_QD64", rw: "randwrite", bs: "4k", iodepth: 64, numjobs: 4, description: "4K Random Write (QD=64)", category: "standard"},
+	{name: "1M_SeqRead_QD32", rw: "read", bs: "1m", iodepth: 32, numjobs: 2, description: "1M Sequential Read (QD=32)", category: "standard"},
+	{name: "1M_SeqWrite_QD32", rw: "write", bs: "1m", iodepth: 32, numjobs: 2, description: "1M Sequential Write (QD=32)", category: "standard"},
+	{name: "4K_Mixed_R70W30_QD64", rw: "randrw", bs: "4k", iodepth: 64, numjobs: 4, rwmixread: 70, description: "4K Mixed 70% Read 30% Write (QD=64)", category: "standard"},
+
+	// Sync write tests (sync and all profiles): small sequential writes through the page cache,
+	// each followed by fdatasync, like a database or consensus write-ahead log
+	{name: "Sync_2300B_fdatasync", rw: "write", bs: "2300", iodepth: 1, numjobs: 1, engine: "sync", extraArgs: []string{"--direct=0", "--fdatasync=1"}, description: "etcd WAL: 2300B Sequential Write + fdatasync", category: "sync_write"},
+	{name: "Sync_8K_fdatasync", rw: "write", bs: "8k", iodepth: 1, numjobs: 1, engine: "sync", extraArgs: []string{"--direct=0", "--fdatasync=1"}, description: "PostgreSQL WAL: 8K Sequential Write + fdatasync", category: "sync_write"},
+
+	// IOPS-focused tests (QD scaling)
+	{name: "4K_RandRead_QD1", rw: "randread", bs: "4k", iodepth: 1, numjobs: 1, description: "4K Random Read (QD=1)", category: "iops_scaling"},
+	{name: "4K_RandRead_QD4", rw: "randread", bs: "4k", iodepth: 4, numjobs: 1, description: "4K Random Read (QD=4)", category: "iops_scaling"},
+	{name: "4K_RandRead_QD16", rw: "randread", bs: "4k", iodepth: 16, numjobs: 1, description: "4K Random Read (QD=16)", category: "iops_scaling"},
+	{name: "4K_RandRead_QD128", rw: "randread", bs: "4k", iodepth: 128, numjobs: 4, description: "4K Random Read (QD=128)", category: "iops_scaling"},
+
+	// Throughput-focused tests
+	{name: "128K_SeqRead_QD32", rw: "read", bs: "128k", iodepth: 32, numjobs: 2, description: "128K Sequential Read (QD=32)", category: "throughput"},
+	{name: "128K_SeqWrite_QD32", rw: "write", bs: "128k", iodepth: 32, numjobs: 2, description: "128K Sequential Write (QD=32)", category: "throughput"},
+	{name: "512K_SeqRead_QD32", rw: "read", bs: "512k", iodepth: 32, numjobs: 2, description: "512K Sequential Read (QD=32)", category: "throughput"},
+	{name: "512K_SeqWrite_QD32", rw: "write", bs: "512k", iodepth: 32, numjobs: 2, description: "512K Sequential Write (QD=32)", category: "throughput"},
+
+	// Latency-focused tests
+	{name: "4K_RandRead_QD1_Latency", rw: "randread", bs: "4k", iodepth: 1, numjobs: 1, description: "4K Random Read Latency (QD=1)", category: "latency"},
+	{name: "4K_RandWrite_QD1_Latency", rw: "randwrite", bs: "4k", iodepth: 1, numjobs: 1, description: "4K Random Write Latency (QD=1)", category: "latency"},
+}
+
+// fioScenarioFile is the layout of a --fio-scenarios YAML file
+type fioScenarioFile struct {
+	Scenarios []struct {
+		Name        string   `yaml:"name"`
+		Description string   `yaml:"description"`
+		RW          string   `yaml:"rw"`
+		BS          string   `yaml:"bs"`
+		IODepth     int      `yaml:"iodepth"`
+		NumJobs     int      `yaml:"numjobs"`
+		RWMixRead   *int     `yaml:"rwmixread"` // nil = fio's default of 50
+		Runtime     int      `yaml:"runtime"`
+		Options     []string `yaml:"options"`
+	} `yaml:"scenarios"`
+}
+
+// fioValidRW lists the fio rw modes accepted in scenario files
+var fioValidRW = map[string]bool{
+	"read": true, "write": true, "randread": true, "randwrite": true,
+	"rw": true, "readwrite": true, "randrw": true, "trim": true, "randtrim": true, "trimwrite": true,
+}
+
+// fioReservedOptions are set by HyprBench for every job and cannot be overridden from a scenario file
+var fioReservedOptions = map[string]bool{
+	"name": true, "filename": true, "directory": true, "output": true, "output-format": true,
+	"readonly": true, "offset": true,
+}
+
+// loadFioScenarioFile reads user-defined scenarios from a YAML file. For example:
+//
+//	scenarios:
+//	  - name: 16K_DB_Pages
+//	    description: 16K random 90/10 database pages
+//	    rw: randrw
+//	    bs: 16k
+//	    iodepth: 32
+//	    numjobs: 4
+//	    rwmixread: 90
+//	    runtime: 120
+//	    options: ["fsync=32", "randrepeat=0"]
+func loadFioScenarioFile(path string) ([]fioScenario, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not read scenario file: %w", err)
+	}
+	var file fioScenarioFile
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&file); err != nil {
+		return nil, fmt.Errorf("could not parse scenario file %s: %w", path, err)
+	}
+	if len(file.Scenarios) == 0 {
+		return nil, fmt.Errorf("scenario file %s defines no scenarios", path)
+	}
+
+	names := make(map[string]bool)
+	for _, builtin := range defaultFioScenarios {
+		names[builtin.name] = true
+	}
+
+	scenarios := make([]fioScenario, 0, len(file.Scenarios))
+	for i, s := range file.Scenarios {
+		where := fmt.Sprintf("scenario %d", i+1)
+		if s.Name != "" {
+			where = fmt.Sprintf("scenario %d (%s)", i+1, s.Name)
+		}
+		switch {
+		case s.Name == "":
+			return nil, fmt.Errorf("%s: name is required", where)
+		case strings.ContainsAny(s.Name, " \t/"):
+			return nil, fmt.Errorf("%s: name must not contain spaces or slashes", where)
+		case names[s.Name]:
+			return nil, fmt.Errorf("%s: name is already used", where)
+		case !fioValidRW[s.RW]:
+			return nil, fmt.Errorf("%s: unsupported rw '%s'", where, s.RW)
+		case s.BS == "":
+			return nil, fmt.Errorf("%s: bs is required", where)
+		case s.IODepth < 0 || s.NumJobs < 0 || s.Runtime < 0:
+			return nil, fmt.Errorf("%s: iodepth, numjobs and runtime must not be negative", where)
+		case s.RWMixRead != nil && (*s.RWMixRead < 0 || *s.RWMixRead > 100):
+			return nil, fmt.Errorf("%s: rwmixread must be between 0 and 100", where)
+		}
+		names[s.Name] = true
+
+		scenario := fioScenario{
+			name:        s.Name,
+			rw:          s.RW,
+			bs:          s.BS,
+			iodepth:     max(s.IODepth, 1),
+			numjobs:     max(s.NumJobs, 1),
+			rwmixread:   50,
+			description: s.Description,
+			category:    "custom",
+			runtime:     s.Runtime,
+		}
+		if s.RWMixRead != nil {
+			scenario.rwmixread = *s.RWMixRead
+		}
+		if scenario.description == "" {
+			scenario.description = fmt.Sprintf("%s %s (QD=%d)", strings.ToUpper(s.BS), s.RW, scenario.iodepth)
+		}
+		for _, option := range s.Options {
+			option = "--" + strings.TrimLeft(strings.TrimSpace(option), "-")
+			key, _, _ := strings.Cut(strings.TrimPrefix(option, "--"), "=")
+			if key == "" {
+				return nil, fmt.Errorf("%s: empty fio option", where)
+			}
+			if fioReservedOptions[key] {
+				return nil, fmt.Errorf("%s: fio option '%s' is managed by HyprBench and cannot be set", where, key)
+			}
+			scenario.extraArgs = append(scenario.extraArgs, option)
+		}
+		scenarios = append(scenarios, scenario)
+	}
+	return scenarios, nil
+}
+
+// selectFioScenarios filters the built-in scenarios for a profile. Custom scenarios always run;
+// the "custom" profile runs only them.
+func selectFioScenarios(testProfile string, custom []fioScenario) []fioScenario {
+	var selected []fioScenario
+	switch testProfile {
+	case "custom":
+		// Custom profile - only scenarios from --fio-scenarios
+	case "quick":
+		// Quick profile - just the essential tests
+		for _, scenario := range defaultFioScenarios {
+			if scenario.name == "4K_RandRead_QD64" || scenario.name == "1M_SeqRead_QD32" {
+				selected = append(selected, scenario)
+			}
+		}
+	case "thorough", "all":
+		// All tests
+		selected = append(selected, defaultFioScenarios...)
+	case "sync":
+		// Sync profile - only the write-ahead log tests
+		for _, scenario := range defaultFioScenarios {
+			if scenario.category == "sync_write" {
+				selected = append(selected, scenario)
+			}
+		}
+	case "iops", "throughput", "latency":
+		// Focused profiles - standard tests plus the matching category
+		category := testProfile
+		if testProfile == "iops" {
+			category = "iops_scaling"
+		}
+		for _, scenario := range defaultFioScenarios {
+			if scenario.category == "standard" || scenario.category == category {
+				selected = append(selected, scenario)
+			}
+		}
+	default: // "standard" or any other value
+		// Standard profile - just the standard tests
+		for _, scenario := range defaultFioScenarios {
+			if scenario.category == "standard" {
+				selected = append(selected, scenario)
+			}
+		}
+	}
+	return append(selected, custom...)
+}
@@ -0,0 +1,144 @@
+package cmd
+
+import (
+	"fmt"
+	htmlpkg "html"
+	"strings"
+)
+
+// fioSyncThreshold is a published latency limit for the sync calls of a write-ahead log
+type fioSyncThreshold struct {
+	name       string
+	percentile float64 // 100 = maximum
+	limitNs    float64
+	source     string
+}
+
+// fioSyncThresholds are checked against every sync-write scenario
+var fioSyncThresholds = []fioSyncThreshold{
+	{name: "etcd", percentile: 99, limitNs: 10 * 1000 * 1000, source: "etcd hardware guide: p99 WAL fdatasync under 10 ms"},
+	{name: "ZooKeeper", percentile: 100, limitNs: 1000 * 1000 * 1000, source: "ZooKeeper fsync.warningthresholdms default: 1 s"},
+}
+
+// FioSyncVerdict is the result of comparing sync latency with one threshold
+type FioSyncVerdict struct {
+	Name       string  // e.g., "etcd"
+	Percentile float64 // Percentile compared (100 = maximum)
+	LimitNs    float64 // Threshold
+	ValueNs    float64 // Measured latency at that percentile
+	Pass       bool    // ValueNs below LimitNs
+	Source     string  // Where the threshold comes from
+}
+
+// fioScenarioEngines returns the engines a scenario runs with: its own engine if it pins one, otherwise all of them.
+// The native engine is left out for scenarios it cannot run, and pinned engines need fio.
+func fioScenarioEngines(scenario fioScenario, engines []fioEngine) []fioEngine {
+	if scenario.engine != "" {
+		if !fioInstalled() {
+			return nil
+		}
+		return []fioEngine{{name: scenario.engine, label: scenario.engine}}
+	}
+	var usable []fioEngine
+	for _, engine := range engines {
+		if engine.name != nativeDiskEngine || nativeDiskScenarioSupported(scenario) == "" {
+			usable = append(usable, engine)
+		}
+	}
+	return usable
+}
+
+// setFioSyncResult parses the sync call latency fio reports for --fsync/--fdatasync jobs and checks the thresholds
+func setFioSyncResult(result *FioTestResult, job map[string]interface{}) {
+	sync, ok := job["sync"].(map[string]interface{})
+	if !ok {
+		return
+	}
+	lat, ok := sync["lat_ns"].(map[string]interface{})
+	if !ok {
+		return
+	}
+	result.Sync = parseFioLatencyStats(lat)
+	if result.Sync.Samples == 0 {
+		return
+	}
+	result.SyncVerdicts = nil
+	for _, threshold := range fioSyncThresholds {
+		value := result.Sync.MaxNs
+		if threshold.percentile < 100 {
+			value = fioPercentileAt(result.Sync.Percentiles, threshold.percentile)
+		}
+		if value == 0 {
+			continue
+		}
+		result.SyncVerdicts = append(result.SyncVerdicts, FioSyncVerdict{
+			Name:       threshold.name,
+			Percentile: threshold.percentile,
+			LimitNs:    threshold.limitNs,
+			ValueNs:    value,
+			Pass:       value < threshold.limitNs,
+			Source:     threshold.source,
+		})
+	}
+}
+
+// fioSyncPercentileName formats a threshold percentile ("p99" or "max")
+func fioSyncPercentileName(percentile float64) string {
+	if percentile >= 100 {
+		return "max"
+	}
+	return fmt.Sprintf("p%g", percentile)
+}
+
+// printFioSyncLatency prints the sync latency of a test and its threshold verdicts
+func printFioSyncLatency(indent string, test FioTestResult) {
+	if test.Sync.Samples == 0 {
+		return
+	}
+	fmt.Printf("%s  fdatasync: %d calls, p50 %s, p99 %s, p99.9 %s, max %s\n", indent, test.Sync.Samples,
+		formatLatencyNs(test.Sync.P50Ns), formatLatencyNs(test.Sync.P99Ns), formatLatencyNs(test.Sync.P999Ns), formatLatencyNs(test.Sync.MaxNs))
+	var verdicts []string
+	for _, verdict := range test.SyncVerdicts {
+		status := colorGreen + "PASS" + colorReset
+		if !verdict.Pass {
+			status = colorRed + "FAIL" + colorReset
+		}
+		verdicts = append(verdicts, fmt.Sprintf("%s %s (%s %s, limit %s)", verdict.Name, status,
+			fioSyncPercentileName(verdict.Percentile), formatLatencyNs(verdict.ValueNs), formatLatencyNs(verdict.LimitNs)))
+	}
+	if len(verdicts) > 0 {
+		fmt.Printf("%s  %s\n", indent, strings.Join(verdicts, ", "))
+	}
+}
+
+// fioSyncHTML renders the sync latency of the sync-write tests of a device
+func fioSyncHTML(device FioDeviceResult) string {
+	var rows string
+	for _, test := range device.TestResults {
+		if test.Sync.Samples == 0 {
+			continue
+		}
+		var verdicts []string
+		for _, verdict := range test.SyncVerdicts {
+			class, status := "highlight", "PASS"
+			if !verdict.Pass {
+				class, status = "warning", "FAIL"
+			}
+			verdicts = append(verdicts, fmt.Sprintf(`<span class="%s" title="%s">%s %s</span> (%s %s, limit %s)`, class, verdict.Source,
+				verdict.Name, status, fioSyncPercentileName(verdict.Percentile), formatLatencyNs(verdict.ValueNs), formatLatencyNs(verdict.LimitNs)))
+		}
+		rows += `
+            <tr><td>` + htmlpkg.EscapeString(test.Description) + `</td><td>` + fmt.Sprintf("%d", test.Sync.Samples) + `</td><td>` +
+			formatLatencyNs(test.Sync.P50Ns) + `</td><td>` + formatLatencyNs(test.Sync.P99Ns) + `</td><td>` +
+			formatLatencyNs(test.Sync.P999Ns) + `</td><td>` + formatLatencyNs(test.Sync.MaxNs) + `</td><td>` +
+			strings.Join(verdicts, "<br>") + `</td></tr>`
+	}
+	if rows == "" {
+		return ""
+	}
+	return `
+        <h4>Sync Write (fdatasync) Latency</h4>
+        <table>
+            <tr><th>Workload</th><th>Syncs</th><th>p50</th><th>p99</th><th>p99.9</th><th>Max</th><th>Thresholds</th></tr>` + rows + `
+        </table>`
+}
@@ -0,0 +1,193 @@
+package cmd
+
+import (
+	"fmt"
+	"runtime"
+)
+
+// HugePageResults holds the 4K vs THP vs hugetlbfs page-size comparison
+type HugePageResults struct {
+	THPEnabled       string               // Active value of /sys/kernel/mm/transparent_hugepage/enabled
+	THPDefrag        string               // Active value of /sys/kernel/mm/transparent_hugepage/defrag
+	KhugepagedDefrag string               // Value of /sys/kernel/mm/transparent_hugepage/khugepaged/defrag
+	HugePageSizeKB   uint64               // Default hugetlb page size from /proc/meminfo
+	HugePagesFree    uint64               // Free pre-reserved hugetlb pages before the run
+	TestSizeBytes    uint64               // Buffer size used for each mode
+	Modes            []HugePageModeResult // One entry per page mode, 4K first
+	TestCompleted    bool                 // Whether the 4K baseline and at least one huge page mode ran
+	ErrorMessage     string               // Error message if the test failed
+}
+
+// HugePageModeResult holds the bandwidth and latency measured with one page mode
+type HugePageModeResult struct {
+	Mode             string  // "4K", "THP (madvise)" or "hugetlbfs"
+	Available        bool    // Whether the mode could be tested
+	SkipReason       string  // Why the mode was skipped
+	BandwidthMBs     float64 // Copy bandwidth in MB/s
+	LatencyNs        float64 // Random-access latency in ns
+	BandwidthSpeedup float64 // Bandwidth relative to 4K pages (1.0 = same)
+	LatencySpeedup   float64 // 4K latency divided by this mode's latency (>1.0 = faster)
+	HugeBackedBytes  uint64  // Bytes of the buffer actually backed by huge pages
+}
+
+// hugePageLatencyHops is the number of dependent loads timed per mode
+const hugePageLatencyHops = 20000000
+
+// runHugePageBenchmarks runs the bandwidth and random-access latency tests with each page mode
+func runHugePageBenchmarks(sysInfo *SystemInfo) error {
+	fmt.Println("  Running huge page impact benchmark...")
+
+	results := HugePageResults{
+		THPEnabled:       activeSysfsChoice(readSysfsValue("/sys/kernel/mm/transparent_hugepage/enabled")),
+		THPDefrag:        activeSysfsChoice(readSysfsValue("/sys/kernel/mm/transparent_hugepage/defrag")),
+		KhugepagedDefrag: readSysfsValue("/sys/kernel/mm/transparent_hugepage/khugepaged/defrag"),
+	}
+
+	meminfo, err := readMeminfo()
+	if err != nil {
+		fmt.Printf("    Warning: could not read /proc/meminfo: %v\n", err)
+	}
+	results.HugePageSizeKB = meminfo["Hugepagesize"]
+	results.HugePagesFree = meminfo["HugePages_Free"]
+
+	sizeBytes, err := parseSizeBytes(memoryHugePagesSize)
+	if err != nil {
+		results.ErrorMessage = fmt.Sprintf("Invalid test size: %v", err)
+		sysInfo.HugePageResults = results
+		return fmt.Errorf("invalid --memory-hugepages-size '%s': %w", memoryHugePagesSize, err)
+	}
+	// Round to whole huge pages so the same size works for every mode
+	hugePageBytes := results.HugePageSizeKB * 1024
+	if hugePageBytes == 0 {
+		hugePageBytes = 2 * 1024 * 1024
+	}
+	sizeBytes = (sizeBytes + hugePageBytes - 1) / hugePageBytes * hugePageBytes
+	results.TestSizeBytes = sizeBytes
+
+	fmt.Printf("    THP enabled: %s, defrag: %s, hugetlb page size: %d kB, free hugetlb pages: %d\n",
+		results.THPEnabled, results.THPDefrag, results.HugePageSizeKB, results.HugePagesFree)
+	fmt.Printf("    Buffer size per mode: %s\n", humanReadableBytes(sizeBytes))
+
+	// 4K baseline
+	results.Modes = append(results.Modes, measurePageMode("4K", int(sizeBytes), func() ([]byte, error) {
+		buf, err := mmapAnonymous(int(sizeBytes))
+		if err != nil {
+			return nil, err
+		}
+		if err := madviseTHP(buf, false); err != nil {
+			munmapBuffer(buf)
+			return nil, err
+		}
+		return buf, nil
+	}))
+
+	// Transparent huge pages via madvise
+	if results.THPEnabled == "never" || results.THPEnabled == "" {
+		results.Modes = append(results.Modes, HugePageModeResult{
+			Mode:       "THP (madvise)",
+			SkipReason: "Transparent huge pages are disabled (transparent_hugepage/enabled = never)",
+		})
+	} else {
+		results.Modes = append(results.Modes, measurePageMode("THP (madvise)", int(sizeBytes), func() ([]byte, error) {
+			buf, err := mmapAnonymous(int(sizeBytes))
+			if err != nil {
+				return nil, err
+			}
+			if err := madviseTHP(buf, true); err != nil {
+				munmapBuffer(buf)
+				return nil, err
+			}
+			return buf, nil
+		}))
+	}
+
+	// Pre-reserved hugetlb pages
+	neededPages := sizeBytes / hugePageBytes
+	if results.HugePagesFree < neededPages {
+		results.Modes = append(results.Modes, HugePageModeResult{
+			Mode: "hugetlbfs",
+			SkipReason: fmt.Sprintf("Only %d free hugetlb pages, %d needed (reserve them with 'sysctl vm.nr_hugepages=%d')",
+				results.HugePagesFree, neededPages, neededPages),
+		})
+	} else {
+		results.Modes = append(results.Modes, measurePageMode("hugetlbfs", int(sizeBytes), func() ([]byte, error) {
+			return mmapHugeTLB(int(sizeBytes))
+		}))
+	}
+
+	// Speedups relative to the 4K baseline
+	base := results.Modes[0]
+	hugeModesRun := 0
+	for i := range results.Modes {
+		mode := &results.Modes[i]
+		if !mode.Available || !base.Available {
+			continue
+		}
+		if base.BandwidthMBs > 0 {
+			mode.BandwidthSpeedup = mode.BandwidthMBs / base.BandwidthMBs
+		}
+		if mode.LatencyNs > 0 {
+			mode.LatencySpeedup = base.LatencyNs / mode.LatencyNs
+		}
+		if i > 0 {
+			hugeModesRun++
+		}
+	}
+	results.TestCompleted = base.Available && hugeModesRun > 0
+	if !base.Available {
+		results.ErrorMessage = "4K baseline failed: " + base.SkipReason
+	}
+
+	for _, mode := range results.Modes {
+		if !mode.Available {
+			fmt.Printf("    %-14s skipped: %s\n", mode.Mode, mode.SkipReason)
+			continue
+		}
+		fmt.Printf("    %-14s bandwidth: %.2f MB/s (x%.2f), latency: %.1f ns (x%.2f), huge-backed: %s\n",
+			mode.Mode, mode.BandwidthMBs, mode.BandwidthSpeedup, mode.LatencyNs, mode.LatencySpeedup,
+			humanReadableBytes(mode.HugeBackedBytes))
+	}
+
+	sysInfo.HugePageResults = results
+	return nil
+}
+
+// measurePageMode allocates a buffer with alloc and runs the bandwidth and latency kernels on it
+func measurePageMode(mode string, size int, alloc func() ([]byte, error)) HugePageModeResult {
+	result := HugePageModeResult{Mode: mode}
+	fmt.Printf("    Measuring with %s pages...\n", mode)
+
+	hugeBefore := hugeBackedBytes()
+	buf, err := alloc()
+	if err != nil {
+		result.SkipReason = fmt.Sprintf("allocation failed: %v", err)
+		return result
+	}
+	defer munmapBuffer(buf)
+	touchPages(buf)
+	if after := hugeBackedBytes(); after > hugeBefore {
+		result.HugeBackedBytes = after - hugeBefore
+	}
+
+	result.BandwidthMBs, err = copyBandwidthMBs(buf, runtime.NumCPU(), 5, nil)
+	if err != nil {
+		result.SkipReason = fmt.Sprintf("bandwidth test failed: %v", err)
+		return result
+	}
+	result.LatencyNs, err = randomAccessLatencyNs(buf, hugePageLatencyHops, nil)
+	if err != nil {
+		result.SkipReason = fmt.Sprintf("latency test failed: %v", err)
+		return result
+	}
+	result.Available = true
+	return result
+}
+
+// hugeBackedBytes returns how much of this process's memory is backed by THP or hugetlb pages
+func hugeBackedBytes() uint64 {
+	rollup, err := readKBFile("/proc/self/smaps_rollup")
+	if err != nil {
+		return 0
+	}
+	return (rollup["AnonHugePages"] + rollup["Private_Hugetlb"] + rollup["Shared_Hugetlb"]) * 1024
+}
@@ -0,0 +1,272 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// edacRoot is where the kernel exposes EDAC memory controller counters
+const edacRoot = "/sys/devices/system/edac/mc"
+
+// EDACCounts is a snapshot of the EDAC corrected/uncorrected error counters
+type EDACCounts struct {
+	Available        bool                  // Whether any EDAC memory controller was found
+	Controllers      []EDACControllerCount // Per memory controller counters
+	DIMMs            []EDACDIMMCount       // Per DIMM/rank counters, if the driver exposes them
+	CorrectedTotal   uint64                // Sum of ce_count over all controllers
+	UncorrectedTotal uint64                // Sum of ue_count over all controllers
+}
+
+// EDACControllerCount holds the counters of one memory controller (mc0, mc1, ...)
+type EDACControllerCount struct {
+	Name        string // e.g., "mc0"
+	Corrected   uint64 // ce_count
+	Uncorrected uint64 // ue_count
+}
+
+// EDACDIMMCount holds the counters of one DIMM or rank below a memory controller
+type EDACDIMMCount struct {
+	Controller  string // e.g., "mc0"
+	Name        string // e.g., "dimm3" or "rank0"
+	Label       string // dimm_label, usually the silkscreen slot name
+	Corrected   uint64 // dimm_ce_count
+	Uncorrected uint64 // dimm_ue_count
+}
+
+// readEDACCounts reads the current EDAC counters from sysfs
+func readEDACCounts() EDACCounts {
+	var counts EDACCounts
+	controllers, _ := filepath.Glob(filepath.Join(edacRoot, "mc[0-9]*"))
+	sort.Strings(controllers)
+	for _, mcPath := range controllers {
+		mc := EDACControllerCount{
+			Name:        filepath.Base(mcPath),
+			Corrected:   readSysfsUint(filepath.Join(mcPath, "ce_count")),
+			Uncorrected: readSysfsUint(filepath.Join(mcPath, "ue_count")),
+		}
+		counts.Available = true
+		counts.Controllers = append(counts.Controllers, mc)
+		counts.CorrectedTotal += mc.Corrected
+		counts.UncorrectedTotal += mc.Uncorrected
+
+		// Newer drivers use dimm*, older ones csrow-based rank*
+		dimms, _ := filepath.Glob(filepath.Join(mcPath, "dimm[0-9]*"))
+		if len(dimms) == 0 {
+			dimms, _ = filepath.Glob(filepath.Join(mcPath, "rank[0-9]*"))
+		}
+		sort.Strings(dimms)
+		for _, dimmPath := range dimms {
+			if _, err := os.Stat(filepath.Join(dimmPath, "dimm_ce_count")); err != nil {
+				continue
+			}
+			counts.DIMMs = append(counts.DIMMs, EDACDIMMCount{
+				Controller:  mc.Name,
+				Name:        filepath.Base(dimmPath),
+				Label:       readSysfsValue(filepath.Join(dimmPath, "dimm_label")),
+				Corrected:   readSysfsUint(filepath.Join(dimmPath, "dimm_ce_count")),
+				Uncorrected: readSysfsUint(filepath.Join(dimmPath, "dimm_ue_count")),
+			})
+		}
+	}
+	return counts
+}
+
+// edacDelta returns the corrected and uncorrected errors counted between two snapshots
+func edacDelta(before, after EDACCounts) (corrected, uncorrected uint64) {
+	return counterDelta(before.CorrectedTotal, after.CorrectedTotal), counterDelta(before.UncorrectedTotal, after.UncorrectedTotal)
+}
+
+// counterDelta returns how much a counter grew, treating a reset counter as no growth
+func counterDelta(before, after uint64) uint64 {
+	if after > before {
+		return after - before
+	}
+	return 0
+}
+
+// edacDIMMDeltas lists the DIMMs whose counters increased between two snapshots, formatted for display
+func edacDIMMDeltas(before, after EDACCounts) []string {
+	previous := make(map[string]EDACDIMMCount)
+	for _, dimm := range before.DIMMs {
+		previous[dimm.Controller+"/"+dimm.Name] = dimm
+	}
+	var deltas []string
+	for _, dimm := range after.DIMMs {
+		prev := previous[dimm.Controller+"/"+dimm.Name]
+		if dimm.Corrected <= prev.Corrected && dimm.Uncorrected <= prev.Uncorrected {
+			continue
+		}
+		name := dimm.Controller + "/" + dimm.Name
+		if dimm.Label != "" {
+			name += " (" + dimm.Label + ")"
+		}
+		deltas = append(deltas, fmt.Sprintf("%s: +%d corrected, +%d uncorrected", name,
+			counterDelta(prev.Corrected, dimm.Corrected), counterDelta(prev.Uncorrected, dimm.Uncorrected)))
+	}
+	return deltas
+}
+
+// readSysfsUint reads a single unsigned integer from a sysfs file, returns 0 if it cannot be read
+func readSysfsUint(path string) uint64 {
+	v, err := strconv.ParseUint(readSysfsValue(path), 10, 64)
+	if err != nil {
+		return 0
+	}
+	return v
+}
+
+// maxKernelLogErrors caps how many kernel log lines are kept in the report
+const maxKernelLogErrors = 100
+
+// hardwareErrorPattern matches kernel log lines that report hardware faults. Block I/O errors only count
+// on physical disks: loop, nbd and network filesystem errors (e.g., "Buffer I/O error on dev loop0") do not.
+var hardwareErrorPattern = regexp.MustCompile(`(?i)(machine check|\bmce:|hardware error|edac .*(error|\bce\b|\bue\b)|` +
+	`ecc error|corrected error|uncorrected error|pcie bus error|\baer:|i/o error, dev (sd|nvme|hd|mmcblk)|` +
+	`critical (medium|target|nexus) error|medium error|temperature above threshold)`)
+
+// kernelLogTimePattern extracts the "[  123.456789]" monotonic timestamp that dmesg prints by default
+var kernelLogTimePattern = regexp.MustCompile(`^\[\s*(\d+\.\d+)\]`)
+
+// HardwareErrorReport lists hardware errors that appeared while the benchmarks ran
+type HardwareErrorReport struct {
+	EDACBefore         EDACCounts // EDAC counters at the start of the run
+	EDACAfter          EDACCounts // EDAC counters at the end of the run
+	NewCorrected       uint64     // New EDAC corrected errors
+	NewUncorrected     uint64     // New EDAC uncorrected errors
+	EDACDIMMDeltas     []string   // DIMMs whose EDAC counters increased
+	MCEAvailable       bool       // Whether the MCE line was found in /proc/interrupts
+	MCEBefore          uint64     // Machine check exceptions at the start of the run (all CPUs)
+	MCEAfter           uint64     // Machine check exceptions at the end of the run (all CPUs)
+	NewMCEs            uint64     // Machine check exceptions raised during the run
+	KernelLogAvailable bool       // Whether the kernel log could be read
+	KernelLogErrors    []string   // New kernel log lines reporting hardware errors
+	ErrorsDetected     bool       // Whether any new hardware error was seen
+}
+
+// hardwareErrorSnapshot is the state captured at the start of the run
+type hardwareErrorSnapshot struct {
+	edac               EDACCounts
+	mce                uint64
+	mceAvailable       bool
+	kernelLogAvailable bool
+	kernelLogLines     int     // Number of lines in the kernel log
+	kernelLogTime      float64 // Timestamp of the last kernel log line (-1 if none could be parsed)
+}
+
+// snapshotHardwareErrors records the EDAC, MCE and kernel log state before the benchmarks start
+func snapshotHardwareErrors() hardwareErrorSnapshot {
+	snapshot := hardwareErrorSnapshot{edac: readEDACCounts(), kernelLogTime: -1}
+	snapshot.mce, snapshot.mceAvailable = readMCECount()
+
+	lines, err := readKernelLog()
+	if err != nil {
+		fmt.Printf("  Warning: could not read the kernel log, hardware error messages will not be captured: %v\n", err)
+		return snapshot
+	}
+	snapshot.kernelLogAvailable = true
+	snapshot.kernelLogLines = len(lines)
+	for i := len(lines) - 1; i >= 0; i-- {
+		if ts, ok := kernelLogTimestamp(lines[i]); ok {
+			snapshot.kernelLogTime = ts
+			break
+		}
+	}
+	return snapshot
+}
+
+// collectHardwareErrors compares the current state with the snapshot taken at the start of the run
+func collectHardwareErrors(before hardwareErrorSnapshot) HardwareErrorReport {
+	report := HardwareErrorReport{
+		EDACBefore:   before.edac,
+		EDACAfter:    readEDACCounts(),
+		MCEAvailable: before.mceAvailable,
+		MCEBefore:    before.mce,
+	}
+	report.NewCorrected, report.NewUncorrected = edacDelta(report.EDACBefore, report.EDACAfter)
+	report.EDACDIMMDeltas = edacDIMMDeltas(report.EDACBefore, report.EDACAfter)
+
+	if before.mceAvailable {
+		report.MCEAfter, _ = readMCECount()
+		report.NewMCEs = counterDelta(report.MCEBefore, report.MCEAfter)
+	}
+
+	if before.kernelLogAvailable {
+		if lines, err := readKernelLog(); err == nil {
+			report.KernelLogAvailable = true
+			for i, line := range lines {
+				if !isNewKernelLogLine(i, line, before) || !hardwareErrorPattern.MatchString(line) {
+					continue
+				}
+				if len(report.KernelLogErrors) == maxKernelLogErrors {
+					report.KernelLogErrors = append(report.KernelLogErrors, "... further messages omitted")
+					break
+				}
+				report.KernelLogErrors = append(report.KernelLogErrors, line)
+			}
+		}
+	}
+
+	report.ErrorsDetected = report.NewCorrected > 0 || report.NewUncorrected > 0 || report.NewMCEs > 0 ||
+		len(report.KernelLogErrors) > 0
+	return report
+}
+
+// isNewKernelLogLine reports whether a line was logged after the snapshot. Timestamps are preferred
+// because the ring buffer may have wrapped; the line count is used when they cannot be parsed.
+func isNewKernelLogLine(index int, line string, before hardwareErrorSnapshot) bool {
+	if before.kernelLogTime >= 0 {
+		if ts, ok := kernelLogTimestamp(line); ok {
+			return ts > before.kernelLogTime
+		}
+	}
+	return index >= before.kernelLogLines
+}
+
+// readKernelLog returns the kernel ring buffer via dmesg
+func readKernelLog() ([]string, error) {
+	output, err := runCommand("dmesg")
+	if err != nil {
+		return nil, err
+	}
+	return strings.Split(strings.TrimRight(output, "\n"), "\n"), nil
+}
+
+// kernelLogTimestamp parses the leading timestamp of a dmesg line
+func kernelLogTimestamp(line string) (float64, bool) {
+	match := kernelLogTimePattern.FindStringSubmatch(line)
+	if match == nil {
+		return 0, false
+	}
+	ts, err := strconv.ParseFloat(match[1], 64)
+	return ts, err == nil
+}
+
+// readMCECount sums the per-CPU "MCE" (machine check exceptions) counts from /proc/interrupts.
+// The MCP line (periodic polls) is not counted as it increments on healthy systems too.
+func readMCECount() (uint64, bool) {
+	data, err := os.ReadFile("/proc/interrupts")
+	if err != nil {
+		return 0, false
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 || fields[0] != "MCE:" {
+			continue
+		}
+		var total uint64
+		for _, field := range fields[1:] {
+			v, err := strconv.ParseUint(field, 10, 64)
+			if err != nil {
+				break // Reached the description text
+			}
+			total += v
+		}
+		return total, true
+	}
+	return 0, false
+}
@@ -0,0 +1,176 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"math/rand"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unsafe"
+)
+
+// cacheLineSize is the stride used by the pointer-chasing latency test
+const cacheLineSize = 64
+
+// latencySink keeps the compiler from optimizing away the pointer chase
+var latencySink uint64
+
+// copyBandwidthMBs copies the first half of buf into the second half using the given number of workers
+// and returns the achieved bandwidth in MB/s, counting bytes read plus bytes written as STREAM Copy does.
+// If setup is non-nil it runs on each worker's locked OS thread before timing starts (e.g., CPU pinning).
+func copyBandwidthMBs(buf []byte, workers, iterations int, setup func() error) (float64, error) {
+	if workers < 1 {
+		workers = 1
+	}
+	half := len(buf) / 2
+	chunk := (half / workers) &^ 4095 // Page-align each worker's slice
+	if chunk == 0 {
+		return 0, fmt.Errorf("buffer of %d bytes is too small for %d workers", len(buf), workers)
+	}
+
+	var ready, done sync.WaitGroup
+	start := make(chan struct{})
+	errCh := make(chan error, workers)
+
+	for w := 0; w < workers; w++ {
+		ready.Add(1)
+		done.Add(1)
+		go func(w int) {
+			defer done.Done()
+			// The thread is intentionally never unlocked: if setup changed its affinity,
+			// the runtime discards the thread when this goroutine exits.
+			runtime.LockOSThread()
+			if setup != nil {
+				if err := setup(); err != nil {
+					errCh <- err
+				}
+			}
+			src := buf[w*chunk : (w+1)*chunk]
+			dst := buf[half+w*chunk : half+(w+1)*chunk]
+			ready.Done()
+			<-start
+			for i := 0; i < iterations; i++ {
+				copy(dst, src)
+			}
+		}(w)
+	}
+
+	ready.Wait()
+	select {
+	case err := <-errCh:
+		close(start)
+		done.Wait()
+		return 0, err
+	default:
+	}
+
+	begin := time.Now()
+	close(start)
+	done.Wait()
+	elapsed := time.Since(begin)
+
+	bytesMoved := float64(chunk) * float64(workers) * float64(iterations) * 2
+	return bytesMoved / elapsed.Seconds() / (1024 * 1024), nil
+}
+
+// randomAccessLatencyNs walks a random cyclic pointer chain through buf, one cache line per hop,
+// and returns the average time per dependent load in nanoseconds.
+// If setup is non-nil it runs on the measuring goroutine's locked OS thread first.
+func randomAccessLatencyNs(buf []byte, hops int, setup func() error) (float64, error) {
+	slots := len(buf) / cacheLineSize
+	if slots < 2 {
+		return 0, fmt.Errorf("buffer of %d bytes is too small for a latency test", len(buf))
+	}
+
+	type latencyResult struct {
+		ns  float64
+		err error
+	}
+	resultCh := make(chan latencyResult, 1)
+
+	go func() {
+		// Locked for the same reason as in copyBandwidthMBs
+		runtime.LockOSThread()
+		if setup != nil {
+			if err := setup(); err != nil {
+				resultCh <- latencyResult{err: err}
+				return
+			}
+		}
+
+		// Build a single random cycle (Sattolo's algorithm) so every hop misses the prefetchers
+		order := make([]uint32, slots)
+		for i := range order {
+			order[i] = uint32(i)
+		}
+		rng := rand.New(rand.NewSource(time.Now().UnixNano()))
+		for i := slots - 1; i > 0; i-- {
+			j := rng.Intn(i)
+			order[i], order[j] = order[j], order[i]
+		}
+
+		words := unsafe.Slice((*uint64)(unsafe.Pointer(&buf[0])), len(buf)/8)
+		const wordsPerLine = cacheLineSize / 8
+		for i := 0; i < slots; i++ {
+			next := order[(i+1)%slots]
+			words[int(order[i])*wordsPerLine] = uint64(next) * wordsPerLine
+		}
+		order = nil
+
+		p := uint64(0)
+		begin := time.Now()
+		for h := 0; h < hops; h++ {
+			p = words[p]
+		}
+		elapsed := time.Since(begin)
+		latencySink = p
+
+		resultCh <- latencyResult{ns: float64(elapsed.Nanoseconds()) / float64(hops)}
+	}()
+
+	res := <-resultCh
+	return res.ns, res.err
+}
+
+// touchPages writes one byte per page so the kernel allocates the whole buffer up front
+func touchPages(buf []byte) {
+	for i := 0; i < len(buf); i += 4096 {
+		buf[i] = 1
+	}
+}
+
+// readKBFile parses "Key:   value kB" files such as /proc/meminfo into raw values
+// (kB for sized fields, plain counts for fields like HugePages_Free)
+func readKBFile(path string) (map[string]uint64, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	values := make(map[string]uint64)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		key, rest, found := strings.Cut(scanner.Text(), ":")
+		if !found {
+			continue
+		}
+		fields := strings.Fields(rest)
+		if len(fields) == 0 {
+			continue
+		}
+		if v, err := strconv.ParseUint(fields[0], 10, 64); err == nil {
+			values[strings.TrimSpace(key)] = v
+		}
+	}
+	return values, scanner.Err()
+}
+
+// readMeminfo returns /proc/meminfo values (in kB, except page counts)
+func readMeminfo() (map[string]uint64, error) {
+	return readKBFile("/proc/meminfo")
+}
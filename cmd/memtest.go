@@ -0,0 +1,297 @@
+package cmd
+
+import (
+	"encoding/binary"
+	"fmt"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unsafe"
+)
+
+// maxStoredMismatches caps how many mismatches are kept for the report; the total is always counted
+const maxStoredMismatches = 10000
+
+// maxPrintedMismatches caps how many mismatches are printed to the console
+const maxPrintedMismatches = 50
+
+// MemoryTestResults holds the results of the memtester-style integrity test
+type MemoryTestResults struct {
+	TestSizeBytes     uint64           // Bytes of memory tested
+	Passes            int              // Passes requested
+	PassesCompleted   int              // Passes that ran to completion
+	Patterns          []string         // Patterns written and verified in each pass
+	Locked            bool             // Whether the buffer was locked into RAM (mlock)
+	MismatchCount     uint64           // Total mismatches found, including those not stored
+	Mismatches        []MemoryMismatch // Mismatch details, capped at maxStoredMismatches
+	EDACBefore        EDACCounts       // EDAC counters before the test
+	EDACAfter         EDACCounts       // EDAC counters after the test
+	CorrectedErrors   uint64           // New EDAC corrected errors during the test
+	UncorrectedErrors uint64           // New EDAC uncorrected errors during the test
+	EDACDIMMDeltas    []string         // DIMMs whose EDAC counters increased
+	DurationSeconds   float64          // Wall-clock time of all passes
+	TestCompleted     bool             // Whether all passes ran
+	Passed            bool             // No mismatches and no new EDAC errors
+	ErrorMessage      string           // Error message if the test failed to run
+}
+
+// MemoryMismatch describes one word that read back differently from what was written
+type MemoryMismatch struct {
+	Pass            int    // 1-based pass number
+	Pattern         string // Pattern being verified
+	VirtualAddress  uint64 // Address of the word in this process
+	PhysicalAddress uint64 // Physical address from /proc/self/pagemap (0 if unavailable)
+	Expected        uint64 // Value written
+	Actual          uint64 // Value read back
+}
+
+// memoryPattern computes the value written to each 64-bit word of the buffer
+type memoryPattern struct {
+	name  string
+	value func(index, addr uint64) uint64
+}
+
+// memoryTestPatterns returns the patterns for one pass. Walking ones and random data vary per pass.
+func memoryTestPatterns(pass int, seed uint64) []memoryPattern {
+	const checker = 0xAAAAAAAAAAAAAAAA
+	return []memoryPattern{
+		{"walking ones", func(i, _ uint64) uint64 { return 1 << ((i + uint64(pass)) % 64) }},
+		{"checkerboard", func(i, _ uint64) uint64 {
+			if i%2 == 0 {
+				return checker
+			}
+			return ^uint64(checker)
+		}},
+		{"checkerboard (inverted)", func(i, _ uint64) uint64 {
+			if i%2 == 0 {
+				return ^uint64(checker)
+			}
+			return checker
+		}},
+		{"random", func(i, _ uint64) uint64 { return splitMix64(seed + uint64(pass)<<48 + i) }},
+		{"address-in-address", func(_, addr uint64) uint64 { return addr }},
+		{"address-in-address (inverted)", func(_, addr uint64) uint64 { return ^addr }},
+	}
+}
+
+// splitMix64 is a stateless mixer, so random data can be regenerated for verification without a copy
+func splitMix64(x uint64) uint64 {
+	x += 0x9E3779B97F4A7C15
+	x = (x ^ (x >> 30)) * 0xBF58476D1CE4E5B9
+	x = (x ^ (x >> 27)) * 0x94D049BB133111EB
+	return x ^ (x >> 31)
+}
+
+// mismatchCollector gathers mismatches from all verify workers
+type mismatchCollector struct {
+	mu         sync.Mutex
+	total      atomic.Uint64
+	mismatches []MemoryMismatch
+}
+
+func (c *mismatchCollector) add(m MemoryMismatch) {
+	c.total.Add(1)
+	c.mu.Lock()
+	if len(c.mismatches) < maxStoredMismatches {
+		c.mismatches = append(c.mismatches, m)
+	}
+	c.mu.Unlock()
+}
+
+// runMemoryIntegrityTest fills a fraction of RAM with test patterns and verifies them over several passes
+func runMemoryIntegrityTest(sysInfo *SystemInfo) error {
+	fmt.Println("  Running memory integrity test...")
+	results := MemoryTestResults{Passes: memoryTestPasses}
+	defer func() { sysInfo.MemoryTestResults = results }()
+
+	if memoryTestPasses < 1 {
+		results.ErrorMessage = "At least one pass is required"
+		return fmt.Errorf("invalid --memory-test-passes %d", memoryTestPasses)
+	}
+	sizeBytes, err := memoryTestSizeBytes(memoryTestSize)
+	if err != nil {
+		results.ErrorMessage = fmt.Sprintf("Invalid test size: %v", err)
+		return fmt.Errorf("invalid --memory-test-size '%s': %w", memoryTestSize, err)
+	}
+	results.TestSizeBytes = sizeBytes
+	fmt.Printf("    Testing %s of memory, %d pass(es)\n", humanReadableBytes(sizeBytes), memoryTestPasses)
+
+	buf, err := mmapAnonymous(int(sizeBytes))
+	if err != nil {
+		results.ErrorMessage = fmt.Sprintf("Could not allocate %s: %v", humanReadableBytes(sizeBytes), err)
+		return fmt.Errorf("failed to allocate test buffer: %w", err)
+	}
+	defer munmapBuffer(buf)
+	if err := mlockBuffer(buf); err != nil {
+		fmt.Printf("    Warning: could not lock test memory (%v); pages may be swapped and go untested\n", err)
+	} else {
+		results.Locked = true
+	}
+
+	words := unsafe.Slice((*uint64)(unsafe.Pointer(&buf[0])), len(buf)/8)
+	base := uint64(uintptr(unsafe.Pointer(&buf[0])))
+	workers := runtime.NumCPU()
+	seed := uint64(time.Now().UnixNano())
+	collector := &mismatchCollector{}
+
+	results.EDACBefore = readEDACCounts()
+	if !results.EDACBefore.Available {
+		fmt.Println("    EDAC counters not available (no EDAC driver loaded); only pattern mismatches will be detected")
+	}
+
+	begin := time.Now()
+	for pass := 1; pass <= memoryTestPasses; pass++ {
+		for _, pattern := range memoryTestPatterns(pass, seed) {
+			if pass == 1 {
+				results.Patterns = append(results.Patterns, pattern.name)
+			}
+			before := collector.total.Load()
+			patternStart := time.Now()
+			fillMemoryPattern(words, base, pattern, workers)
+			verifyMemoryPattern(words, base, pattern, pass, workers, collector)
+			found := collector.total.Load() - before
+
+			status := colorGreen + "ok" + colorReset
+			if found > 0 {
+				status = fmt.Sprintf("%s%d mismatches%s", colorRed, found, colorReset)
+			}
+			fmt.Printf("    Pass %d/%d: %-30s %s (%s)\n", pass, memoryTestPasses, pattern.name, status,
+				time.Since(patternStart).Round(time.Millisecond))
+		}
+		results.PassesCompleted = pass
+	}
+	results.DurationSeconds = time.Since(begin).Seconds()
+
+	results.EDACAfter = readEDACCounts()
+	results.CorrectedErrors, results.UncorrectedErrors = edacDelta(results.EDACBefore, results.EDACAfter)
+	results.EDACDIMMDeltas = edacDIMMDeltas(results.EDACBefore, results.EDACAfter)
+
+	results.MismatchCount = collector.total.Load()
+	results.Mismatches = collector.mismatches
+	resolvePhysicalAddresses(results.Mismatches)
+
+	results.TestCompleted = results.PassesCompleted == memoryTestPasses
+	results.Passed = results.TestCompleted && results.MismatchCount == 0 &&
+		results.CorrectedErrors == 0 && results.UncorrectedErrors == 0
+
+	if results.Passed {
+		fmt.Printf("    %sMemory integrity test passed%s (%s in %.0fs)\n", colorGreen, colorReset,
+			humanReadableBytes(sizeBytes), results.DurationSeconds)
+	} else {
+		fmt.Printf("    %sMemory integrity test FAILED: %d mismatches, %d new corrected and %d new uncorrected EDAC errors%s\n",
+			colorRed, results.MismatchCount, results.CorrectedErrors, results.UncorrectedErrors, colorReset)
+	}
+	return nil
+}
+
+// memoryTestSizeBytes resolves a size such as "50%" (of MemAvailable) or "8G", rounded down to whole pages
+func memoryTestSizeBytes(spec string) (uint64, error) {
+	var size uint64
+	if percentStr, isPercent := strings.CutSuffix(strings.TrimSpace(spec), "%"); isPercent {
+		percent, err := strconv.ParseFloat(percentStr, 64)
+		if err != nil || percent <= 0 || percent > 95 {
+			return 0, fmt.Errorf("percentage must be between 0 and 95")
+		}
+		meminfo, err := readMeminfo()
+		if err != nil {
+			return 0, fmt.Errorf("could not read available memory: %w", err)
+		}
+		size = uint64(float64(meminfo["MemAvailable"]*1024) * percent / 100)
+	} else {
+		var err error
+		if size, err = parseSizeBytes(spec); err != nil {
+			return 0, err
+		}
+	}
+	size &^= 4095
+	if size == 0 {
+		return 0, fmt.Errorf("size is smaller than one page")
+	}
+	return size, nil
+}
+
+// forEachChunk splits words into one contiguous chunk per worker and runs fn on each in parallel
+func forEachChunk(words []uint64, workers int, fn func(start, end int)) {
+	chunk := (len(words) + workers - 1) / workers
+	var wg sync.WaitGroup
+	for start := 0; start < len(words); start += chunk {
+		end := min(start+chunk, len(words))
+		wg.Add(1)
+		go func(start, end int) {
+			defer wg.Done()
+			fn(start, end)
+		}(start, end)
+	}
+	wg.Wait()
+}
+
+// fillMemoryPattern writes the pattern to every word of the buffer
+func fillMemoryPattern(words []uint64, base uint64, pattern memoryPattern, workers int) {
+	forEachChunk(words, workers, func(start, end int) {
+		for i := start; i < end; i++ {
+			words[i] = pattern.value(uint64(i), base+uint64(i)*8)
+		}
+	})
+}
+
+// verifyMemoryPattern reads every word back and records any that differ from the pattern
+func verifyMemoryPattern(words []uint64, base uint64, pattern memoryPattern, pass, workers int, collector *mismatchCollector) {
+	forEachChunk(words, workers, func(start, end int) {
+		for i := start; i < end; i++ {
+			addr := base + uint64(i)*8
+			expected := pattern.value(uint64(i), addr)
+			if actual := words[i]; actual != expected {
+				collector.add(MemoryMismatch{
+					Pass:           pass,
+					Pattern:        pattern.name,
+					VirtualAddress: addr,
+					Expected:       expected,
+					Actual:         actual,
+				})
+			}
+		}
+	})
+}
+
+// resolvePhysicalAddresses fills in PhysicalAddress from /proc/self/pagemap. The kernel only
+// reports page frame numbers to root (CAP_SYS_ADMIN); otherwise the addresses stay 0.
+func resolvePhysicalAddresses(mismatches []MemoryMismatch) {
+	if len(mismatches) == 0 {
+		return
+	}
+	pagemap, err := os.Open("/proc/self/pagemap")
+	if err != nil {
+		return
+	}
+	defer pagemap.Close()
+
+	pageSize := uint64(os.Getpagesize())
+	entry := make([]byte, 8)
+	for i := range mismatches {
+		page := mismatches[i].VirtualAddress / pageSize
+		if _, err := pagemap.ReadAt(entry, int64(page*8)); err != nil {
+			return
+		}
+		value := binary.LittleEndian.Uint64(entry)
+		const present = 1 << 63
+		pfn := value & (1<<55 - 1)
+		if value&present == 0 || pfn == 0 {
+			continue
+		}
+		mismatches[i].PhysicalAddress = pfn*pageSize + mismatches[i].VirtualAddress%pageSize
+	}
+}
+
+// formatMemoryMismatch formats one mismatch for the console and reports
+func formatMemoryMismatch(m MemoryMismatch) string {
+	addr := fmt.Sprintf("virt 0x%012x", m.VirtualAddress)
+	if m.PhysicalAddress != 0 {
+		addr += fmt.Sprintf(" phys 0x%012x", m.PhysicalAddress)
+	}
+	return fmt.Sprintf("pass %d, %s, %s: expected 0x%016x, got 0x%016x (bits 0x%016x)",
+		m.Pass, m.Pattern, addr, m.Expected, m.Actual, m.Expected^m.Actual)
+}
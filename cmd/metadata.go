@@ -0,0 +1,283 @@
+package cmd
+
+import (
+	"fmt"
+	htmlpkg "html"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// metadataFilesPerDir is the number of files in each leaf directory of the tree
+const metadataFilesPerDir = 100
+
+// metadataFileSize is the payload written to each file, small enough to stay a metadata workload
+const metadataFileSize = 4096
+
+// metadataOperations are the phases of the benchmark, in the order they run
+var metadataOperations = []string{"create", "stat", "readdir", "rename", "unlink"}
+
+// MetadataResult holds the small-file metadata benchmark of one target
+type MetadataResult struct {
+	Directory    string            // Directory the tree was created in
+	Files        int               // Files per variant
+	FilesPerDir  int               // Files in each directory of the tree
+	FileSize     int               // Bytes written to each file
+	Variants     []MetadataVariant // Single- and multi-threaded runs
+	ErrorMessage string            // Error message if the benchmark failed
+}
+
+// MetadataVariant is one run of all operations with a fixed number of threads
+type MetadataVariant struct {
+	Threads    int                // Worker threads, each with its own subtree
+	Operations []MetadataOpResult // In metadataOperations order
+}
+
+// MetadataOpResult holds throughput and latency of one operation
+type MetadataOpResult struct {
+	Operation string  // create, stat, readdir, rename or unlink
+	Ops       int     // Operations performed (files, or directories for readdir)
+	Seconds   float64 // Wall time of the phase across all threads
+	OpsPerSec float64 // Ops / Seconds
+	MeanNs    float64 // Mean latency of one operation
+	P50Ns     float64
+	P99Ns     float64
+	MaxNs     float64
+}
+
+// metadataWorker is one thread's share of the tree
+type metadataWorker struct {
+	dirs  []string
+	files []string // Current name of every file, updated by rename
+}
+
+// runMetadataBenchmark creates, stats, lists, renames and unlinks files in a directory tree under dir,
+// once with a single thread and once with threads workers that each own a subtree
+func runMetadataBenchmark(dir string, files, threads int) MetadataResult {
+	result := MetadataResult{Directory: dir, Files: files, FilesPerDir: metadataFilesPerDir, FileSize: metadataFileSize}
+	if files <= 0 {
+		result.ErrorMessage = fmt.Sprintf("invalid file count %d", files)
+		return result
+	}
+	if threads <= 0 {
+		threads = min(runtime.NumCPU(), 16)
+	}
+
+	variants := []int{1}
+	if threads > 1 {
+		variants = append(variants, threads)
+	}
+
+	fmt.Printf("      Running metadata benchmark: %d files, %d per directory, in %s\n", files, metadataFilesPerDir, dir)
+	fmt.Println("      ------------------------------------------------------------------------------------------------")
+	fmt.Printf("      %-8s | %-9s | %-12s | %-12s | %-12s | %-12s\n", "Threads", "Operation", "Ops/s", "Mean", "p99", "Max")
+	fmt.Println("      ------------------------------------------------------------------------------------------------")
+	for _, n := range variants {
+		variantDir := filepath.Join(dir, fmt.Sprintf("metadata_t%d", n))
+		variant, err := runMetadataVariant(variantDir, files, n)
+		os.RemoveAll(variantDir)
+		if err != nil {
+			result.ErrorMessage = fmt.Sprintf("%d thread(s): %v", n, err)
+			fmt.Printf("      Error: %s\n", result.ErrorMessage)
+			break
+		}
+		for _, op := range variant.Operations {
+			fmt.Printf("      %-8d | %-9s | %-12.0f | %-12s | %-12s | %-12s\n", n, op.Operation, op.OpsPerSec,
+				formatLatencyNs(op.MeanNs), formatLatencyNs(op.P99Ns), formatLatencyNs(op.MaxNs))
+		}
+		result.Variants = append(result.Variants, variant)
+	}
+	fmt.Println("      ------------------------------------------------------------------------------------------------")
+	return result
+}
+
+// runMetadataVariant runs every operation over the whole tree, with all threads finishing a phase
+// before the next starts
+func runMetadataVariant(dir string, files, threads int) (MetadataVariant, error) {
+	variant := MetadataVariant{Threads: threads}
+
+	// Spread the files over the workers, and each worker's files over directories of metadataFilesPerDir
+	workers := make([]metadataWorker, threads)
+	for w := range workers {
+		count := files / threads
+		if w < files%threads {
+			count++
+		}
+		for i := 0; i < count; i++ {
+			if i%metadataFilesPerDir == 0 {
+				workers[w].dirs = append(workers[w].dirs, filepath.Join(dir, fmt.Sprintf("w%03d", w), fmt.Sprintf("d%05d", i/metadataFilesPerDir)))
+			}
+			workers[w].files = append(workers[w].files, filepath.Join(workers[w].dirs[len(workers[w].dirs)-1], fmt.Sprintf("f%06d", i)))
+		}
+		for _, d := range workers[w].dirs {
+			if err := os.MkdirAll(d, 0755); err != nil {
+				return variant, err
+			}
+		}
+	}
+
+	payload := make([]byte, metadataFileSize)
+	for _, operation := range metadataOperations {
+		var op func(w *metadataWorker, i int) error
+		count := func(w *metadataWorker) int { return len(w.files) }
+		switch operation {
+		case "create":
+			op = func(w *metadataWorker, i int) error {
+				f, err := os.OpenFile(w.files[i], os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+				if err != nil {
+					return err
+				}
+				if _, err := f.Write(payload); err != nil {
+					f.Close()
+					return err
+				}
+				return f.Close()
+			}
+		case "stat":
+			op = func(w *metadataWorker, i int) error {
+				_, err := os.Lstat(w.files[i])
+				return err
+			}
+		case "readdir":
+			count = func(w *metadataWorker) int { return len(w.dirs) }
+			op = func(w *metadataWorker, i int) error {
+				_, err := os.ReadDir(w.dirs[i])
+				return err
+			}
+		case "rename":
+			op = func(w *metadataWorker, i int) error {
+				renamed := w.files[i] + ".r"
+				if err := os.Rename(w.files[i], renamed); err != nil {
+					return err
+				}
+				w.files[i] = renamed
+				return nil
+			}
+		case "unlink":
+			op = func(w *metadataWorker, i int) error {
+				return os.Remove(w.files[i])
+			}
+		}
+
+		opResult, err := runMetadataPhase(operation, workers, count, op)
+		if err != nil {
+			return variant, fmt.Errorf("%s: %w", operation, err)
+		}
+		variant.Operations = append(variant.Operations, opResult)
+	}
+	return variant, nil
+}
+
+// runMetadataPhase runs one operation on all workers in parallel and collects the per-operation latencies
+func runMetadataPhase(operation string, workers []metadataWorker, count func(*metadataWorker) int, op func(*metadataWorker, int) error) (MetadataOpResult, error) {
+	latencies := make([][]int64, len(workers))
+	errs := make([]error, len(workers))
+
+	var wg sync.WaitGroup
+	start := time.Now()
+	for w := range workers {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			worker := &workers[w]
+			n := count(worker)
+			latencies[w] = make([]int64, 0, n)
+			for i := 0; i < n; i++ {
+				opStart := time.Now()
+				if err := op(worker, i); err != nil {
+					errs[w] = err
+					return
+				}
+				latencies[w] = append(latencies[w], time.Since(opStart).Nanoseconds())
+			}
+		}(w)
+	}
+	wg.Wait()
+	elapsed := time.Since(start).Seconds()
+
+	result := MetadataOpResult{Operation: operation, Seconds: elapsed}
+	for _, err := range errs {
+		if err != nil {
+			return result, err
+		}
+	}
+
+	var all []int64
+	for _, l := range latencies {
+		all = append(all, l...)
+	}
+	if len(all) == 0 || elapsed <= 0 {
+		return result, nil
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i] < all[j] })
+	var total int64
+	for _, ns := range all {
+		total += ns
+	}
+	result.Ops = len(all)
+	result.OpsPerSec = float64(len(all)) / elapsed
+	result.MeanNs = float64(total) / float64(len(all))
+	result.P50Ns = float64(all[len(all)*50/100])
+	result.P99Ns = float64(all[len(all)*99/100])
+	result.MaxNs = float64(all[len(all)-1])
+	return result, nil
+}
+
+// metadataOpsPerSec returns the throughput of an operation in a variant, or 0 if it did not run
+func metadataOpsPerSec(variant MetadataVariant, operation string) float64 {
+	for _, op := range variant.Operations {
+		if op.Operation == operation {
+			return op.OpsPerSec
+		}
+	}
+	return 0
+}
+
+// printMetadataResult prints the metadata benchmark of a device
+func printMetadataResult(indent string, result MetadataResult) {
+	if len(result.Variants) == 0 && result.ErrorMessage == "" {
+		return
+	}
+	fmt.Printf("%sMetadata (%d files of %s, %d per directory):\n", indent, result.Files, humanReadableBytes(uint64(result.FileSize)), result.FilesPerDir)
+	for _, variant := range result.Variants {
+		var cells []string
+		for _, op := range variant.Operations {
+			cells = append(cells, fmt.Sprintf("%s %.0f/s (p99 %s)", op.Operation, op.OpsPerSec, formatLatencyNs(op.P99Ns)))
+		}
+		fmt.Printf("%s  %2d thread(s): %s\n", indent, variant.Threads, strings.Join(cells, ", "))
+	}
+	if result.ErrorMessage != "" {
+		fmt.Printf("%s  Error: %s\n", indent, result.ErrorMessage)
+	}
+}
+
+// metadataHTML renders the metadata benchmark of a device as a table with one row per operation and thread count
+func metadataHTML(result MetadataResult) string {
+	if len(result.Variants) == 0 && result.ErrorMessage == "" {
+		return ""
+	}
+	html := `
+        <h4>Metadata and Small Files</h4>
+        <p>` + fmt.Sprintf("%d files of %s, %d per directory; readdir counts directories.", result.Files, humanReadableBytes(uint64(result.FileSize)), result.FilesPerDir) + `</p>
+        <table>
+            <tr><th>Threads</th><th>Operation</th><th>Ops/s</th><th>Mean</th><th>p50</th><th>p99</th><th>Max</th></tr>`
+	for _, variant := range result.Variants {
+		for _, op := range variant.Operations {
+			html += fmt.Sprintf(`
+            <tr><td>%d</td><td>%s</td><td class="highlight">%.0f</td><td>%s</td><td>%s</td><td>%s</td><td>%s</td></tr>`,
+				variant.Threads, op.Operation, op.OpsPerSec, formatLatencyNs(op.MeanNs), formatLatencyNs(op.P50Ns),
+				formatLatencyNs(op.P99Ns), formatLatencyNs(op.MaxNs))
+		}
+	}
+	html += `
+        </table>`
+	if result.ErrorMessage != "" {
+		html += `
+        <p class="warning">` + htmlpkg.EscapeString(result.ErrorMessage) + `</p>`
+	}
+	return html
+}
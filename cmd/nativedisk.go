@@ -0,0 +1,315 @@
+package cmd
+
+import (
+	"fmt"
+	"math"
+	"math/bits"
+	"math/rand"
+	"os"
+	"os/exec"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// nativeDiskEngine is the label of the built-in Go disk engine, used when fio is not installed
+const nativeDiskEngine = "native-odirect"
+
+// nativeDiskAlign is the offset and length alignment O_DIRECT needs on every common device
+const nativeDiskAlign = 4096
+
+// nativeDiskPercentiles are the completion latency percentiles reported, matching fio's default list
+var nativeDiskPercentiles = []float64{1, 5, 10, 20, 30, 40, 50, 60, 70, 80, 90, 95, 99, 99.5, 99.9, 99.95, 99.99}
+
+// fioInstalled reports whether the fio binary is on the PATH
+func fioInstalled() bool {
+	_, err := exec.LookPath("fio")
+	return err == nil
+}
+
+// nativeDiskNote marks a summary line measured by the native engine
+func nativeDiskNote(test FioTestResult) string {
+	if test.Engine != nativeDiskEngine {
+		return ""
+	}
+	return " (" + nativeDiskEngine + ", not fio)"
+}
+
+// nativeDiskScenarioSupported returns why the native engine cannot run a scenario, or "" if it can.
+// Only plain O_DIRECT jobs are supported: no extra fio options and block sizes the device can align.
+func nativeDiskScenarioSupported(scenario fioScenario) string {
+	if len(scenario.extraArgs) > 0 || scenario.engine != "" {
+		return "needs fio options"
+	}
+	bs, err := parseSizeBytes(scenario.bs)
+	if err != nil || bs == 0 || bs%nativeDiskAlign != 0 {
+		return fmt.Sprintf("block size %s is not a multiple of %d", scenario.bs, nativeDiskAlign)
+	}
+	switch scenario.rw {
+	case "read", "write", "randread", "randwrite", "randrw":
+		return ""
+	}
+	return "access pattern " + scenario.rw + " is not supported"
+}
+
+// nativeLatencyHistogram records latencies in log-linear buckets: 16 per power of two, so percentiles
+// are accurate to within about 3% without keeping every sample
+type nativeLatencyHistogram struct {
+	counts  [976]uint64 // Enough buckets for any uint64 latency
+	samples uint64
+	bytes   uint64 // Bytes transferred by the recorded I/Os
+	sumNs   float64
+	sumSqNs float64
+	minNs   uint64
+	maxNs   uint64
+}
+
+// nativeLatencyBucket returns the bucket index of a latency
+func nativeLatencyBucket(ns uint64) int {
+	if ns < 16 {
+		return int(ns)
+	}
+	exp := bits.Len64(ns)
+	shift := exp - 5
+	return (exp-4)*16 + int(ns>>shift) - 16
+}
+
+// nativeLatencyBucketValue returns the midpoint latency of a bucket
+func nativeLatencyBucketValue(index int) float64 {
+	if index < 16 {
+		return float64(index)
+	}
+	exp := index/16 + 4
+	top := uint64(index%16 + 16)
+	shift := exp - 5
+	lower := top << shift
+	upper := (top+1)<<shift - 1
+	return float64(lower+upper) / 2
+}
+
+// record adds one I/O of size bytes that took ns
+func (h *nativeLatencyHistogram) record(ns uint64, size int) {
+	h.counts[nativeLatencyBucket(ns)]++
+	if h.samples == 0 || ns < h.minNs {
+		h.minNs = ns
+	}
+	if ns > h.maxNs {
+		h.maxNs = ns
+	}
+	h.samples++
+	h.sumNs += float64(ns)
+	h.sumSqNs += float64(ns) * float64(ns)
+	h.bytes += uint64(size)
+}
+
+// merge adds the samples of another histogram
+func (h *nativeLatencyHistogram) merge(other *nativeLatencyHistogram) {
+	if other.samples == 0 {
+		return
+	}
+	for i, count := range other.counts {
+		h.counts[i] += count
+	}
+	if h.samples == 0 || other.minNs < h.minNs {
+		h.minNs = other.minNs
+	}
+	if other.maxNs > h.maxNs {
+		h.maxNs = other.maxNs
+	}
+	h.samples += other.samples
+	h.sumNs += other.sumNs
+	h.sumSqNs += other.sumSqNs
+	h.bytes += other.bytes
+}
+
+// stats converts the histogram into the latency statistics fio results carry
+func (h *nativeLatencyHistogram) stats() FioLatencyStats {
+	stats := FioLatencyStats{Samples: h.samples}
+	if h.samples == 0 {
+		return stats
+	}
+	n := float64(h.samples)
+	stats.MinNs, stats.MaxNs = float64(h.minNs), float64(h.maxNs)
+	stats.MeanNs = h.sumNs / n
+	stats.StdDevNs = math.Sqrt(math.Max(h.sumSqNs/n-stats.MeanNs*stats.MeanNs, 0))
+
+	var seen uint64
+	next := 0
+	pow2 := make(map[uint64]uint64)
+	for i, count := range h.counts {
+		if count == 0 {
+			continue
+		}
+		seen += count
+		value := nativeLatencyBucketValue(i)
+		for next < len(nativeDiskPercentiles) && float64(seen) >= nativeDiskPercentiles[next]/100*n {
+			stats.Percentiles = append(stats.Percentiles, FioPercentile{Percentile: nativeDiskPercentiles[next], Ns: value})
+			next++
+		}
+		upper := uint64(1)
+		if ns := uint64(value); ns > 1 {
+			upper = 1 << bits.Len64(ns-1)
+		}
+		pow2[upper] += count
+	}
+	for upper, count := range pow2 {
+		stats.Histogram = append(stats.Histogram, FioLatencyBucket{UpperNs: upper, Count: count})
+	}
+	sort.Slice(stats.Histogram, func(i, j int) bool { return stats.Histogram[i].UpperNs < stats.Histogram[j].UpperNs })
+	stats.P50Ns = fioPercentileAt(stats.Percentiles, 50)
+	stats.P90Ns = fioPercentileAt(stats.Percentiles, 90)
+	stats.P99Ns = fioPercentileAt(stats.Percentiles, 99)
+	stats.P999Ns = fioPercentileAt(stats.Percentiles, 99.9)
+	stats.P9999Ns = fioPercentileAt(stats.Percentiles, 99.99)
+	return stats
+}
+
+// direction converts the histogram of one direction into a fio-style result over elapsed seconds
+func (h *nativeLatencyHistogram) direction(elapsed float64) FioDirectionResult {
+	result := FioDirectionResult{TotalIOs: h.samples}
+	if elapsed > 0 {
+		result.IOPS = float64(h.samples) / elapsed
+		result.BandwidthMB = float64(h.bytes) / elapsed / (1024 * 1024)
+	}
+	// Each worker issues one synchronous I/O at a time, so there is no separate submission latency
+	result.Latency.Completion = h.stats()
+	result.Latency.Total = result.Latency.Completion
+	return result
+}
+
+// nativeDiskLayout makes sure a test file is at least size bytes, writing it out with O_DIRECT
+// like fio lays out its files. Block devices are left alone.
+func nativeDiskLayout(path string, size int64) error {
+	if info, err := os.Stat(path); err == nil && (info.Mode()&os.ModeDevice != 0 || info.Size() >= size) {
+		return nil
+	}
+	file, err := openDirect(path, os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	const chunk = 1024 * 1024
+	buf, err := mmapAnonymous(chunk)
+	if err != nil {
+		return err
+	}
+	defer munmapBuffer(buf)
+	rand.New(rand.NewSource(1)).Read(buf) // Incompressible, so compressing drives do real work
+
+	for offset := int64(0); offset < size; offset += chunk {
+		if _, err := file.WriteAt(buf, offset); err != nil {
+			return fmt.Errorf("laying out %s: %w", path, err)
+		}
+	}
+	return file.Sync()
+}
+
+// runNativeDiskTest runs a scenario with the built-in engine: numjobs jobs with iodepth workers each,
+// every worker issuing synchronous O_DIRECT reads and writes from page-aligned buffers. Like fio without
+// --time_based, each job stops after size bytes of I/O or the scenario runtime, whichever comes first.
+func runNativeDiskTest(result *FioTestResult, scenario fioScenario, path string, size uint64, readOnly bool) error {
+	if reason := nativeDiskScenarioSupported(scenario); reason != "" {
+		return fmt.Errorf("%s: %s", nativeDiskEngine, reason)
+	}
+	if readOnly && fioScenarioWrites(scenario) {
+		return fmt.Errorf("%s writes to a read-only target", scenario.name)
+	}
+	bs, _ := parseSizeBytes(scenario.bs)
+	blocks := int64(size / bs)
+	if blocks == 0 {
+		return fmt.Errorf("test size %s is smaller than the block size %s", humanReadableBytes(size), scenario.bs)
+	}
+
+	if !readOnly {
+		if err := nativeDiskLayout(path, int64(size)); err != nil {
+			return err
+		}
+	}
+	flag := os.O_RDWR
+	if readOnly {
+		flag = os.O_RDONLY
+	}
+	file, err := openDirect(path, flag, 0)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	numjobs, iodepth := max(scenario.numjobs, 1), max(scenario.iodepth, 1)
+	workers := numjobs * iodepth
+	reads := make([]nativeLatencyHistogram, workers)
+	writes := make([]nativeLatencyHistogram, workers)
+	errs := make([]error, workers)
+	budgets := make([]int64, numjobs)   // I/Os left in each job
+	positions := make([]int64, numjobs) // Next sequential block of each job
+	for job := range budgets {
+		budgets[job] = blocks
+	}
+
+	deadline := time.Now().Add(time.Duration(fioScenarioRuntime(scenario)) * time.Second)
+	var wg sync.WaitGroup
+	start := time.Now()
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			job := w / iodepth
+			buf, err := mmapAnonymous(int(bs))
+			if err != nil {
+				errs[w] = err
+				return
+			}
+			defer munmapBuffer(buf)
+			rng := rand.New(rand.NewSource(time.Now().UnixNano() + int64(w)))
+			rng.Read(buf)
+
+			random := scenario.rw == "randread" || scenario.rw == "randwrite" || scenario.rw == "randrw"
+			for time.Now().Before(deadline) && atomic.AddInt64(&budgets[job], -1) >= 0 {
+				block := rng.Int63n(blocks)
+				if !random {
+					block = (atomic.AddInt64(&positions[job], 1) - 1) % blocks
+				}
+				write := scenario.rw == "write" || scenario.rw == "randwrite"
+				if scenario.rw == "randrw" {
+					write = rng.Intn(100) >= scenario.rwmixread
+				}
+
+				ioStart := time.Now()
+				if write {
+					_, err = file.WriteAt(buf, block*int64(bs))
+				} else {
+					_, err = file.ReadAt(buf, block*int64(bs))
+				}
+				ns := uint64(time.Since(ioStart).Nanoseconds())
+				if err != nil {
+					errs[w] = err
+					return
+				}
+				if write {
+					writes[w].record(ns, len(buf))
+				} else {
+					reads[w].record(ns, len(buf))
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+	elapsed := time.Since(start).Seconds()
+
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	var read, write nativeLatencyHistogram
+	for w := range reads {
+		read.merge(&reads[w])
+		write.merge(&writes[w])
+	}
+	result.Read = read.direction(elapsed)
+	result.Write = write.direction(elapsed)
+	setFioTotals(result)
+	return nil
+}
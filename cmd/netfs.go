@@ -0,0 +1,206 @@
+package cmd
+
+import (
+	"fmt"
+	htmlpkg "html"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// networkFilesystemKinds maps mount table filesystem types to the storage they reach over the network
+var networkFilesystemKinds = map[string]string{
+	"nfs":       "NFS",
+	"nfs4":      "NFS",
+	"cifs":      "SMB",
+	"smb3":      "SMB",
+	"ceph":      "CephFS",
+	"glusterfs": "GlusterFS",
+	"lustre":    "Lustre",
+	"beegfs":    "BeeGFS",
+	"gpfs":      "GPFS",
+	"9p":        "9P",
+	"virtiofs":  "virtiofs",
+}
+
+// NetworkFilesystem describes the network or FUSE filesystem a benchmark target is on
+type NetworkFilesystem struct {
+	Kind       string   // NFS, SMB, CephFS, FUSE, ...; empty for local storage
+	FSType     string   // Filesystem type from the mount table (e.g., nfs4, cifs, ceph, fuse.sshfs)
+	Source     string   // Mount source as listed in the mount table
+	Server     string   // Server, monitor addresses or FUSE source
+	Export     string   // Exported path or share
+	MountPoint string   // Mount point of the filesystem
+	Version    string   // Protocol version from the vers= option, if given
+	Options    string   // Mount options
+	DirectIO   bool     // Whether O_DIRECT reads and writes work on the target
+	FioArgs    []string // fio options added to every scenario on this target
+	Notes      []string // How to read the results on this filesystem
+}
+
+// networkFilesystemKind returns the kind of a network or FUSE filesystem type, or "" for local ones.
+// fuseblk (e.g., ntfs-3g) is block-backed but still goes through the FUSE daemon.
+func networkFilesystemKind(fsType string) string {
+	if kind, ok := networkFilesystemKinds[fsType]; ok {
+		return kind
+	}
+	if fsType == "fuse" || fsType == "fuseblk" || strings.HasPrefix(fsType, "fuse.") {
+		return "FUSE"
+	}
+	return ""
+}
+
+// splitNetworkSource splits a mount source into server and export:
+// "nas:/export" (NFS, sshfs), "//nas/share" (SMB), "mon1:6789,mon2:6789:/path" and "user@fsid.fs=/path" (CephFS)
+func splitNetworkSource(source string) (server, export string) {
+	if i := strings.Index(source, "=/"); i >= 0 {
+		return source[:i], source[i+1:]
+	}
+	if strings.HasPrefix(source, "//") {
+		server, share, _ := strings.Cut(source[2:], "/")
+		return server, "/" + share
+	}
+	if i := strings.Index(source, ":/"); i >= 0 {
+		return source[:i], source[i+1:]
+	}
+	return source, ""
+}
+
+// mountOption returns the value of a key=value mount option
+func mountOption(options, key string) string {
+	for _, option := range strings.Split(options, ",") {
+		if value, ok := strings.CutPrefix(option, key+"="); ok {
+			return value
+		}
+	}
+	return ""
+}
+
+// detectNetworkFilesystem finds the mount holding path and describes it if it is a network or FUSE
+// filesystem. testDir is probed for O_DIRECT support, which decides the fio options.
+func detectNetworkFilesystem(topology *storageTopology, path, testDir string) (NetworkFilesystem, bool) {
+	mount, ok := topology.mountFor(path)
+	kind := networkFilesystemKind(mount.fsType)
+	if !ok || kind == "" {
+		return NetworkFilesystem{}, false
+	}
+
+	fs := NetworkFilesystem{
+		Kind:       kind,
+		FSType:     mount.fsType,
+		Source:     mount.source,
+		MountPoint: mount.mountPoint,
+		Version:    mountOption(mount.options, "vers"),
+		Options:    mount.options,
+	}
+	fs.Server, fs.Export = splitNetworkSource(mount.source)
+	if addrs := mountOption(mount.options, "mon_addr"); addrs != "" && kind == "CephFS" {
+		fs.Server = strings.ReplaceAll(addrs, "/", ",")
+	}
+
+	if err := probeDirectIO(testDir); err != nil {
+		// Buffered I/O instead: drop the client cache before each job and flush writes before it ends
+		fs.FioArgs = []string{"--direct=0", "--invalidate=1", "--end_fsync=1"}
+		fs.Notes = append(fs.Notes, fmt.Sprintf("O_DIRECT is not supported here (%v), so fio runs buffered and results include the client page cache", err))
+	} else {
+		fs.DirectIO = true
+		fs.Notes = append(fs.Notes, "O_DIRECT only bypasses the client cache; the server may still serve reads and acknowledge writes from its own cache")
+	}
+	if kind != "FUSE" {
+		// Preallocation is emulated or unsupported on most network filesystems; lay files out with writes
+		fs.FioArgs = append(fs.FioArgs, "--fallocate=none")
+		fs.Notes = append(fs.Notes, "Latency includes the network round trip to the server")
+	} else {
+		fs.Notes = append(fs.Notes, "Every I/O passes through the FUSE daemon in user space")
+	}
+	switch {
+	case kind == "NFS" && slices.Contains(strings.Split(mount.options, ","), "sync"):
+		fs.Notes = append(fs.Notes, "Mounted with sync: every write waits for the server")
+	case kind == "SMB" && mountOption(mount.options, "cache") == "none":
+		fs.Notes = append(fs.Notes, "Mounted with cache=none: buffered I/O is not cached by the client either")
+	}
+	return fs, true
+}
+
+// probeDirectIO writes and reads one aligned block with O_DIRECT in dir
+func probeDirectIO(dir string) error {
+	path := filepath.Join(dir, "hyprbench_odirect_probe")
+	defer os.Remove(path)
+	file, err := openDirect(path, os.O_CREATE|os.O_RDWR|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	buf, err := mmapAnonymous(nativeDiskAlign)
+	if err != nil {
+		return err
+	}
+	defer munmapBuffer(buf)
+	if _, err := file.WriteAt(buf, 0); err != nil {
+		return err
+	}
+	_, err = file.ReadAt(buf, 0)
+	return err
+}
+
+// networkStorageLabel names a network target for the results, e.g. "NFS v4.2 nas:/export (network storage)"
+func networkStorageLabel(fs NetworkFilesystem) string {
+	label := fs.Kind
+	if fs.Version != "" {
+		label += " v" + fs.Version
+	}
+	if fs.Kind == "FUSE" {
+		return label + " " + fs.Source + " (" + fs.FSType + ", FUSE storage)"
+	}
+	return label + " " + fs.Source + " (network storage)"
+}
+
+// printNetworkFilesystem prints the server, export, options and caveats of a network target
+func printNetworkFilesystem(indent string, fs NetworkFilesystem) {
+	if fs.Kind == "" {
+		return
+	}
+	fmt.Printf("%sNetwork Storage: %s (%s), server %s", indent, fs.Kind, fs.FSType, fs.Server)
+	if fs.Export != "" {
+		fmt.Printf(", export %s", fs.Export)
+	}
+	fmt.Println()
+	fmt.Printf("%s  Mount options: %s\n", indent, fs.Options)
+	if len(fs.FioArgs) > 0 {
+		fmt.Printf("%s  fio options: %s\n", indent, strings.Join(fs.FioArgs, " "))
+	}
+	for _, note := range fs.Notes {
+		fmt.Printf("%s  Note: %s\n", indent, note)
+	}
+}
+
+// networkFilesystemHTML renders the network filesystem details of a target
+func networkFilesystemHTML(fs NetworkFilesystem) string {
+	if fs.Kind == "" {
+		return ""
+	}
+	html := `
+        <p class="warning">Network storage: ` + fs.Kind + ` (` + htmlpkg.EscapeString(fs.FSType) + `), server ` + htmlpkg.EscapeString(fs.Server)
+	if fs.Export != "" {
+		html += `, export ` + htmlpkg.EscapeString(fs.Export)
+	}
+	html += `</p>
+        <p>Mount options: ` + htmlpkg.EscapeString(fs.Options) + `</p>`
+	if len(fs.FioArgs) > 0 {
+		html += `
+        <p>fio options: ` + strings.Join(fs.FioArgs, " ") + `</p>`
+	}
+	if len(fs.Notes) > 0 {
+		html += `
+        <ul>`
+		for _, note := range fs.Notes {
+			html += `
+            <li>` + htmlpkg.EscapeString(note) + `</li>`
+		}
+		html += `
+        </ul>`
+	}
+	return html
+}
@@ -0,0 +1,315 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// NumaResults holds the node-by-node memory bandwidth and latency matrix
+type NumaResults struct {
+	Mode          string      // "numa" on multi-node systems, "uma" when only a single node exists
+	Method        string      // How placement was enforced: "mbind", "numactl" or "none"
+	Nodes         []int       // NUMA node IDs in matrix order
+	NodeCPUs      []string    // CPU list of each node (e.g., "0-15,32-47")
+	TestSizeBytes uint64      // Buffer size used for each matrix cell
+	BandwidthMBs  [][]float64 // [CPU node][memory node] copy bandwidth in MB/s (-1 if not measured)
+	LatencyNs     [][]float64 // [CPU node][memory node] random-access latency in ns (-1 if not measured)
+	TestCompleted bool        // Whether every cell of the matrix was measured
+	ErrorMessage  string      // Error message if the test failed
+}
+
+// numaNode describes one node from /sys/devices/system/node
+type numaNode struct {
+	ID      int
+	CPUList string
+	CPUs    []int
+}
+
+// numaLatencyHops is the number of dependent loads timed per matrix cell
+const numaLatencyHops = 10000000
+
+// numaWorkerSize is the buffer size for the numa-worker subcommand
+var numaWorkerSize uint64
+
+// numaWorkerCmd measures a single matrix cell. It is re-executed under numactl when mbind is unavailable.
+var numaWorkerCmd = &cobra.Command{
+	Use:    "numa-worker",
+	Hidden: true,
+	Short:  "Internal: measure memory bandwidth and latency under the current NUMA policy",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		bw, lat, err := measureMemoryCell(int(numaWorkerSize), runtime.NumCPU(), -1, nil)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("bandwidth_mbs=%.2f latency_ns=%.2f\n", bw, lat)
+		return nil
+	},
+}
+
+func init() {
+	numaWorkerCmd.Flags().Uint64Var(&numaWorkerSize, "size", 512*1024*1024, "Buffer size in bytes")
+	rootCmd.AddCommand(numaWorkerCmd)
+}
+
+// runNumaMemoryBenchmarks measures bandwidth and latency from every node's CPUs to every node's memory
+func runNumaMemoryBenchmarks(sysInfo *SystemInfo) error {
+	fmt.Println("  Running NUMA memory bandwidth/latency matrix...")
+
+	sizeBytes, err := parseSizeBytes(memoryNumaSize)
+	if err != nil {
+		sysInfo.NumaResults.ErrorMessage = fmt.Sprintf("Invalid test size: %v", err)
+		return fmt.Errorf("invalid --memory-numa-size '%s': %w", memoryNumaSize, err)
+	}
+
+	nodes, err := detectNumaNodes()
+	if err != nil {
+		fmt.Printf("    Warning: could not read NUMA topology: %v\n", err)
+	}
+
+	results := NumaResults{
+		Mode:          "numa",
+		Method:        "mbind",
+		TestSizeBytes: sizeBytes,
+	}
+
+	if len(nodes) <= 1 {
+		// UMA machine (or no NUMA support in the kernel): a single unpinned cell
+		fmt.Println("    Single memory node detected, measuring one UMA cell.")
+		results.Mode = "uma"
+		results.Method = "none"
+		node := numaNode{ID: 0, CPUList: fmt.Sprintf("0-%d", runtime.NumCPU()-1)}
+		if len(nodes) == 1 {
+			node = nodes[0]
+		}
+		node.CPUs = nil // Let the scheduler place workers
+		nodes = []numaNode{node}
+	} else if probeErr := probeMbind(nodes[0].ID); probeErr != nil {
+		if _, lookErr := exec.LookPath("numactl"); lookErr == nil {
+			fmt.Printf("    mbind unavailable (%v), falling back to numactl.\n", probeErr)
+			results.Method = "numactl"
+		} else {
+			results.ErrorMessage = fmt.Sprintf("Cannot enforce NUMA placement: %v (numactl not found)", probeErr)
+			sysInfo.NumaResults = results
+			return fmt.Errorf("cannot enforce NUMA placement: %w", probeErr)
+		}
+	}
+
+	fmt.Printf("    Mode: %s, placement: %s, nodes: %d, buffer per cell: %s\n",
+		results.Mode, results.Method, len(nodes), humanReadableBytes(sizeBytes))
+
+	results.BandwidthMBs = make([][]float64, len(nodes))
+	results.LatencyNs = make([][]float64, len(nodes))
+	for _, n := range nodes {
+		results.Nodes = append(results.Nodes, n.ID)
+		results.NodeCPUs = append(results.NodeCPUs, n.CPUList)
+	}
+
+	results.TestCompleted = true
+	for i, cpuNode := range nodes {
+		results.BandwidthMBs[i] = make([]float64, len(nodes))
+		results.LatencyNs[i] = make([]float64, len(nodes))
+
+		for j, memNode := range nodes {
+			results.BandwidthMBs[i][j] = -1
+			results.LatencyNs[i][j] = -1
+
+			if results.Mode == "numa" && len(cpuNode.CPUs) == 0 {
+				// Memory-only node (e.g., CXL expander): nothing can run there
+				continue
+			}
+
+			fmt.Printf("    Measuring CPU node %d -> memory node %d...\n", cpuNode.ID, memNode.ID)
+
+			var bw, lat float64
+			var cellErr error
+			switch results.Method {
+			case "numactl":
+				bw, lat, cellErr = measureMemoryCellNumactl(cpuNode.ID, memNode.ID, sizeBytes)
+			case "mbind":
+				bw, lat, cellErr = measureMemoryCell(int(sizeBytes), len(cpuNode.CPUs), memNode.ID, cpuNode.CPUs)
+			default:
+				bw, lat, cellErr = measureMemoryCell(int(sizeBytes), runtime.NumCPU(), -1, nil)
+			}
+			if cellErr != nil {
+				fmt.Printf("      Error: %v\n", cellErr)
+				results.TestCompleted = false
+				results.ErrorMessage = cellErr.Error()
+				continue
+			}
+
+			results.BandwidthMBs[i][j] = bw
+			results.LatencyNs[i][j] = lat
+			fmt.Printf("      Bandwidth: %.2f MB/s, Latency: %.1f ns\n", bw, lat)
+		}
+	}
+
+	sysInfo.NumaResults = results
+	return nil
+}
+
+// measureMemoryCell allocates a buffer (bound to memNode when memNode >= 0) and measures it
+// from workers pinned to cpus (unpinned when cpus is empty)
+func measureMemoryCell(size, workers, memNode int, cpus []int) (float64, float64, error) {
+	buf, err := mmapAnonymous(size)
+	if err != nil {
+		return 0, 0, fmt.Errorf("allocating %d bytes: %w", size, err)
+	}
+	defer munmapBuffer(buf)
+
+	if memNode >= 0 {
+		if err := mbindNode(buf, memNode); err != nil {
+			return 0, 0, err
+		}
+	}
+	touchPages(buf)
+
+	var pin func() error
+	if len(cpus) > 0 {
+		pin = func() error { return setThreadAffinity(cpus) }
+	}
+	if workers < 1 {
+		workers = 1
+	}
+
+	bw, err := copyBandwidthMBs(buf, workers, 5, pin)
+	if err != nil {
+		return 0, 0, err
+	}
+	lat, err := randomAccessLatencyNs(buf, numaLatencyHops, pin)
+	if err != nil {
+		return 0, 0, err
+	}
+	return bw, lat, nil
+}
+
+// measureMemoryCellNumactl re-executes hyprbench under numactl to measure one matrix cell
+func measureMemoryCellNumactl(cpuNode, memNode int, size uint64) (float64, float64, error) {
+	self, err := os.Executable()
+	if err != nil {
+		return 0, 0, fmt.Errorf("could not locate hyprbench executable: %w", err)
+	}
+
+	output, err := runCommand("numactl",
+		fmt.Sprintf("--cpunodebind=%d", cpuNode),
+		fmt.Sprintf("--membind=%d", memNode),
+		self, "numa-worker", fmt.Sprintf("--size=%d", size))
+	if err != nil {
+		return 0, 0, err
+	}
+
+	re := regexp.MustCompile(`bandwidth_mbs=([\d.]+) latency_ns=([\d.]+)`)
+	matches := re.FindStringSubmatch(output)
+	if len(matches) != 3 {
+		return 0, 0, fmt.Errorf("could not parse numa-worker output: %s", strings.TrimSpace(output))
+	}
+	bw, _ := strconv.ParseFloat(matches[1], 64)
+	lat, _ := strconv.ParseFloat(matches[2], 64)
+	return bw, lat, nil
+}
+
+// probeMbind checks whether the kernel allows binding memory to a node
+func probeMbind(node int) error {
+	buf, err := mmapAnonymous(1 << 20)
+	if err != nil {
+		return err
+	}
+	defer munmapBuffer(buf)
+	return mbindNode(buf, node)
+}
+
+// detectNumaNodes reads node IDs and their CPU lists from sysfs
+func detectNumaNodes() ([]numaNode, error) {
+	dirs, err := filepath.Glob("/sys/devices/system/node/node[0-9]*")
+	if err != nil {
+		return nil, err
+	}
+
+	var nodes []numaNode
+	for _, dir := range dirs {
+		id, err := strconv.Atoi(strings.TrimPrefix(filepath.Base(dir), "node"))
+		if err != nil {
+			continue
+		}
+		cpuList := ""
+		if data, err := os.ReadFile(filepath.Join(dir, "cpulist")); err == nil {
+			cpuList = strings.TrimSpace(string(data))
+		}
+		cpus, err := parseCPUList(cpuList)
+		if err != nil {
+			return nil, fmt.Errorf("parsing cpulist of node %d: %w", id, err)
+		}
+		nodes = append(nodes, numaNode{ID: id, CPUList: cpuList, CPUs: cpus})
+	}
+
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i].ID < nodes[j].ID })
+	return nodes, nil
+}
+
+// parseCPUList expands a kernel CPU list such as "0-3,8-11" into individual CPU numbers
+func parseCPUList(list string) ([]int, error) {
+	var cpus []int
+	if list == "" {
+		return cpus, nil
+	}
+	for _, part := range strings.Split(list, ",") {
+		part = strings.TrimSpace(part)
+		if lo, hi, found := strings.Cut(part, "-"); found {
+			start, err := strconv.Atoi(lo)
+			if err != nil {
+				return nil, err
+			}
+			end, err := strconv.Atoi(hi)
+			if err != nil {
+				return nil, err
+			}
+			for cpu := start; cpu <= end; cpu++ {
+				cpus = append(cpus, cpu)
+			}
+		} else {
+			cpu, err := strconv.Atoi(part)
+			if err != nil {
+				return nil, err
+			}
+			cpus = append(cpus, cpu)
+		}
+	}
+	return cpus, nil
+}
+
+// numaLocalRemote averages the matrix diagonal (local) and off-diagonal (remote) cells.
+// Remote values are -1 on UMA systems.
+func numaLocalRemote(matrix [][]float64) (float64, float64) {
+	var localSum, remoteSum float64
+	var localCount, remoteCount int
+	for i := range matrix {
+		for j, v := range matrix[i] {
+			if v < 0 {
+				continue
+			}
+			if i == j {
+				localSum += v
+				localCount++
+			} else {
+				remoteSum += v
+				remoteCount++
+			}
+		}
+	}
+	local, remote := -1.0, -1.0
+	if localCount > 0 {
+		local = localSum / float64(localCount)
+	}
+	if remoteCount > 0 {
+		remote = remoteSum / float64(remoteCount)
+	}
+	return local, remote
+}
@@ -0,0 +1,264 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	htmlpkg "html"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// pciDevicesRoot is where the kernel lists PCI functions
+const pciDevicesRoot = "/sys/bus/pci/devices"
+
+// PCIeLink is the negotiated and maximum link of an NVMe controller or network adapter
+type PCIeLink struct {
+	Address      string   // PCI address (e.g., 0000:41:00.0)
+	Class        string   // "NVMe" or "Network"
+	Description  string   // lspci description, or vendor:device IDs
+	Devices      []string // Kernel devices behind the function (e.g., nvme0, enp65s0f0)
+	CurrentSpeed float64  // Negotiated speed in GT/s (0 if unknown)
+	CurrentWidth int      // Negotiated lanes
+	MaxSpeed     float64  // Maximum speed the device supports in GT/s
+	MaxWidth     int      // Maximum lanes the device supports
+	Degraded     bool     // Link trained below its maximum speed or width
+	LimitedBy    string   // Upstream port that cannot go faster, if that explains the degradation
+}
+
+// pcieGenerations maps the per-lane transfer rate to the PCIe generation
+var pcieGenerations = map[float64]int{2.5: 1, 5: 2, 8: 3, 16: 4, 32: 5, 64: 6}
+
+// readPCIeLinks reads current_link_speed/width and max_link_speed/width for every NVMe controller
+// and network adapter. lspciOutput (plain `lspci`) supplies the descriptions.
+func readPCIeLinks(lspciOutput string) []PCIeLink {
+	descriptions := make(map[string]string)
+	scanner := bufio.NewScanner(strings.NewReader(lspciOutput))
+	for scanner.Scan() {
+		// 41:00.0 Non-Volatile memory controller: Samsung Electronics Co Ltd NVMe SSD Controller PM9A1/PM9A3/980PRO
+		if address, description, ok := strings.Cut(strings.TrimSpace(scanner.Text()), " "); ok {
+			descriptions[address] = description
+		}
+	}
+
+	var links []PCIeLink
+	entries, _ := os.ReadDir(pciDevicesRoot)
+	for _, entry := range entries {
+		address := entry.Name()
+		dir := filepath.Join(pciDevicesRoot, address)
+		class := readSysfsValue(filepath.Join(dir, "class"))
+		link := PCIeLink{Address: address}
+		switch {
+		case strings.HasPrefix(class, "0x0108"): // Mass storage, non-volatile memory
+			link.Class = "NVMe"
+			link.Devices = readDirNames(filepath.Join(dir, "nvme"))
+		case strings.HasPrefix(class, "0x02"): // Network controller
+			link.Class = "Network"
+			link.Devices = readDirNames(filepath.Join(dir, "net"))
+		default:
+			continue
+		}
+		// Virtual functions and emulated devices have no link to train
+		if !fileExists(filepath.Join(dir, "max_link_speed")) {
+			continue
+		}
+
+		link.Description = descriptions[strings.TrimPrefix(address, "0000:")]
+		if link.Description == "" {
+			link.Description = descriptions[address]
+		}
+		if vendor := readSysfsValue(filepath.Join(dir, "vendor")); link.Description == "" && vendor != "" {
+			link.Description = strings.TrimPrefix(vendor, "0x") + ":" +
+				strings.TrimPrefix(readSysfsValue(filepath.Join(dir, "device")), "0x")
+		}
+		link.CurrentSpeed, link.CurrentWidth = readPCIeLinkState(dir, "current")
+		link.MaxSpeed, link.MaxWidth = readPCIeLinkState(dir, "max")
+		if link.CurrentSpeed > 0 && link.MaxSpeed > 0 && link.CurrentWidth > 0 && link.MaxWidth > 0 {
+			link.Degraded = link.CurrentSpeed < link.MaxSpeed || link.CurrentWidth < link.MaxWidth
+		}
+
+		// A slot, riser or switch port with a lower maximum explains the downgrade
+		if link.Degraded {
+			if resolved, err := filepath.EvalSymlinks(dir); err == nil {
+				upstream := filepath.Dir(resolved)
+				upSpeed, upWidth := readPCIeLinkState(upstream, "max")
+				if (upSpeed > 0 && upSpeed < link.MaxSpeed) || (upWidth > 0 && upWidth < link.MaxWidth) {
+					link.LimitedBy = fmt.Sprintf("upstream port %s supports at most %s", filepath.Base(upstream), formatPCIeLink(upSpeed, upWidth))
+				}
+			}
+		}
+		links = append(links, link)
+	}
+	sort.Slice(links, func(i, j int) bool {
+		if links[i].Class != links[j].Class {
+			return links[i].Class == "NVMe"
+		}
+		return links[i].Address < links[j].Address
+	})
+	return links
+}
+
+// readPCIeLinkState reads <prefix>_link_speed (e.g., "16.0 GT/s PCIe") and <prefix>_link_width from a PCI device directory
+func readPCIeLinkState(dir, prefix string) (float64, int) {
+	speedFields := strings.Fields(readSysfsValue(filepath.Join(dir, prefix+"_link_speed")))
+	var speed float64
+	if len(speedFields) > 0 {
+		speed, _ = strconv.ParseFloat(speedFields[0], 64)
+	}
+	width, _ := strconv.Atoi(readSysfsValue(filepath.Join(dir, prefix+"_link_width")))
+	return speed, width
+}
+
+// formatPCIeLink formats a link as "Gen4 x4 (16 GT/s)"
+func formatPCIeLink(speed float64, width int) string {
+	if speed <= 0 || width <= 0 {
+		return "unknown"
+	}
+	if gen, ok := pcieGenerations[speed]; ok {
+		return fmt.Sprintf("Gen%d x%d", gen, width)
+	}
+	return fmt.Sprintf("%g GT/s x%d", speed, width)
+}
+
+// pcieLinkName names a link by its kernel devices, falling back to the PCI address
+func pcieLinkName(link PCIeLink) string {
+	if len(link.Devices) == 0 {
+		return link.Address
+	}
+	return strings.Join(link.Devices, ", ") + " (" + link.Address + ")"
+}
+
+// pcieLinkFor finds the link of a kernel device. NVMe namespaces and partitions (nvme0n1, /dev/nvme0n1p2)
+// resolve to their controller (nvme0); network interfaces match by name.
+func pcieLinkFor(links []PCIeLink, device string) (PCIeLink, bool) {
+	name := strings.TrimPrefix(device, "/dev/")
+	if rest, ok := strings.CutPrefix(name, "nvme"); ok {
+		digits := len(rest) - len(strings.TrimLeft(rest, "0123456789"))
+		name = "nvme" + rest[:digits]
+	}
+	for _, link := range links {
+		if slices.Contains(link.Devices, name) {
+			return link, true
+		}
+	}
+	return PCIeLink{}, false
+}
+
+// pcieLinkState formats a link as "Gen4 x4 (max Gen4 x4)", colored red and marked DEGRADED when it trained low
+func pcieLinkState(link PCIeLink) string {
+	state := fmt.Sprintf("%s (max %s)", formatPCIeLink(link.CurrentSpeed, link.CurrentWidth), formatPCIeLink(link.MaxSpeed, link.MaxWidth))
+	if link.Degraded {
+		return colorRed + "DEGRADED " + state + colorReset
+	} else if link.CurrentSpeed > 0 {
+		return colorGreen + state + colorReset
+	}
+	return state
+}
+
+// pcieLinkStateHTML is pcieLinkState for the HTML report
+func pcieLinkStateHTML(link PCIeLink) string {
+	state := formatPCIeLink(link.CurrentSpeed, link.CurrentWidth) + " (max " + formatPCIeLink(link.MaxSpeed, link.MaxWidth) + ")"
+	if link.Degraded {
+		return `<span class="warning">DEGRADED ` + state + `</span>`
+	} else if link.CurrentSpeed > 0 {
+		return `<span class="highlight">` + state + `</span>`
+	}
+	return state
+}
+
+// printPCIeLinks prints the negotiated and maximum link of each device, with degraded links in red
+func printPCIeLinks(indent string, links []PCIeLink) {
+	for _, link := range links {
+		fmt.Printf("%s%-8s %-30s %s\n", indent, link.Class, pcieLinkName(link), pcieLinkState(link))
+		fmt.Printf("%s         %s\n", indent, link.Description)
+		if link.LimitedBy != "" {
+			fmt.Printf("%s         %s\n", indent, link.LimitedBy)
+		}
+	}
+}
+
+// pcieLinkWarnings returns one warning line per degraded link
+func pcieLinkWarnings(links []PCIeLink) []string {
+	var warnings []string
+	for _, link := range links {
+		if !link.Degraded {
+			continue
+		}
+		warning := fmt.Sprintf("%s %s runs at %s instead of %s", link.Class, pcieLinkName(link),
+			formatPCIeLink(link.CurrentSpeed, link.CurrentWidth), formatPCIeLink(link.MaxSpeed, link.MaxWidth))
+		if link.LimitedBy != "" {
+			warning += "; " + link.LimitedBy
+		} else {
+			warning += "; check the slot, riser and BIOS link settings"
+		}
+		warnings = append(warnings, warning)
+	}
+	return warnings
+}
+
+// pcieLinksHTML renders the PCIe links as a table, highlighting degraded links
+func pcieLinksHTML(links []PCIeLink) string {
+	if len(links) == 0 {
+		return ""
+	}
+	html := `
+    <div class="section">
+        <h2>PCIe Links</h2>`
+	for _, warning := range pcieLinkWarnings(links) {
+		html += `
+        <p class="warning">Warning: ` + htmlpkg.EscapeString(warning) + `</p>`
+	}
+	html += `
+        <table>
+            <tr><th>Type</th><th>Device</th><th>Description</th><th>Current Link</th><th>Maximum Link</th></tr>`
+	for _, link := range links {
+		current := formatPCIeLink(link.CurrentSpeed, link.CurrentWidth)
+		if link.Degraded {
+			current = `<span class="warning">` + current + `</span>`
+		} else if link.CurrentSpeed > 0 {
+			current = `<span class="highlight">` + current + `</span>`
+		}
+		html += `
+            <tr><td>` + link.Class + `</td><td>` + pcieLinkName(link) + `</td><td>` + htmlpkg.EscapeString(link.Description) + `</td><td>` +
+			current + `</td><td>` + formatPCIeLink(link.MaxSpeed, link.MaxWidth) + `</td></tr>`
+	}
+	return html + `
+        </table>
+    </div>`
+}
+
+// printNetworkAdapterLinks lists the PCIe link of each network adapter next to the network results
+func printNetworkAdapterLinks(indent string, links []PCIeLink) {
+	if !slices.ContainsFunc(links, func(link PCIeLink) bool { return link.Class == "Network" }) {
+		return
+	}
+	fmt.Printf("%sNetwork Adapters (PCIe link, current vs maximum):\n", indent)
+	for _, link := range links {
+		if link.Class == "Network" {
+			fmt.Printf("%s  %-30s %s\n", indent, pcieLinkName(link), pcieLinkState(link))
+		}
+	}
+	fmt.Println()
+}
+
+// networkAdapterLinksHTML renders the PCIe link of each network adapter as a table
+func networkAdapterLinksHTML(links []PCIeLink) string {
+	var rows string
+	for _, link := range links {
+		if link.Class == "Network" {
+			rows += `
+            <tr><td>` + pcieLinkName(link) + `</td><td>` + htmlpkg.EscapeString(link.Description) + `</td><td>` + pcieLinkStateHTML(link) + `</td></tr>`
+		}
+	}
+	if rows == "" {
+		return ""
+	}
+	return `
+        <h3>Network Adapters (PCIe)</h3>
+        <table>
+            <tr><th>Device</th><th>Description</th><th>PCIe Link</th></tr>` + rows + `
+        </table>`
+}
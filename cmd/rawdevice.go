@@ -0,0 +1,151 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// rawDeviceInfo describes a whole block device that passed the destructive-mode safety checks
+type rawDeviceInfo struct {
+	path      string // Resolved device path, e.g. /dev/nvme0n1
+	name      string // Kernel name, e.g. nvme0n1
+	serial    string // Serial number the user confirmed
+	model     string // Model from sysfs, if reported
+	sizeBytes uint64 // Capacity of the device
+}
+
+// rawDeviceSignatures are on-disk signatures that mean the device belongs to a volume manager or pool,
+// even when that pool is not currently imported or assembled
+var rawDeviceSignatures = map[string]string{
+	"LVM2_member":       "LVM physical volume",
+	"linux_raid_member": "md RAID member",
+	"zfs_member":        "ZFS pool member",
+	"crypto_LUKS":       "LUKS encrypted volume",
+	"ceph_bluestore":    "Ceph OSD",
+}
+
+// validateRawDeviceFlags checks the combination of --raw-device, --destroy-data and --confirm-serial
+// before any benchmark runs, so a typo does not surface only after the CPU and memory tests
+func validateRawDeviceFlags() error {
+	switch {
+	case rawDevice == "" && destroyData:
+		return fmt.Errorf("--destroy-data requires --raw-device")
+	case rawDevice == "" && confirmSerial != "":
+		return fmt.Errorf("--confirm-serial requires --raw-device")
+	case rawDevice == "" && fioDiscard:
+		return fmt.Errorf("--fio-discard erases the device and needs --raw-device with --destroy-data")
+	case rawDevice == "":
+		return nil
+	case !destroyData:
+		return fmt.Errorf("--raw-device overwrites %s; add --destroy-data to confirm", rawDevice)
+	case confirmSerial == "":
+		return fmt.Errorf("--raw-device requires --confirm-serial with the serial number of %s", rawDevice)
+	case len(fioTargetDirs) > 0:
+		return fmt.Errorf("--raw-device and --fio-target-dir cannot be combined")
+	case skipDisk:
+		return fmt.Errorf("--raw-device has no effect with --skip-disk")
+	}
+	_, err := validateRawDevice(rawDevice, confirmSerial)
+	return err
+}
+
+// validateRawDevice checks that devicePath is a whole, unused block device whose serial matches
+// confirmSerial. Every check must pass before any data is written to it.
+func validateRawDevice(devicePath, confirmSerial string) (rawDeviceInfo, error) {
+	var info rawDeviceInfo
+
+	if !strings.HasPrefix(devicePath, "/dev/") {
+		return info, fmt.Errorf("%s is not a /dev path", devicePath)
+	}
+	resolved, err := filepath.EvalSymlinks(devicePath)
+	if err != nil {
+		return info, fmt.Errorf("could not resolve %s: %w", devicePath, err)
+	}
+	stat, err := os.Stat(resolved)
+	if err != nil {
+		return info, fmt.Errorf("could not stat %s: %w", resolved, err)
+	}
+	if stat.Mode()&os.ModeDevice == 0 || stat.Mode()&os.ModeCharDevice != 0 {
+		return info, fmt.Errorf("%s is not a block device", resolved)
+	}
+	info.path = resolved
+	info.name = filepath.Base(resolved)
+
+	topology := loadStorageTopology()
+	device, ok := topology.devices[info.name]
+	if !ok {
+		return info, fmt.Errorf("%s is not listed in /sys/class/block", resolved)
+	}
+	if device.kind == "partition" {
+		return info, fmt.Errorf("%s is a partition; pass the whole device", resolved)
+	}
+	if device.kind != "disk" {
+		return info, fmt.Errorf("%s is a virtual device (%s); pass a physical disk", resolved, device.kind)
+	}
+
+	sysDir := filepath.Join("/sys/class/block", info.name)
+
+	// The serial must match exactly what the operator typed, so a wrong /dev name cannot wipe another drive
+	info.serial = rawDeviceSerial(info.path, sysDir)
+	if info.serial == "" {
+		return info, fmt.Errorf("could not read the serial number of %s", resolved)
+	}
+	if info.serial != strings.TrimSpace(confirmSerial) {
+		return info, fmt.Errorf("serial number of %s is '%s', which does not match --confirm-serial '%s'", resolved, info.serial, confirmSerial)
+	}
+	info.model = readSysfsValue(filepath.Join(sysDir, "device/model"))
+
+	if len(device.partitions) > 0 {
+		return info, fmt.Errorf("%s has partitions (%s); remove them first", resolved, strings.Join(device.partitions, ", "))
+	}
+	// Holders, mounts (including btrfs and ZFS members), pools and swap anywhere on the device
+	if users := topology.deviceUsers(info.name); len(users) > 0 {
+		return info, fmt.Errorf("%s is in use: %s", resolved, strings.Join(users, ", "))
+	}
+	if fsType, err := runCommand("lsblk", "-dno", "FSTYPE", info.path); err == nil {
+		fsType = strings.TrimSpace(fsType)
+		if what, ok := rawDeviceSignatures[fsType]; ok {
+			return info, fmt.Errorf("%s carries a %s signature (%s); wipe it first with wipefs", resolved, what, fsType)
+		}
+	}
+	for _, bootDisk := range findBootDisks(topology) {
+		if bootDisk == info.path {
+			return info, fmt.Errorf("%s is the boot disk", resolved)
+		}
+	}
+
+	// An exclusive open fails with EBUSY if the kernel knows of any other user (mount, dm, md, swap)
+	f, err := os.OpenFile(info.path, os.O_RDONLY|os.O_EXCL, 0)
+	if err != nil {
+		return info, fmt.Errorf("%s is busy: %w", resolved, err)
+	}
+	f.Close()
+
+	sectors, err := strconv.ParseUint(readSysfsValue(filepath.Join(sysDir, "size")), 10, 64)
+	if err != nil || sectors == 0 {
+		return info, fmt.Errorf("could not read the size of %s", resolved)
+	}
+	info.sizeBytes = sectors * 512 // sysfs always counts 512-byte sectors
+	return info, nil
+}
+
+// rawDeviceSerial reads the serial from sysfs (NVMe, virtio) or falls back to lsblk (SATA/SAS via udev)
+func rawDeviceSerial(devicePath, sysDir string) string {
+	for _, path := range []string{filepath.Join(sysDir, "device/serial"), filepath.Join(sysDir, "serial")} {
+		if serial := readSysfsValue(path); serial != "" {
+			return serial
+		}
+	}
+	if output, err := runCommand("lsblk", "-dno", "SERIAL", devicePath); err == nil {
+		return strings.TrimSpace(output)
+	}
+	return ""
+}
+
+// fioScenarioWrites reports whether a scenario writes to its target
+func fioScenarioWrites(scenario fioScenario) bool {
+	return scenario.rw != "read" && scenario.rw != "randread"
+}
@@ -6,6 +6,7 @@ import (
 	"encoding/json"
 	"encoding/xml" // For XML unmarshalling
 	"fmt"          // For io.ReadAll
+	htmlpkg "html"
 	"math"
 	"os"
 	"os/exec"
@@ -33,8 +34,63 @@ var (
 	skipNetblast  bool // Specific skip for netblast part
 	skipPublicRef bool
 
-	fioTargetDir string
-	fioTestSize  string
+	fioTargetDirs    []string
+	fioParallel      bool
+	fioBuffered      bool
+	fioSchedSweep    string
+	fioEngineSpecs   []string
+	fioTestSize      string
+	fioScenariosFile string
+
+	// For destructive whole-device testing
+	rawDevice     string
+	destroyData   bool
+	confirmSerial string
+
+	// For SSD preconditioning and steady-state detection
+	fioSteadyState          bool
+	fioSteadyStateRound     int
+	fioSteadyStateMaxRounds int
+
+	// For the queue-depth sweep
+	fioQDSweep        bool
+	fioQDSweepRW      string
+	fioQDSweepJobs    []int
+	fioQDSweepRuntime int
+
+	// For the read/write mix sweep
+	fioMixSweep        bool
+	fioMixSweepRatios  []int
+	fioMixSweepBS      string
+	fioMixSweepQD      int
+	fioMixSweepRuntime int
+
+	// For trace replay
+	fioReplay      string
+	fioReplaySpeed float64
+	fioReplayRemap string
+
+	// For the discard test on --raw-device
+	fioDiscard        bool
+	fioDiscardRuntime int
+
+	// For the metadata and small-file benchmark
+	diskMetadata        bool
+	diskMetadataFiles   int
+	diskMetadataThreads int
+
+	// For NUMA memory matrix
+	memoryNuma     bool
+	memoryNumaSize string
+
+	// For huge page impact benchmark
+	memoryHugePages     bool
+	memoryHugePagesSize string
+
+	// For memory integrity test
+	memoryTest       bool
+	memoryTestSize   string
+	memoryTestPasses int
 
 	// For auto-dependency installation
 	autoInstallDeps bool
@@ -62,8 +118,11 @@ type SystemInfo struct {
 	CPUSpeed         string
 	CPUCache         string
 	RAMTotal         string
-	RAMType          string // May be hard to get reliably without parsing dmidecode deeply
-	RAMSpeed         string // May be hard to get reliably
+	RAMType          string     // Summarized from the DIMM inventory (e.g., "DDR4 (Registered, ECC)")
+	RAMSpeed         string     // Configured speed of the slowest DIMM, with the rated speed if higher
+	DIMMs            []DIMMInfo // Per-slot inventory from dmidecode -t memory
+	MemoryECCType    string     // Error correction type of the physical memory array
+	MemoryWarnings   []string   // Channel population, mixed module and speed misconfigurations
 	MotherboardMfr   string
 	MotherboardModel string
 	OSName           string
@@ -72,6 +131,7 @@ type SystemInfo struct {
 	StorageDevices   []StorageDevice
 	NVMeControllers  []string
 	NVMeDetails      []NVMeInfo
+	PCIeLinks        []PCIeLink // Negotiated vs maximum link of NVMe controllers and NICs
 	Hostname         string
 	HyprBenchVersion string
 	TestDate         string
@@ -85,8 +145,19 @@ type SystemInfo struct {
 	StreamTriadBandwidthMBs  string // MB/s
 	PtsStreamResultFile      string // Path to the result file for reference
 	PtsEnterpriseSetupNeeded bool   // Flag if setup was needed
+	// NUMA Memory Matrix (if --memory-numa)
+	NumaResults NumaResults // Node-by-node bandwidth/latency matrix
+	// Huge Page Impact (if --memory-hugepages)
+	HugePageResults HugePageResults // 4K vs THP vs hugetlbfs comparison
+	// Memory Integrity Test (if --memory-test)
+	MemoryTestResults MemoryTestResults // Pattern test mismatches and EDAC counters
+	// Hardware errors (EDAC, MCE, kernel log) that appeared while the benchmarks ran
+	HardwareErrors HardwareErrorReport
 	// Disk I/O Benchmark Results (FIO)
-	FioResults []FioDeviceResult // Results for each tested device
+	FioEngines         []FioEngineSupport  // I/O engines requested with --fio-engines and their kernel/fio support
+	FioResults         []FioDeviceResult   // Results for each tested device
+	FioParallelResults []FioParallelResult // Scenarios run on all devices at once (if --fio-parallel)
+	DriveHealth        []DriveHealth       // Health of the tested drives before and after the disk benchmarks
 	// Network Benchmark Results
 	SpeedtestResults SpeedtestResult  // Results from local speedtest
 	Iperf3Results    []Iperf3Result   // Results from iperf3 single server tests
@@ -124,22 +195,51 @@ type FioDeviceResult struct {
 	DevicePath     string          // Path to the device or mount point tested
 	DeviceModel    string          // Model of the device (if available)
 	MountPoint     string          // Mount point where test was performed
+	AccessMode     string          // "filesystem", "network filesystem", "FUSE filesystem", "raw read-only" or "raw destructive"
+	StorageStack   string          // Layers from the filesystem down to the disks (e.g., "ext4 on vg0-data (lvm) on md0 (raid1) on ...")
+	PhysicalDisks  []string        // Disks that back the target
 	TestFileSize   string          // Size of the test file used
 	TestResults    []FioTestResult // Results for each test scenario
 	TestsCompleted bool            // Whether all tests completed successfully
+
+	Network NetworkFilesystem // Server, export and options if the target is on a network or FUSE filesystem
+
+	SteadyState FioSteadyStateResult // Preconditioning and steady-state convergence (if --fio-steady-state)
+	Metadata    MetadataResult       // Create/stat/readdir/rename/unlink rates (if --disk-metadata)
+	QDSweep     []FioQDSweepCurve    // IOPS vs p99 latency from QD1 to QD256, one curve per job count (if --fio-qd-sweep)
+	MixSweep    FioMixSweep          // Random I/O at several read/write ratios (if --fio-mix-sweep)
+	Replay      FioReplayResult      // Recorded trace against its replay (if --fio-replay)
+	Discard     FioDiscardResult     // Discard speed and writes before and after it (if --fio-discard)
+
+	QueueSettings  []BlockQueueSettings // Block-layer queue settings of every device under the target
+	SchedulerSweep FioSchedulerSweep    // One scenario under each I/O scheduler (if --fio-scheduler-sweep)
+
+	Buffered        []FioBufferedResult // Direct vs buffered vs cached results (if --fio-buffered)
+	BufferedWarning string              // Set when the test file fits in RAM, so buffered results are cache results
 }
 
 type FioTestResult struct {
-	TestName    string  // Name of the test (e.g., "4K_RandRead_QD64")
-	ReadWrite   string  // Type of test (e.g., "randread", "write", "randrw")
-	BlockSize   string  // Block size used (e.g., "4k", "1m")
-	IODepth     int     // IO depth used
-	NumJobs     int     // Number of jobs used
-	RWMixRead   int     // Read percentage for mixed tests (0-100)
-	IOPS        float64 // IO operations per second
-	BandwidthMB float64 // Bandwidth in MB/s
-	LatencyUs   float64 // Average latency in microseconds
-	LatencyUnit string  // Unit for latency (us, ms)
+	TestName     string   // Name of the test (e.g., "4K_RandRead_QD64")
+	Engine       string   // I/O engine and options (e.g., "libaio", "io_uring+fixedbufs")
+	Description  string   // Human-readable description of the scenario
+	Category     string   // Scenario category (standard, sync_write, iops_scaling, throughput, latency, custom)
+	ReadWrite    string   // Type of test (e.g., "randread", "write", "randrw")
+	BlockSize    string   // Block size used (e.g., "4k", "1m")
+	IODepth      int      // IO depth used
+	NumJobs      int      // Number of jobs used
+	RWMixRead    int      // Read percentage for mixed tests (0-100)
+	RuntimeSec   int      // Runtime limit in seconds
+	ExtraOptions []string // Additional fio options of the scenario (e.g., "--fdatasync=1")
+	IOPS         float64  // Total IO operations per second over all directions
+	BandwidthMB  float64  // Total bandwidth in MB/s over all directions
+	LatencyUs    float64  // Mean completion latency over all I/Os in microseconds
+
+	Read  FioDirectionResult // Read metrics (also set for the read side of mixed tests)
+	Write FioDirectionResult // Write metrics (also set for the write side of mixed tests)
+	Trim  FioDirectionResult // Trim metrics for trim workloads
+
+	Sync         FioLatencyStats  // fsync/fdatasync call latency (sync write tests)
+	SyncVerdicts []FioSyncVerdict // Sync latency against published thresholds (etcd, ZooKeeper)
 }
 
 // Network benchmark result structures
@@ -249,6 +349,11 @@ or can attempt to auto-install them if run with --auto-install-deps.`,
 
 		checkRoot() // Call the root check function
 
+		if err := validateRawDeviceFlags(); err != nil {
+			fmt.Fprintf(os.Stderr, "Refusing to run: %v\n", err)
+			os.Exit(1)
+		}
+
 		fmt.Println("Checking dependencies...")
 		if err := checkDependencies(autoInstallDeps); err != nil {
 			fmt.Fprintf(os.Stderr, "Dependency check failed: %v\n", err)
@@ -264,6 +369,9 @@ or can attempt to auto-install them if run with --auto-install-deps.`,
 		printSystemInformation(sysInfo)
 		fmt.Println("--- Finished System Information ---")
 
+		// Snapshot error counters so faults triggered by the benchmarks are not reported as a clean pass
+		hwErrorSnapshot := snapshotHardwareErrors()
+
 		if !skipCPU {
 			fmt.Println("\n--- Running CPU Benchmarks ---")
 			if err := runCpuBenchmarks(&sysInfo); err != nil { // Pass sysInfo
@@ -276,12 +384,31 @@ or can attempt to auto-install them if run with --auto-install-deps.`,
 			if err := runMemoryBenchmarks(&sysInfo); err != nil { // Pass sysInfo
 				fmt.Printf("Error during Memory benchmarks: %v\n", err)
 			}
+			if memoryNuma {
+				if err := runNumaMemoryBenchmarks(&sysInfo); err != nil {
+					fmt.Printf("Error during NUMA memory benchmarks: %v\n", err)
+				}
+			}
+			if memoryHugePages {
+				if err := runHugePageBenchmarks(&sysInfo); err != nil {
+					fmt.Printf("Error during huge page benchmarks: %v\n", err)
+				}
+			}
+			if memoryTest {
+				if err := runMemoryIntegrityTest(&sysInfo); err != nil {
+					fmt.Printf("Error during memory integrity test: %v\n", err)
+				}
+			}
 			fmt.Println("--- Finished Memory Benchmarks ---")
 		}
 
 		if !skipDisk {
 			fmt.Println("\n--- Running Disk I/O Benchmarks ---")
-			if err := runDiskBenchmarks(fioTargetDir, fioTestSize, fioTestProfile, &sysInfo); err != nil {
+			testSize := fioTestSize
+			if rawDevice != "" && !cmd.Flags().Changed("fio-test-size") {
+				testSize = "" // Whole device
+			}
+			if err := runDiskBenchmarks(fioTargetDirs, testSize, fioTestProfile, &sysInfo); err != nil {
 				fmt.Printf("Error during Disk I/O benchmarks: %v\n", err)
 			}
 			fmt.Println("--- Finished Disk I/O Benchmarks ---")
@@ -316,11 +443,50 @@ or can attempt to auto-install them if run with --auto-install-deps.`,
 			fmt.Println("--- Finished Public Reference Benchmarks ---")
 		}
 
+		sysInfo.HardwareErrors = collectHardwareErrors(hwErrorSnapshot)
+
 		// Print summary of key metrics
 		fmt.Println("\n========================================")
 		fmt.Println(colorBold + "HyprBench Summary" + colorReset)
 		fmt.Println("----------------------------------------")
 
+		// Hardware errors come first so they cannot be missed
+		hw := sysInfo.HardwareErrors
+		if hw.ErrorsDetected {
+			fmt.Printf("%s%sHARDWARE ERRORS DETECTED DURING THIS RUN - results are not a clean pass%s\n", colorBold, colorRed, colorReset)
+			if hw.NewCorrected > 0 || hw.NewUncorrected > 0 {
+				fmt.Printf("%s          EDAC: +%d corrected, +%d uncorrected%s\n", colorRed, hw.NewCorrected, hw.NewUncorrected, colorReset)
+			}
+			for _, delta := range hw.EDACDIMMDeltas {
+				fmt.Printf("%s                %s%s\n", colorRed, delta, colorReset)
+			}
+			if hw.NewMCEs > 0 {
+				fmt.Printf("%s          Machine checks: +%d%s\n", colorRed, hw.NewMCEs, colorReset)
+			}
+			if len(hw.KernelLogErrors) > 0 {
+				fmt.Printf("%s          Kernel log: %d hardware error message(s), e.g.:%s\n", colorRed, len(hw.KernelLogErrors), colorReset)
+				for _, line := range hw.KernelLogErrors[:min(5, len(hw.KernelLogErrors))] {
+					fmt.Printf("%s            %s%s\n", colorRed, line, colorReset)
+				}
+			}
+		} else {
+			checked := []string{}
+			if hw.EDACBefore.Available {
+				checked = append(checked, "EDAC")
+			}
+			if hw.MCEAvailable {
+				checked = append(checked, "MCE")
+			}
+			if hw.KernelLogAvailable {
+				checked = append(checked, "kernel log")
+			}
+			if len(checked) > 0 {
+				fmt.Printf("Hardware: %sno new errors%s (%s checked)\n", colorGreen, colorReset, strings.Join(checked, ", "))
+			} else {
+				fmt.Printf("Hardware: %serror sources unavailable (no EDAC, MCE or kernel log access)%s\n", colorYellow, colorReset)
+			}
+		}
+
 		// CPU Summary
 		if sysInfo.SysbenchSingleThreadScore != "" || sysInfo.SysbenchMultiThreadScore != "" {
 			fmt.Printf("CPU:      %s (%s cores, %s threads)\n", sysInfo.CPUModel, sysInfo.CPUCores, sysInfo.CPUThreads)
@@ -342,6 +508,34 @@ or can attempt to auto-install them if run with --auto-install-deps.`,
 					colorGreen, sysInfo.StreamTriadBandwidthMBs, colorReset)
 			}
 		}
+		for _, warning := range sysInfo.MemoryWarnings {
+			fmt.Printf("          %s%s%s\n", colorYellow, warning, colorReset)
+		}
+		if sysInfo.NumaResults.Mode != "" {
+			localBW, remoteBW := numaLocalRemote(sysInfo.NumaResults.BandwidthMBs)
+			localLat, remoteLat := numaLocalRemote(sysInfo.NumaResults.LatencyNs)
+			if localBW >= 0 {
+				fmt.Printf("          NUMA Local:    %s%.0f MB/s, %.1f ns%s\n", colorGreen, localBW, localLat, colorReset)
+			}
+			if remoteBW >= 0 {
+				fmt.Printf("          NUMA Remote:   %s%.0f MB/s, %.1f ns%s\n", colorYellow, remoteBW, remoteLat, colorReset)
+			}
+		}
+		for i, mode := range sysInfo.HugePageResults.Modes {
+			if i > 0 && mode.Available {
+				fmt.Printf("          %-14s %sx%.2f latency, x%.2f bandwidth vs 4K%s\n",
+					mode.Mode+":", colorGreen, mode.LatencySpeedup, mode.BandwidthSpeedup, colorReset)
+			}
+		}
+		if mt := sysInfo.MemoryTestResults; mt.PassesCompleted > 0 {
+			if mt.Passed {
+				fmt.Printf("          Integrity:     %sPASS%s (%s, %d passes)\n", colorGreen, colorReset,
+					humanReadableBytes(mt.TestSizeBytes), mt.PassesCompleted)
+			} else {
+				fmt.Printf("          Integrity:     %sFAIL: %d mismatches, +%d CE / +%d UE EDAC errors%s\n", colorRed,
+					mt.MismatchCount, mt.CorrectedErrors, mt.UncorrectedErrors, colorReset)
+			}
+		}
 
 		// Disk Summary
 		if len(sysInfo.FioResults) > 0 {
@@ -355,8 +549,8 @@ or can attempt to auto-install them if run with --auto-install-deps.`,
 				// Find 4K Random Read result
 				for _, test := range device.TestResults {
 					if strings.Contains(test.TestName, "4K_RandRead") {
-						fmt.Printf("          4K Random Read:  %s%.0f IOPS, %.2f MB/s%s\n",
-							colorGreen, test.IOPS, test.BandwidthMB, colorReset)
+						fmt.Printf("          4K Random Read:  %s%.0f IOPS, %.2f MB/s%s%s\n",
+							colorGreen, test.IOPS, test.BandwidthMB, colorReset, nativeDiskNote(test))
 						break
 					}
 				}
@@ -364,11 +558,92 @@ or can attempt to auto-install them if run with --auto-install-deps.`,
 				// Find 1M Sequential Read result
 				for _, test := range device.TestResults {
 					if strings.Contains(test.TestName, "1M_SeqRead") {
-						fmt.Printf("          1M Sequential Read: %s%.2f MB/s%s\n",
-							colorGreen, test.BandwidthMB, colorReset)
+						fmt.Printf("          1M Sequential Read: %s%.2f MB/s%s%s\n",
+							colorGreen, test.BandwidthMB, colorReset, nativeDiskNote(test))
+						break
+					}
+				}
+
+				if discard := device.Discard; discard.Latency.Samples > 0 {
+					fmt.Printf("          Discard: %s%.2f GiB/s, p99 %s per %s request%s\n", colorGreen, discard.ThroughputGBs,
+						formatLatencyNs(discard.Latency.P99Ns), humanReadableBytes(discard.ChunkBytes), colorReset)
+				}
+
+				for _, curve := range device.QDSweep {
+					if curve.KneeIndex >= 0 {
+						knee := curve.Points[curve.KneeIndex]
+						fmt.Printf("          QD Knee (%s, %d job(s)): %sQD %d, %.0f IOPS, p99 %s%s\n", curve.Workload, curve.NumJobs,
+							colorGreen, knee.IODepth, knee.IOPS, formatLatencyNs(knee.P99LatencyNs), colorReset)
+					}
+				}
+
+				if ss := device.SteadyState; len(ss.Rounds) > 0 {
+					state := "steady state"
+					if !ss.SteadyState {
+						state = "NOT steady"
+					}
+					fmt.Printf("          Steady 4K Write: %s%.0f IOPS, p99 %s (%s after %d rounds)%s\n",
+						colorGreen, ss.SteadyIOPS, formatLatencyNs(ss.SteadyP99LatencyNs), state, len(ss.Rounds), colorReset)
+				}
+
+				// Sync write results against the etcd/ZooKeeper thresholds
+				for _, test := range device.TestResults {
+					if test.Sync.Samples == 0 {
+						continue
+					}
+					color, verdicts := colorGreen, make([]string, 0, len(test.SyncVerdicts))
+					for _, verdict := range test.SyncVerdicts {
+						status := "PASS"
+						if !verdict.Pass {
+							color, status = colorRed, "FAIL"
+						}
+						verdicts = append(verdicts, verdict.Name+" "+status)
+					}
+					fmt.Printf("          %-16s %sfdatasync p99 %s (%s)%s\n", test.TestName+":", color,
+						formatLatencyNs(test.Sync.P99Ns), strings.Join(verdicts, ", "), colorReset)
+				}
+
+				// Find the mixed result; read latency under concurrent writes is what gets tuned
+				for _, test := range device.TestResults {
+					if strings.Contains(test.TestName, "4K_Mixed") && test.Read.IOPS > 0 && test.Write.IOPS > 0 {
+						fmt.Printf("          4K Mixed R%dW%d:   %sread %.0f IOPS (p99 %s), write %.0f IOPS (p99 %s)%s\n",
+							test.RWMixRead, 100-test.RWMixRead, colorGreen,
+							test.Read.IOPS, formatLatencyNs(test.Read.Latency.Completion.P99Ns),
+							test.Write.IOPS, formatLatencyNs(test.Write.Latency.Completion.P99Ns), colorReset)
 						break
 					}
 				}
+
+				// Metadata rates of the widest run
+				if variants := device.Metadata.Variants; len(variants) > 0 {
+					widest := variants[len(variants)-1]
+					fmt.Printf("          Metadata (%d thr): %screate %.0f/s, stat %.0f/s, unlink %.0f/s%s\n", widest.Threads, colorGreen,
+						metadataOpsPerSec(widest, "create"), metadataOpsPerSec(widest, "stat"), metadataOpsPerSec(widest, "unlink"), colorReset)
+				}
+			}
+		}
+
+		// Parallel multi-device summary: aggregate of the headline tests
+		for _, result := range sysInfo.FioParallelResults {
+			if strings.Contains(result.TestName, "4K_RandRead") || strings.Contains(result.TestName, "1M_SeqRead") {
+				scaling := ""
+				if result.ScalingPercent > 0 {
+					scaling = fmt.Sprintf(" (%.1f%% scaling)", result.ScalingPercent)
+				}
+				fmt.Printf("Disk x%d:  %s: %s%.0f IOPS, %.2f MB/s%s%s\n", len(result.Devices), result.TestName,
+					colorGreen, result.IOPS, result.BandwidthMB, scaling, colorReset)
+			}
+		}
+
+		// PCIe links that trained below their maximum explain otherwise puzzling disk and network results
+		for _, warning := range pcieLinkWarnings(sysInfo.PCIeLinks) {
+			fmt.Printf("PCIe:     %s%s%s\n", colorRed, warning, colorReset)
+		}
+
+		// Drive health: only what the benchmarks changed for the worse
+		for _, health := range sysInfo.DriveHealth {
+			if concerns := driveHealthConcerns(health); len(concerns) > 0 {
+				fmt.Printf("Health:   %s: %s%s%s\n", health.DevicePath, colorYellow, strings.Join(concerns, ", "), colorReset)
 			}
 		}
 
@@ -459,9 +734,50 @@ func init() {
 	rootCmd.Flags().BoolVar(&skipNetblast, "skip-netblast", false, "Skip only the hyprbench-netblast multi-server tests (if --skip-network is not set)")
 	rootCmd.Flags().BoolVar(&skipPublicRef, "skip-public-ref", false, "Skip public reference benchmarks (e.g., UnixBench via PTS)")
 
-	rootCmd.Flags().StringVar(&fioTargetDir, "fio-target-dir", "", "Specify a single directory/device for FIO tests (overrides NVMe auto-detection)")
+	rootCmd.Flags().StringSliceVar(&fioTargetDirs, "fio-target-dir", nil, "Directories (mount points) for FIO tests, comma-separated or repeated (overrides NVMe auto-detection)")
+	rootCmd.Flags().StringSliceVar(&fioEngineSpecs, "fio-engines", []string{"libaio"}, "FIO I/O engines to run every scenario with, e.g. libaio,io_uring,psync. io_uring options are appended with '+': io_uring+fixedbufs+sqthread_poll+hipri")
+	rootCmd.Flags().BoolVar(&fioBuffered, "fio-buffered", false, "Also run the sequential and random read/write tests with buffered I/O (caches dropped first) and measure cached re-reads, shown next to the O_DIRECT results")
+	rootCmd.Flags().StringVar(&fioSchedSweep, "fio-scheduler-sweep", "", "FIO scenario (e.g., 4K_RandRead_QD64) to rerun under each available I/O scheduler (none, mq-deadline, kyber, bfq) on every target; the original schedulers are restored afterwards")
+	rootCmd.Flags().BoolVar(&fioParallel, "fio-parallel", false, "After the per-device tests, run each scenario on all targets at the same time and report aggregate and per-device results")
 	rootCmd.Flags().StringVar(&fioTestSize, "fio-test-size", "1G", "Override FIO test file size (e.g., 1G, 8G, 16G). Default is 1G for faster runs.")
-	rootCmd.Flags().StringVar(&fioTestProfile, "fio-profile", "standard", "FIO test profile: 'standard', 'quick', 'thorough', 'iops', 'throughput', 'latency', or 'all'")
+	rootCmd.Flags().StringVar(&fioTestProfile, "fio-profile", "standard", "FIO test profile: 'standard', 'quick', 'thorough', 'iops', 'throughput', 'latency', 'sync' (fdatasync WAL tests only), 'all', or 'custom' (only --fio-scenarios)")
+	rootCmd.Flags().BoolVar(&fioSteadyState, "fio-steady-state", false, "Precondition each target (sequential fills, then random writes) and run rounds until 4K random write IOPS reach SNIA-style steady state. Use a test size close to the drive capacity")
+	rootCmd.Flags().IntVar(&fioSteadyStateRound, "fio-steady-state-round", 60, "Duration of each steady-state round in seconds")
+	rootCmd.Flags().IntVar(&fioSteadyStateMaxRounds, "fio-steady-state-max-rounds", 25, "Maximum number of steady-state rounds before giving up")
+	rootCmd.Flags().BoolVar(&fioQDSweep, "fio-qd-sweep", false, "Run 4K I/O at queue depths 1 to 256 on each target, record the IOPS vs p99 latency curve and find its knee")
+	rootCmd.Flags().StringVar(&fioQDSweepRW, "fio-qd-sweep-rw", "randread", "Access pattern of the queue-depth sweep: randread, randwrite or randrw")
+	rootCmd.Flags().IntSliceVar(&fioQDSweepJobs, "fio-qd-sweep-jobs", []int{1, 4}, "Job counts of the queue-depth sweep, one curve each")
+	rootCmd.Flags().IntVar(&fioQDSweepRuntime, "fio-qd-sweep-runtime", 20, "Duration of each queue-depth sweep point in seconds")
+	rootCmd.Flags().BoolVar(&fioMixSweep, "fio-mix-sweep", false, "Run random I/O at several read/write ratios on each target and report read and write results for each ratio")
+	rootCmd.Flags().IntSliceVar(&fioMixSweepRatios, "fio-mix-sweep-ratios", []int{100, 90, 70, 50, 30, 0}, "Read percentages of the mix sweep (100 = reads only, 0 = writes only)")
+	rootCmd.Flags().StringVar(&fioMixSweepBS, "fio-mix-sweep-bs", "4k", "Block size of the mix sweep")
+	rootCmd.Flags().IntVar(&fioMixSweepQD, "fio-mix-sweep-qd", 64, fmt.Sprintf("Queue depth per job of the mix sweep (%d jobs)", fioMixSweepJobs))
+	rootCmd.Flags().IntVar(&fioMixSweepRuntime, "fio-mix-sweep-runtime", 30, "Duration of each mix sweep ratio in seconds")
+	rootCmd.Flags().StringVar(&fioReplay, "fio-replay", "", "Replay a recorded I/O trace (binary blktrace or fio iolog) on each target and compare the achieved latency with the trace")
+	rootCmd.Flags().Float64Var(&fioReplaySpeed, "fio-replay-speed", 1, "Replay speed relative to the trace timing (2 = twice as fast, 0 = as fast as possible)")
+	rootCmd.Flags().BoolVar(&fioDiscard, "fio-discard", false, "On the --raw-device target: measure sequential and random writes on the refilled device, then again right after discarding it all (like blkdiscard), timing each discard request")
+	rootCmd.Flags().IntVar(&fioDiscardRuntime, "fio-discard-runtime", 60, "Duration of the random write workload before and after the discard in seconds")
+	rootCmd.Flags().StringVar(&fioReplayRemap, "fio-replay-remap", "wrap", "How trace offsets beyond the target are remapped: wrap (modulo the target size) or scale (trace span scaled onto the target)")
+	rootCmd.Flags().BoolVar(&diskMetadata, "disk-metadata", false, "Also measure create, stat, readdir, rename and unlink rates for many small files on each filesystem target, single- and multi-threaded")
+	rootCmd.Flags().IntVar(&diskMetadataFiles, "disk-metadata-files", 20000, "Number of files in the metadata benchmark tree")
+	rootCmd.Flags().IntVar(&diskMetadataThreads, "disk-metadata-threads", 0, "Threads for the multi-threaded metadata run (0 = number of CPUs, up to 16)")
+	rootCmd.Flags().StringVar(&rawDevice, "raw-device", "", "Run FIO write tests on a whole raw block device (e.g., /dev/nvme1n1). ALL DATA ON IT IS DESTROYED; requires --destroy-data and --confirm-serial")
+	rootCmd.Flags().BoolVar(&destroyData, "destroy-data", false, "Confirm that the --raw-device may be overwritten")
+	rootCmd.Flags().StringVar(&confirmSerial, "confirm-serial", "", "Serial number of the --raw-device, which must match before any data is written")
+	rootCmd.Flags().StringVar(&fioScenariosFile, "fio-scenarios", "", "YAML file with additional FIO scenarios (name, rw, bs, iodepth, numjobs, rwmixread, runtime, options), run with every profile")
+
+	// NUMA memory matrix
+	rootCmd.Flags().BoolVar(&memoryNuma, "memory-numa", false, "Measure a node-by-node memory bandwidth/latency matrix with workers and memory pinned to each NUMA node")
+	rootCmd.Flags().StringVar(&memoryNumaSize, "memory-numa-size", "512M", "Buffer size for each NUMA matrix cell (e.g., 256M, 1G)")
+
+	// Huge page impact benchmark
+	rootCmd.Flags().BoolVar(&memoryHugePages, "memory-hugepages", false, "Compare memory bandwidth and random-access latency with 4K pages, THP (madvise) and pre-reserved hugetlb pages")
+	rootCmd.Flags().StringVar(&memoryHugePagesSize, "memory-hugepages-size", "1G", "Buffer size for each page mode (should be well above the TLB reach, e.g., 1G)")
+
+	// Memory integrity test
+	rootCmd.Flags().BoolVar(&memoryTest, "memory-test", false, "Run a memtester-style integrity test (walking ones, checkerboard, random, address-in-address) and check EDAC counters")
+	rootCmd.Flags().StringVar(&memoryTestSize, "memory-test-size", "50%", "Amount of memory to test, as a percentage of available memory or an absolute size (e.g., 50%, 16G)")
+	rootCmd.Flags().IntVar(&memoryTestPasses, "memory-test-passes", 3, "Number of passes over all patterns for the memory integrity test")
 
 	// Export options
 	rootCmd.Flags().StringVar(&exportJSON, "export-json", "", "Export results to JSON file (e.g., ./hyprbench-results.json)")
@@ -577,9 +893,7 @@ func gatherSystemInformation(sysInfo *SystemInfo) error {
 	} else {
 		sysInfo.RAMTotal = parseFreeForTotal(ramOutput, "Mem:") // Use ramOutput
 	}
-	// RAM Type and Speed often require dmidecode, which is more complex. Placeholder for now.
-	sysInfo.RAMType = "N/A (requires dmidecode)"
-	sysInfo.RAMSpeed = "N/A (requires dmidecode)"
+	gatherDIMMInventory(sysInfo)
 
 	fmt.Println("  Gathering OS Information...")
 	kernelOutput, err := runCommand("uname", "-r")
@@ -682,6 +996,13 @@ func gatherSystemInformation(sysInfo *SystemInfo) error {
 		sysInfo.NVMeControllers = parseLspciForNVMe(lspciOutput)
 	}
 
+	// --- PCIe Links (sysfs) ---
+	fmt.Println("  Checking PCIe link speed and width of NVMe controllers and NICs...")
+	sysInfo.PCIeLinks = readPCIeLinks(lspciOutput)
+	for _, warning := range pcieLinkWarnings(sysInfo.PCIeLinks) {
+		fmt.Printf("    %sWARNING: %s%s\n", colorRed, warning, colorReset)
+	}
+
 	// --- Detailed NVMe Info ---
 	fmt.Println("  Gathering Detailed NVMe Device Information (lsblk)...")
 	lsblkNvmeOutput, err := runCommand("lsblk", "-d", "-p", "-no", "NAME,MODEL,SIZE")
@@ -691,8 +1012,6 @@ func gatherSystemInformation(sysInfo *SystemInfo) error {
 		sysInfo.NVMeDetails = parseLsblkForNVMeDetails(lsblkNvmeOutput)
 	}
 
-	// TODO: More detailed RAM Type/Speed (dmidecode -t memory)
-
 	return nil
 }
 
@@ -714,7 +1033,35 @@ func printSystemInformation(sysInfo SystemInfo) {
 
 	fmt.Printf(" RAM Total:         %s\n", sysInfo.RAMTotal)
 	fmt.Printf(" RAM Type:          %s\n", sysInfo.RAMType)
-	fmt.Printf(" RAM Speed:         %s\n\n", sysInfo.RAMSpeed)
+	fmt.Printf(" RAM Speed:         %s\n", sysInfo.RAMSpeed)
+	if sysInfo.MemoryECCType != "" {
+		fmt.Printf(" RAM ECC:           %s\n", sysInfo.MemoryECCType)
+	}
+	fmt.Println()
+
+	if len(sysInfo.DIMMs) > 0 {
+		fmt.Println(" Memory Modules (dmidecode):")
+		fmt.Printf("  %-16s %-10s %-6s %-18s %-4s %-4s %-14s %-20s\n", "Slot", "Size", "Type", "Speed (cfg/rated)", "Rank", "ECC", "Manufacturer", "Part Number")
+		for _, dimm := range sysInfo.DIMMs {
+			if !dimm.Populated {
+				fmt.Printf("  %-16s %-10s\n", dimm.Locator, "[empty]")
+				continue
+			}
+			ecc := "no"
+			if dimm.ECC {
+				ecc = "yes"
+			}
+			fmt.Printf("  %-16s %-10s %-6s %-18s %-4d %-4s %-14s %-20s\n", dimm.Locator, dimm.Size, dimm.Type,
+				fmt.Sprintf("%d/%d MT/s", dimm.ConfiguredSpeedMTs, dimm.RatedSpeedMTs), dimm.Rank, ecc, dimm.Manufacturer, dimm.PartNumber)
+		}
+		fmt.Println()
+	}
+	for _, warning := range sysInfo.MemoryWarnings {
+		fmt.Printf(" %sWarning: %s%s\n", colorYellow, warning, colorReset)
+	}
+	if len(sysInfo.MemoryWarnings) > 0 {
+		fmt.Println()
+	}
 
 	fmt.Printf(" Motherboard:       %s %s\n", strings.TrimSpace(sysInfo.MotherboardMfr), strings.TrimSpace(sysInfo.MotherboardModel)) // Removed extra \n
 
@@ -753,6 +1100,63 @@ func printSystemInformation(sysInfo SystemInfo) {
 			fmt.Printf("  Raw results XML: %s\n", sysInfo.PtsStreamResultFile)
 		}
 	}
+	if sysInfo.NumaResults.Mode != "" {
+		numa := sysInfo.NumaResults
+		fmt.Printf("\nNUMA Memory Matrix (mode: %s, placement: %s, %s per cell):\n",
+			numa.Mode, numa.Method, humanReadableBytes(numa.TestSizeBytes))
+		printNumaMatrix("Bandwidth (MB/s)", numa.Nodes, numa.BandwidthMBs, "%.0f")
+		printNumaMatrix("Latency (ns)", numa.Nodes, numa.LatencyNs, "%.1f")
+		if numa.ErrorMessage != "" {
+			fmt.Printf("  Error: %s\n", numa.ErrorMessage)
+		}
+	}
+	if len(sysInfo.HugePageResults.Modes) > 0 {
+		hp := sysInfo.HugePageResults
+		fmt.Printf("\nHuge Page Impact (THP enabled: %s, defrag: %s, %s per mode):\n",
+			hp.THPEnabled, hp.THPDefrag, humanReadableBytes(hp.TestSizeBytes))
+		fmt.Printf("  %-14s | %-16s | %-9s | %-12s | %-9s | %-12s\n", "Mode", "Bandwidth (MB/s)", "Speedup", "Latency (ns)", "Speedup", "Huge-backed")
+		for _, mode := range hp.Modes {
+			if !mode.Available {
+				fmt.Printf("  %-14s | skipped: %s\n", mode.Mode, mode.SkipReason)
+				continue
+			}
+			fmt.Printf("  %-14s | %-16.2f | x%-8.2f | %-12.1f | x%-8.2f | %-12s\n", mode.Mode, mode.BandwidthMBs, mode.BandwidthSpeedup,
+				mode.LatencyNs, mode.LatencySpeedup, humanReadableBytes(mode.HugeBackedBytes))
+		}
+		if hp.ErrorMessage != "" {
+			fmt.Printf("  Error: %s\n", hp.ErrorMessage)
+		}
+	}
+	if mt := sysInfo.MemoryTestResults; mt.Passes > 0 {
+		fmt.Printf("\nMemory Integrity Test (%s, %d/%d passes, %.0fs):\n",
+			humanReadableBytes(mt.TestSizeBytes), mt.PassesCompleted, mt.Passes, mt.DurationSeconds)
+		fmt.Printf("  Patterns:            %s\n", strings.Join(mt.Patterns, ", "))
+		if mt.Passed {
+			fmt.Printf("  Result:              %sPASS%s\n", colorGreen, colorReset)
+		} else if mt.PassesCompleted > 0 {
+			fmt.Printf("  Result:              %sFAIL%s\n", colorRed, colorReset)
+		}
+		fmt.Printf("  Mismatches:          %d\n", mt.MismatchCount)
+		if mt.EDACBefore.Available {
+			fmt.Printf("  EDAC Errors:         +%d corrected, +%d uncorrected (totals now %d / %d)\n",
+				mt.CorrectedErrors, mt.UncorrectedErrors, mt.EDACAfter.CorrectedTotal, mt.EDACAfter.UncorrectedTotal)
+		} else {
+			fmt.Println("  EDAC Errors:         N/A (no EDAC driver loaded)")
+		}
+		for _, delta := range mt.EDACDIMMDeltas {
+			fmt.Printf("    %s%s%s\n", colorRed, delta, colorReset)
+		}
+		for i, mismatch := range mt.Mismatches {
+			if i == maxPrintedMismatches {
+				fmt.Printf("    ... %d more (see JSON export for up to %d)\n", mt.MismatchCount-uint64(i), maxStoredMismatches)
+				break
+			}
+			fmt.Printf("    %s%s%s\n", colorRed, formatMemoryMismatch(mismatch), colorReset)
+		}
+		if mt.ErrorMessage != "" {
+			fmt.Printf("  Error: %s\n", mt.ErrorMessage)
+		}
+	}
 	fmt.Println() // Add a newline before storage devices or the end line
 
 	if len(sysInfo.StorageDevices) > 0 {
@@ -790,10 +1194,20 @@ func printSystemInformation(sysInfo SystemInfo) {
 		fmt.Println()
 	}
 
+	if len(sysInfo.PCIeLinks) > 0 {
+		fmt.Println("PCIe Links (current vs maximum):")
+		printPCIeLinks("  ", sysInfo.PCIeLinks)
+		fmt.Println()
+	}
+
 	if len(sysInfo.NVMeDetails) > 0 {
 		fmt.Println("NVMe Device Details (lsblk):")
 		for _, nvme := range sysInfo.NVMeDetails {
-			fmt.Printf("  Device: %s, Model: %s, Size: %s\n", nvme.DevicePath, nvme.Model, nvme.Size)
+			fmt.Printf("  Device: %s, Model: %s, Size: %s", nvme.DevicePath, nvme.Model, nvme.Size)
+			if link, ok := pcieLinkFor(sysInfo.PCIeLinks, nvme.DevicePath); ok {
+				fmt.Printf(", PCIe: %s", pcieLinkState(link))
+			}
+			fmt.Println()
 			// TODO: Print partition info if gathered
 		}
 		fmt.Println()
@@ -809,6 +1223,18 @@ func printSystemInformation(sysInfo SystemInfo) {
 				fmt.Printf("  Model: %s\n", device.DeviceModel)
 			}
 			fmt.Printf("  Mount Point: %s\n", device.MountPoint)
+			if device.AccessMode != "" {
+				fmt.Printf("  Access Mode: %s\n", device.AccessMode)
+			}
+			if device.StorageStack != "" {
+				fmt.Printf("  Storage Stack: %s\n", device.StorageStack)
+			}
+			for _, disk := range device.PhysicalDisks {
+				if link, ok := pcieLinkFor(sysInfo.PCIeLinks, disk); ok {
+					fmt.Printf("  PCIe Link (%s): %s\n", disk, pcieLinkState(link))
+				}
+			}
+			printNetworkFilesystem("  ", device.Network)
 			fmt.Printf("  Test File Size: %s\n", device.TestFileSize)
 
 			if !device.TestsCompleted {
@@ -816,13 +1242,13 @@ func printSystemInformation(sysInfo SystemInfo) {
 			}
 
 			// Print table header
-			fmt.Println("  ---------------------------------------------------------------------------------")
-			fmt.Printf("  %-32s | %-10s | %-18s | %-18s\n", "Test", "IOPS", "Bandwidth (MB/s)", "Avg Latency")
-			fmt.Println("  ---------------------------------------------------------------------------------")
+			fmt.Println("  ------------------------------------------------------------------------------------------------")
+			fmt.Printf("  %-32s | %-10s | %-18s | %-12s | %-12s\n", "Test", "IOPS", "Bandwidth (MB/s)", "Avg Latency", "p99 Latency")
+			fmt.Println("  ------------------------------------------------------------------------------------------------")
 
 			// Print each test result
 			for _, test := range device.TestResults {
-				var iopsStr, bwStr, latencyStr string
+				var iopsStr, bwStr string
 
 				if test.IOPS < 0 {
 					iopsStr = "FAIL"
@@ -836,23 +1262,65 @@ func printSystemInformation(sysInfo SystemInfo) {
 					bwStr = fmt.Sprintf("%.2f", test.BandwidthMB)
 				}
 
-				if test.LatencyUs < 0 {
-					latencyStr = "FAIL"
-				} else if test.LatencyUnit == "ms" {
-					latencyStr = fmt.Sprintf("%.2f ms", test.LatencyUs)
-				} else {
-					latencyStr = fmt.Sprintf("%.2f us", test.LatencyUs)
+				fmt.Printf("  %-32s | %-10s | %-18s | %-12s | %-12s\n",
+					fioTestLabel(test), iopsStr, bwStr, formatLatencyNs(test.LatencyUs*1000), formatLatencyNs(fioCompletionP99Ns(test)))
+				printFioDirectionRows("  ", test)
+				printFioSyncLatency("  ", test)
+			}
+
+			fmt.Println("  ------------------------------------------------------------------------------------------------")
+			printFioEngineComparison("  ", device, sysInfo.FioEngines)
+			printFioBuffered("  ", device)
+			printBlockQueue("  ", device)
+			printFioQDSweep("  ", device.QDSweep)
+			printFioMixSweep("  ", device.MixSweep)
+			printFioReplay("  ", device.Replay)
+			printFioDiscard("  ", device.Discard)
+			printMetadataResult("  ", device.Metadata)
+
+			if ss := device.SteadyState; len(ss.Rounds) > 0 || ss.ErrorMessage != "" {
+				fmt.Printf("  Steady State (%s, engine=%s, %d x %ds rounds):\n", ss.Workload, ss.Engine, len(ss.Rounds), ss.RoundSeconds)
+				if ss.SteadyState {
+					fmt.Printf("    %sReached at round %d%s: %.0f IOPS, %.2f MB/s, p99 %s (range %.1f%%, slope %.1f%%)\n",
+						colorGreen, ss.SteadyStateRound, colorReset, ss.SteadyIOPS, ss.SteadyBandwidthMB,
+						formatLatencyNs(ss.SteadyP99LatencyNs), ss.WindowRangePercent, ss.WindowSlopePercent)
+				} else if len(ss.Rounds) > 0 {
+					fmt.Printf("    %sNot reached%s: last-window average %.0f IOPS (range %.1f%%, slope %.1f%%)\n",
+						colorYellow, colorReset, ss.SteadyIOPS, ss.WindowRangePercent, ss.WindowSlopePercent)
+				}
+				if ss.ErrorMessage != "" {
+					fmt.Printf("    Error: %s\n", ss.ErrorMessage)
 				}
-
-				fmt.Printf("  %-32s | %-10s | %-18s | %-18s\n",
-					test.TestName, iopsStr, bwStr, latencyStr)
 			}
-
-			fmt.Println("  ---------------------------------------------------------------------------------")
 			fmt.Println() // Add a newline between devices
 		}
 	}
 
+	// Display drive health before and after the disk benchmarks
+	if len(sysInfo.DriveHealth) > 0 {
+		fmt.Println("Drive Health (before -> after disk benchmarks):")
+		printDriveHealth("  ", sysInfo.DriveHealth)
+		fmt.Println()
+	}
+
+	// Display parallel multi-device results
+	if len(sysInfo.FioParallelResults) > 0 {
+		fmt.Println("Parallel Multi-Device FIO Results:")
+		for _, result := range sysInfo.FioParallelResults {
+			scaling := "N/A"
+			if result.ScalingPercent > 0 {
+				scaling = fmt.Sprintf("%.1f%% of solo sum", result.ScalingPercent)
+			}
+			fmt.Printf("  %s (%d devices): %.0f IOPS, %.2f MB/s, %s\n",
+				result.TestName, len(result.Devices), result.IOPS, result.BandwidthMB, scaling)
+			printFioParallelDevices("    ", result)
+			if result.ErrorMessage != "" {
+				fmt.Printf("    Error: %s\n", result.ErrorMessage)
+			}
+		}
+		fmt.Println()
+	}
+
 	// Display Network Benchmark Results
 	// 1. Local Speedtest Results
 	if sysInfo.SpeedtestResults.ToolUsed != "None" && sysInfo.SpeedtestResults.ToolUsed != "" {
@@ -935,6 +1403,11 @@ func printSystemInformation(sysInfo SystemInfo) {
 		fmt.Println()
 	}
 
+	// 4. PCIe links of the network adapters, which cap what the tests above can reach
+	if sysInfo.SpeedtestResults.TestCompleted || len(sysInfo.Iperf3Results) > 0 || len(sysInfo.NetblastResults) > 0 {
+		printNetworkAdapterLinks("  ", sysInfo.PCIeLinks)
+	}
+
 	// Display Stress Benchmark Results
 	if sysInfo.StressResults.CPUBogoOps > 0 || sysInfo.StressResults.MatrixBogoOps > 0 || sysInfo.StressResults.VMBogoOps > 0 {
 		fmt.Println("System Stress Benchmark Results (stress-ng):")
@@ -1026,6 +1499,26 @@ func printSystemInformation(sysInfo SystemInfo) {
 	fmt.Println("----------------------------------------")
 }
 
+// printNumaMatrix prints one CPU-node x memory-node table
+func printNumaMatrix(title string, nodes []int, matrix [][]float64, format string) {
+	fmt.Printf("  %-18s", title)
+	for _, node := range nodes {
+		fmt.Printf(" | %-10s", fmt.Sprintf("mem%d", node))
+	}
+	fmt.Println()
+	for i, node := range nodes {
+		fmt.Printf("  %-18s", fmt.Sprintf("cpu%d", node))
+		for j := range nodes {
+			value := "N/A"
+			if i < len(matrix) && j < len(matrix[i]) && matrix[i][j] >= 0 {
+				value = fmt.Sprintf(format, matrix[i][j])
+			}
+			fmt.Printf(" | %-10s", value)
+		}
+		fmt.Println()
+	}
+}
+
 func runCpuBenchmarks(sysInfo *SystemInfo) error { // Add *SystemInfo parameter
 	fmt.Println("  Running sysbench CPU benchmarks...")
 	var err error
@@ -1328,87 +1821,204 @@ func fileExists(filename string) bool {
 	return !info.IsDir()
 }
 
-func runDiskBenchmarks(targetDir, testSize, testProfile string, sysInfo *SystemInfo) error {
+func runDiskBenchmarks(targetDirs []string, testSize, testProfile string, sysInfo *SystemInfo) error {
 	fmt.Println("  Running Disk I/O benchmarks (FIO)...")
 
-	// Define FIO test scenarios
-	fioScenarios := []struct {
-		name        string
-		rw          string
-		bs          string
-		iodepth     int
-		numjobs     int
-		rwmixread   int
-		description string
-		category    string
-	}{
-		// Standard tests (always run)
-		{"4K_RandRead_QD64", "randread", "4k", 64, 4, 0, "4K Random Read (QD=64)", "standard"},
-		{"4K_RandWrite_QD64", "randwrite", "4k", 64, 4, 0, "4K Random Write (QD=64)", "standard"},
-		{"1M_SeqRead_QD32", "read", "1m", 32, 2, 0, "1M Sequential Read (QD=32)", "standard"},
-		{"1M_SeqWrite_QD32", "write", "1m", 32, 2, 0, "1M Sequential Write (QD=32)", "standard"},
-		{"4K_Mixed_R70W30_QD64", "randrw", "4k", 64, 4, 70, "4K Mixed 70% Read 30% Write (QD=64)", "standard"},
-
-		// IOPS-focused tests (QD scaling)
-		{"4K_RandRead_QD1", "randread", "4k", 1, 1, 0, "4K Random Read (QD=1)", "iops_scaling"},
-		{"4K_RandRead_QD4", "randread", "4k", 4, 1, 0, "4K Random Read (QD=4)", "iops_scaling"},
-		{"4K_RandRead_QD16", "randread", "4k", 16, 1, 0, "4K Random Read (QD=16)", "iops_scaling"},
-		{"4K_RandRead_QD128", "randread", "4k", 128, 4, 0, "4K Random Read (QD=128)", "iops_scaling"},
-
-		// Throughput-focused tests
-		{"128K_SeqRead_QD32", "read", "128k", 32, 2, 0, "128K Sequential Read (QD=32)", "throughput"},
-		{"128K_SeqWrite_QD32", "write", "128k", 32, 2, 0, "128K Sequential Write (QD=32)", "throughput"},
-		{"512K_SeqRead_QD32", "read", "512k", 32, 2, 0, "512K Sequential Read (QD=32)", "throughput"},
-		{"512K_SeqWrite_QD32", "write", "512k", 32, 2, 0, "512K Sequential Write (QD=32)", "throughput"},
-
-		// Latency-focused tests
-		{"4K_RandRead_QD1_Latency", "randread", "4k", 1, 1, 0, "4K Random Read Latency (QD=1)", "latency"},
-		{"4K_RandWrite_QD1_Latency", "randwrite", "4k", 1, 1, 0, "4K Random Write Latency (QD=1)", "latency"},
+	// Load user-defined scenarios first so a bad file fails before any test runs
+	var customScenarios []fioScenario
+	if fioScenariosFile != "" {
+		var err error
+		customScenarios, err = loadFioScenarioFile(fioScenariosFile)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("    Loaded %d custom FIO scenario(s) from %s\n", len(customScenarios), fioScenariosFile)
+	} else if testProfile == "custom" {
+		return fmt.Errorf("the 'custom' FIO profile requires --fio-scenarios")
 	}
 
-	// Determine test targets
-	var testTargets []struct {
-		mountPoint string
-		devicePath string
-		deviceName string
+	var sweepScenario fioScenario
+	if fioSchedSweep != "" {
+		var ok bool
+		if sweepScenario, ok = findFioScenario(fioSchedSweep, customScenarios); !ok {
+			return fmt.Errorf("unknown --fio-scheduler-sweep scenario '%s'", fioSchedSweep)
+		}
 	}
 
-	if targetDir != "" {
-		// User specified a target directory
-		fmt.Printf("    Using user-specified target directory: %s\n", targetDir)
-
-		// Check if it's a directory and not a raw device path
-		if strings.HasPrefix(targetDir, "/dev/") {
-			fmt.Printf("    Warning: %s appears to be a raw device path. For safety, only mounted directories are supported.\n", targetDir)
-			return fmt.Errorf("raw device paths are not supported for FIO tests, please specify a mounted directory")
+	if fioQDSweep {
+		switch fioQDSweepRW {
+		case "randread", "randwrite", "randrw":
+		default:
+			return fmt.Errorf("invalid --fio-qd-sweep-rw '%s' (use randread, randwrite or randrw)", fioQDSweepRW)
+		}
+		for _, jobs := range fioQDSweepJobs {
+			if jobs < 1 {
+				return fmt.Errorf("invalid --fio-qd-sweep-jobs value %d", jobs)
+			}
+		}
+		if fioQDSweepRuntime < 1 {
+			return fmt.Errorf("invalid --fio-qd-sweep-runtime %d", fioQDSweepRuntime)
 		}
+	}
 
-		// Check if directory exists
-		if _, err := os.Stat(targetDir); os.IsNotExist(err) {
-			return fmt.Errorf("specified target directory %s does not exist", targetDir)
+	if fioMixSweep {
+		if len(fioMixSweepRatios) == 0 {
+			return fmt.Errorf("--fio-mix-sweep-ratios is empty")
+		}
+		for _, ratio := range fioMixSweepRatios {
+			if ratio < 0 || ratio > 100 {
+				return fmt.Errorf("invalid --fio-mix-sweep-ratios value %d (read percentage from 0 to 100)", ratio)
+			}
+		}
+		if bs, err := parseSizeBytes(fioMixSweepBS); err != nil || bs == 0 {
+			return fmt.Errorf("invalid --fio-mix-sweep-bs '%s'", fioMixSweepBS)
 		}
+		if fioMixSweepQD < 1 || fioMixSweepRuntime < 1 {
+			return fmt.Errorf("--fio-mix-sweep-qd and --fio-mix-sweep-runtime must be at least 1")
+		}
+	}
+
+	if fioDiscard && fioDiscardRuntime < 1 {
+		return fmt.Errorf("invalid --fio-discard-runtime %d", fioDiscardRuntime)
+	}
 
-		// Check if it's the root filesystem
-		absPath, err := filepath.Abs(targetDir)
+	// Parse the replay trace once; every target replays the same I/O
+	var replayTrace *ioTrace
+	if fioReplay != "" {
+		if fioReplaySpeed < 0 {
+			return fmt.Errorf("invalid --fio-replay-speed %g", fioReplaySpeed)
+		}
+		if fioReplayRemap != "wrap" && fioReplayRemap != "scale" {
+			return fmt.Errorf("invalid --fio-replay-remap '%s' (use wrap or scale)", fioReplayRemap)
+		}
+		trace, err := loadIOTrace(fioReplay)
 		if err != nil {
-			return fmt.Errorf("could not resolve absolute path for %s: %w", targetDir, err)
+			return fmt.Errorf("invalid --fio-replay: %w", err)
+		}
+		replayTrace = trace
+		summary := trace.summary
+		fmt.Printf("    Replay trace: %s (%s), %d reads and %d writes over %.1fs, %s span\n", fioReplay, trace.format,
+			summary.Reads, summary.Writes, summary.DurationSec, humanReadableBytes(summary.Span))
+	}
+
+	// Resolve the I/O engines; unsupported ones are recorded and skipped
+	requestedEngines, err := parseFioEngines(fioEngineSpecs)
+	if err != nil {
+		return fmt.Errorf("invalid --fio-engines: %w", err)
+	}
+	engines, engineSupport := detectFioEngineSupport(requestedEngines, sysInfo.KernelVersion)
+	sysInfo.FioEngines = engineSupport
+	for _, support := range engineSupport {
+		status := colorGreen + "available" + colorReset
+		if !support.Available {
+			status = colorYellow + "unavailable" + colorReset
+		}
+		fmt.Printf("    I/O engine %s: %s", support.Engine, status)
+		if support.Notes != "" {
+			fmt.Printf(" (%s)", support.Notes)
+		}
+		fmt.Println()
+	}
+	if len(engines) == 0 {
+		return fmt.Errorf("none of the requested FIO engines are supported on this system")
+	}
+
+	// Buffered runs, sweeps, steady state and parallel runs drive fio directly; use the first fio engine
+	var primaryFioEngine fioEngine
+	haveFioEngine := false
+	for _, engine := range engines {
+		if engine.name != nativeDiskEngine {
+			primaryFioEngine, haveFioEngine = engine, true
+			break
 		}
+	}
+	if !haveFioEngine && (fioBuffered || fioSchedSweep != "" || fioQDSweep || fioMixSweep || replayTrace != nil || fioDiscard || fioSteadyState || fioParallel) {
+		fmt.Printf("    %sNo fio engine available: skipping buffered, scheduler sweep, queue-depth sweep, mix sweep, trace replay, discard, steady-state and parallel tests%s\n", colorYellow, colorReset)
+		fioBuffered, fioSchedSweep, fioQDSweep, fioMixSweep, fioDiscard, fioSteadyState, fioParallel = false, "", false, false, false, false, false
+		replayTrace = nil
+	}
+
+	// Resolve mounts and block devices down to physical disks once, for target selection and the report
+	topology := loadStorageTopology()
+
+	// Determine test targets
+	var testTargets []struct {
+		mountPoint string
+		devicePath string
+		deviceName string
+	}
+	var rawInfo rawDeviceInfo
 
-		if absPath == "/" {
-			fmt.Println("    Warning: Target directory resolves to root filesystem (/). Skipping for safety.")
-			return fmt.Errorf("testing on root filesystem (/) is not allowed for safety")
+	if rawDevice != "" {
+		// Destructive whole-device mode; re-check right before writing in case anything changed since startup
+		var err error
+		rawInfo, err = validateRawDevice(rawDevice, confirmSerial)
+		if err != nil {
+			return fmt.Errorf("raw device safety check failed: %w", err)
 		}
+		fmt.Printf("    %sDESTRUCTIVE raw device test: all data on %s (serial %s, %s) will be overwritten%s\n",
+			colorRed, rawInfo.path, rawInfo.serial, humanReadableBytes(rawInfo.sizeBytes), colorReset)
 
-		// Add to test targets
 		testTargets = append(testTargets, struct {
 			mountPoint string
 			devicePath string
 			deviceName string
 		}{
-			mountPoint: absPath,
-			devicePath: "user-specified",
-			deviceName: absPath,
+			mountPoint: "raw", // Special flag for destructive whole-device testing
+			devicePath: rawInfo.path,
+			deviceName: fmt.Sprintf("%s (%s, serial %s) [Raw, destructive]", rawInfo.path, rawInfo.model, rawInfo.serial),
 		})
+	} else if len(targetDirs) > 0 {
+		// User specified one or more target directories
+		seenSources := make(map[string]string)
+		for _, targetDir := range targetDirs {
+			fmt.Printf("    Using user-specified target directory: %s\n", targetDir)
+
+			// Check if it's a directory and not a raw device path
+			if strings.HasPrefix(targetDir, "/dev/") {
+				fmt.Printf("    Warning: %s appears to be a raw device path. Use --raw-device with --destroy-data and --confirm-serial to test a whole device.\n", targetDir)
+				return fmt.Errorf("raw device paths are not supported by --fio-target-dir, please specify a mounted directory")
+			}
+
+			// Check if directory exists
+			if _, err := os.Stat(targetDir); os.IsNotExist(err) {
+				return fmt.Errorf("specified target directory %s does not exist", targetDir)
+			}
+
+			// Check if it's the root filesystem
+			absPath, err := filepath.Abs(targetDir)
+			if err != nil {
+				return fmt.Errorf("could not resolve absolute path for %s: %w", targetDir, err)
+			}
+
+			if absPath == "/" {
+				fmt.Println("    Warning: Target directory resolves to root filesystem (/). Skipping for safety.")
+				return fmt.Errorf("testing on root filesystem (/) is not allowed for safety")
+			}
+
+			// Name the target after the device behind it, so per-device results are recognisable
+			devicePath := "user-specified"
+			if m, ok := topology.mountFor(absPath); ok {
+				devicePath = m.source
+				for _, disk := range topology.mountDisks(m) {
+					if other, seen := seenSources[disk]; seen {
+						fmt.Printf("    Warning: %s and %s share disk /dev/%s; parallel results will not be independent\n", other, absPath, disk)
+					}
+					seenSources[disk] = absPath
+				}
+			}
+
+			// Add to test targets
+			testTargets = append(testTargets, struct {
+				mountPoint string
+				devicePath string
+				deviceName string
+			}{
+				mountPoint: absPath,
+				devicePath: devicePath,
+				deviceName: absPath,
+			})
+		}
 	} else {
 		// Auto-detect storage devices for testing
 		fmt.Println("    Auto-detecting storage devices for testing...")
@@ -1423,9 +2033,15 @@ func runDiskBenchmarks(targetDir, testSize, testProfile string, sysInfo *SystemI
 		testedDevices := make(map[string]bool)
 
 		// First, try to find the boot disk and test it
-		bootDisk := findBootDisk()
-		if bootDisk != "" {
-			fmt.Printf("    Detected boot disk: %s\n", bootDisk)
+		bootDisks := findBootDisks(topology)
+		if rootMount, ok := topology.mountFor("/"); ok && len(bootDisks) > 0 {
+			fmt.Printf("    Detected boot disk: %s (%s)\n", strings.Join(bootDisks, ", "), topology.mountStack(rootMount))
+
+			// /tmp is often tmpfs, which would measure RAM; /var/tmp is on the root filesystem
+			bootTestDir := "/tmp"
+			if tmpMount, ok := topology.mountFor(bootTestDir); ok && len(topology.mountDisks(tmpMount)) == 0 {
+				bootTestDir = "/var/tmp"
+			}
 
 			// Add boot disk to test targets
 			testTargets = append(testTargets, struct {
@@ -1433,13 +2049,15 @@ func runDiskBenchmarks(targetDir, testSize, testProfile string, sysInfo *SystemI
 				devicePath string
 				deviceName string
 			}{
-				mountPoint: "/tmp", // We'll use /tmp for testing
-				devicePath: bootDisk,
-				deviceName: fmt.Sprintf("%s (Boot Disk)", bootDisk),
+				mountPoint: bootTestDir,
+				devicePath: rootMount.source,
+				deviceName: fmt.Sprintf("%s (Boot Disk)", strings.Join(bootDisks, " + ")),
 			})
 
-			testedDevices[bootDisk] = true
-			fmt.Printf("    Added boot disk for testing: %s\n", bootDisk)
+			for _, bootDisk := range bootDisks {
+				testedDevices[bootDisk] = true
+			}
+			fmt.Printf("    Added boot disk for testing: %s (directory: %s)\n", rootMount.source, bootTestDir)
 		}
 
 		// Next, try to find NVMe devices for raw testing if we're root
@@ -1454,16 +2072,10 @@ func runDiskBenchmarks(targetDir, testSize, testProfile string, sysInfo *SystemI
 					continue
 				}
 
-				// Skip if it's the boot disk
-				if nvme.DevicePath == bootDisk {
-					fmt.Printf("    Skipping %s as it's the boot disk\n", nvme.DevicePath)
-					continue
-				}
-
 				fmt.Printf("    Processing NVMe device for direct testing: %s\n", nvme.DevicePath)
 
 				// Check if this device is safe for direct testing
-				isSafe, reason := isSafeForDirectTesting(nvme.DevicePath)
+				isSafe, reason := isSafeForDirectTesting(topology, nvme.DevicePath)
 				if isSafe {
 					// Add for direct testing
 					testTargets = append(testTargets, struct {
@@ -1473,7 +2085,7 @@ func runDiskBenchmarks(targetDir, testSize, testProfile string, sysInfo *SystemI
 					}{
 						mountPoint: "direct", // Special flag for direct device testing
 						devicePath: nvme.DevicePath,
-						deviceName: fmt.Sprintf("%s (%s) [Direct]", nvme.DevicePath, nvme.Model),
+						deviceName: fmt.Sprintf("%s (%s) [Direct, read-only]", nvme.DevicePath, nvme.Model),
 					})
 
 					testedDevices[nvme.DevicePath] = true
@@ -1499,7 +2111,7 @@ func runDiskBenchmarks(targetDir, testSize, testProfile string, sysInfo *SystemI
 				fmt.Printf("    Processing NVMe device for filesystem testing: %s\n", nvme.DevicePath)
 
 				// Find the best mount point for this device
-				bestMountPoint := findBestMountPoint(nvme.DevicePath)
+				bestMountPoint := findBestMountPoint(topology, nvme.DevicePath)
 				if bestMountPoint.MountPoint != "" {
 					testTargets = append(testTargets, struct {
 						mountPoint string
@@ -1511,9 +2123,13 @@ func runDiskBenchmarks(targetDir, testSize, testProfile string, sysInfo *SystemI
 						deviceName: fmt.Sprintf("%s (%s)", bestMountPoint.DevicePath, nvme.Model),
 					})
 
+					// A RAID or multi-device filesystem covers every disk under it
 					testedDevices[nvme.DevicePath] = true
-					fmt.Printf("    Added NVMe filesystem for testing: %s (mount: %s)\n",
-						bestMountPoint.DevicePath, bestMountPoint.MountPoint)
+					for _, disk := range bestMountPoint.Disks {
+						testedDevices["/dev/"+disk] = true
+					}
+					fmt.Printf("    Added NVMe filesystem for testing: %s (mount: %s, %s)\n",
+						bestMountPoint.DevicePath, bestMountPoint.MountPoint, bestMountPoint.DeviceName)
 				} else {
 					fmt.Printf("    No suitable filesystem found for NVMe device: %s\n", nvme.DevicePath)
 				}
@@ -1524,173 +2140,108 @@ func runDiskBenchmarks(targetDir, testSize, testProfile string, sysInfo *SystemI
 		if len(testTargets) == 0 {
 			fmt.Println("    No suitable NVMe devices found. Checking other storage devices...")
 
-			// Get all block devices with mount points
-			output, err := runCommand("lsblk", "-pno", "NAME,MOUNTPOINT,TYPE,SIZE")
-			if err != nil {
-				fmt.Printf("    Error listing block devices: %v\n", err)
-			} else {
-				// Parse output to find suitable mount points
-				scanner := bufio.NewScanner(strings.NewReader(output))
-				for scanner.Scan() {
-					line := scanner.Text()
-					if line == "" {
-						continue
+			// Every mounted filesystem backed by disks (directly or through LVM, LUKS, md RAID, btrfs or ZFS),
+			// one per set of disks so a RAID is not tested once per member
+			for _, m := range topology.mounts {
+				if m.mountPoint == "/" {
+					continue
+				}
+				disks := topology.mountDisks(m)
+				if len(disks) == 0 {
+					continue // tmpfs, proc, network filesystems
+				}
+				alreadyTested := false
+				for _, disk := range disks {
+					if testedDevices["/dev/"+disk] {
+						alreadyTested = true
+						break
 					}
+				}
+				if alreadyTested {
+					continue
+				}
 
-					fields := strings.Fields(line)
-					if len(fields) >= 3 {
-						devicePath := fields[0]
-						mountPoint := ""
-						if len(fields) >= 2 {
-							mountPoint = fields[1]
-						}
-						deviceType := ""
-						if len(fields) >= 3 {
-							deviceType = fields[2]
-						}
+				testTargets = append(testTargets, struct {
+					mountPoint string
+					devicePath string
+					deviceName string
+				}{
+					mountPoint: m.mountPoint,
+					devicePath: m.source,
+					deviceName: m.source,
+				})
+				for _, disk := range disks {
+					testedDevices["/dev/"+disk] = true
+				}
 
-						// Skip root filesystem and non-mounted devices
-						if mountPoint == "" || mountPoint == "/" || mountPoint == "[SWAP]" {
-							continue
-						}
+				fmt.Printf("    Added mount point: %s for device: %s (%s)\n", m.mountPoint, m.source, topology.mountStack(m))
+			}
 
-						// Skip non-disk and non-partition devices
-						if deviceType != "disk" && deviceType != "part" && deviceType != "lvm" {
-							continue
-						}
+			// If still no targets, try to find any mounted directory with sufficient space
+			if len(testTargets) == 0 {
+				fmt.Println("    No specific devices found. Looking for any suitable mount point...")
 
-						// Add to test targets
-						testTargets = append(testTargets, struct {
-							mountPoint string
-							devicePath string
-							deviceName string
-						}{
-							mountPoint: mountPoint,
-							devicePath: devicePath,
-							deviceName: devicePath,
-						})
-
-						fmt.Printf("    Added mount point: %s for device: %s\n", mountPoint, devicePath)
+				// Any mounted filesystem with at least 1 GiB free; root, pseudo filesystems and
+				// network storage (which needs --fio-target-dir) are skipped
+				for _, m := range topology.mounts {
+					if m.mountPoint == "/" || pseudoFilesystems[m.fsType] || networkFilesystemKind(m.fsType) != "" {
+						continue
 					}
+					_, available, err := filesystemSpace(m.mountPoint)
+					if err != nil || available < 1<<30 {
+						continue
+					}
+					testTargets = append(testTargets, struct {
+						mountPoint string
+						devicePath string
+						deviceName string
+					}{
+						mountPoint: m.mountPoint,
+						devicePath: "unknown",
+						deviceName: fmt.Sprintf("Mount point: %s", m.mountPoint),
+					})
+
+					fmt.Printf("    Added mount point with sufficient space: %s\n", m.mountPoint)
+					break // Just need one good target
 				}
 			}
+		}
+	}
 
-			// If still no targets, try to find LVM volumes
-			if len(testTargets) == 0 {
-				fmt.Println("    Checking for LVM volumes...")
-
-				// Try to get LVM volumes
-				lvmOutput, err := runCommand("lvs", "--noheadings", "-o", "lv_path,lv_size")
-				if err == nil {
-					scanner := bufio.NewScanner(strings.NewReader(lvmOutput))
-					for scanner.Scan() {
-						line := scanner.Text()
-						if line == "" {
-							continue
-						}
+	if len(testTargets) == 0 {
+		fmt.Println("    No suitable targets found for FIO benchmarks.")
+		return nil
+	}
 
-						fields := strings.Fields(line)
-						if len(fields) >= 1 {
-							lvPath := fields[0]
+	// Snapshot the health of every disk behind the targets, to compare after the tests
+	var healthDisks []string
+	seenDisks := make(map[string]bool)
+	for _, target := range testTargets {
+		for _, disk := range topology.targetDisks(target.mountPoint, target.devicePath) {
+			if dev, ok := topology.devices[disk]; ok && dev.kind == "disk" && !seenDisks[disk] {
+				seenDisks[disk] = true
+				healthDisks = append(healthDisks, "/dev/"+disk)
+			}
+		}
+	}
+	if len(healthDisks) > 0 {
+		fmt.Printf("    Capturing drive health before the tests: %s\n", strings.Join(healthDisks, ", "))
+		sysInfo.DriveHealth = captureDriveHealth(healthDisks)
+	}
 
-							// Check if this LV is mounted
-							mountOutput, err := runCommand("findmnt", "-no", "TARGET", lvPath)
-							if err == nil && mountOutput != "" {
-								mountPoint := strings.TrimSpace(mountOutput)
+	// Initialize FIO results in SystemInfo
+	sysInfo.FioResults = make([]FioDeviceResult, 0, len(testTargets))
+	var parallelTargets []fioParallelTarget
 
-								// Skip root filesystem
-								if mountPoint == "/" {
-									continue
-								}
-
-								// Add to test targets
-								testTargets = append(testTargets, struct {
-									mountPoint string
-									devicePath string
-									deviceName string
-								}{
-									mountPoint: mountPoint,
-									devicePath: lvPath,
-									deviceName: lvPath,
-								})
-
-								fmt.Printf("    Added LVM mount point: %s for volume: %s\n", mountPoint, lvPath)
-							}
-						}
-					}
-				}
-			}
-
-			// If still no targets, try to find any mounted directory with sufficient space
-			if len(testTargets) == 0 {
-				fmt.Println("    No specific devices found. Looking for any suitable mount point...")
-
-				// Get mount points with available space
-				dfOutput, err := runCommand("df", "-T", "--output=target,fstype,avail")
-				if err == nil {
-					scanner := bufio.NewScanner(strings.NewReader(dfOutput))
-					scanner.Scan() // Skip header
-
-					for scanner.Scan() {
-						line := scanner.Text()
-						if line == "" {
-							continue
-						}
-
-						fields := strings.Fields(line)
-						if len(fields) >= 3 {
-							mountPoint := fields[0]
-							fsType := fields[1]
-							availStr := fields[2]
-
-							// Skip special filesystems and root
-							if mountPoint == "/" ||
-								strings.HasPrefix(fsType, "tmpfs") ||
-								strings.HasPrefix(fsType, "devtmpfs") ||
-								strings.HasPrefix(fsType, "sysfs") ||
-								strings.HasPrefix(fsType, "proc") {
-								continue
-							}
-
-							// Parse available space
-							availKB, err := strconv.ParseUint(availStr, 10, 64)
-							if err == nil && availKB > 1048576 { // At least 1GB free
-								// Add to test targets
-								testTargets = append(testTargets, struct {
-									mountPoint string
-									devicePath string
-									deviceName string
-								}{
-									mountPoint: mountPoint,
-									devicePath: "unknown",
-									deviceName: fmt.Sprintf("Mount point: %s", mountPoint),
-								})
-
-								fmt.Printf("    Added mount point with sufficient space: %s\n", mountPoint)
-								break // Just need one good target
-							}
-						}
-					}
-				}
-			}
-		}
-	}
-
-	if len(testTargets) == 0 {
-		fmt.Println("    No suitable targets found for FIO benchmarks.")
-		return nil
-	}
-
-	// Initialize FIO results in SystemInfo
-	sysInfo.FioResults = make([]FioDeviceResult, 0, len(testTargets))
-
-	// For each target, run the FIO tests
-	for _, target := range testTargets {
-		fmt.Printf("\n    Starting FIO benchmarks for %s (mount point: %s)\n", target.deviceName, target.mountPoint)
+	// For each target, run the FIO tests
+	for _, target := range testTargets {
+		fmt.Printf("\n    Starting FIO benchmarks for %s (mount point: %s)\n", target.deviceName, target.mountPoint)
 
 		var testFilePath string
 		var availableSpace uint64
-		var isDirectDeviceTest bool
+		var isDirectDeviceTest bool // Auto-detected raw device, read-only
+		var isRawDeviceTest bool    // --raw-device, writes allowed
+		var network NetworkFilesystem
 
 		// Check if this is a direct device test
 		if target.mountPoint == "direct" {
@@ -1701,10 +2252,20 @@ func runDiskBenchmarks(targetDir, testSize, testProfile string, sysInfo *SystemI
 			// Just use a reasonable test size (1GB)
 			availableSpace = 1024 * 1024 * 1024 * 10 // Assume 10GB available
 
-			fmt.Printf("      Using direct device testing for %s\n", target.devicePath)
+			fmt.Printf("      Using read-only direct device testing for %s\n", target.devicePath)
+		} else if target.mountPoint == "raw" {
+			isRawDeviceTest = true
+			testFilePath = target.devicePath
+			availableSpace = rawInfo.sizeBytes
+
+			// An empty size means the whole device (raw mode has a single target)
+			if testSize == "" {
+				testSize = fmt.Sprintf("%dM", rawInfo.sizeBytes/(1024*1024))
+			}
+			fmt.Printf("      Using whole-device destructive testing for %s (%s)\n", target.devicePath, testSize)
 		} else {
-			// Create a temporary directory for testing
-			tempDir := filepath.Join("/tmp", fmt.Sprintf("hyprbench_fio_%d", time.Now().UnixNano()))
+			// Create a temporary directory for testing on the target filesystem
+			tempDir := filepath.Join(target.mountPoint, fmt.Sprintf("hyprbench_fio_%d", time.Now().UnixNano()))
 			err := os.MkdirAll(tempDir, 0755)
 			if err != nil {
 				fmt.Printf("      Error creating temporary directory %s: %v\n", tempDir, err)
@@ -1715,23 +2276,19 @@ func runDiskBenchmarks(targetDir, testSize, testProfile string, sysInfo *SystemI
 			// Use the temporary directory for testing
 			testFilePath = filepath.Join(tempDir, "fio_test_file")
 
-			// Check available space in /tmp
-			output, err := runCommand("df", "--output=avail", "-B1", "/tmp")
+			// Check available space on the target filesystem
+			_, availableSpace, err = filesystemSpace(target.mountPoint)
 			if err != nil {
-				fmt.Printf("      Error checking available space on /tmp: %v\n", err)
+				fmt.Printf("      Error checking available space on %s: %v\n", target.mountPoint, err)
 				continue
 			}
 
-			// Parse available space
-			scanner := bufio.NewScanner(strings.NewReader(output))
-			scanner.Scan() // Skip header
-			if scanner.Scan() {
-				availStr := strings.TrimSpace(scanner.Text())
-				availableSpace, err = strconv.ParseUint(availStr, 10, 64)
-				if err != nil {
-					fmt.Printf("      Error parsing available space '%s': %v\n", availStr, err)
-					continue
-				}
+			// Network and FUSE filesystems are not local disks: label them and adapt the fio options
+			if fs, ok := detectNetworkFilesystem(topology, target.mountPoint, tempDir); ok {
+				network = fs
+				target.deviceName = networkStorageLabel(fs)
+				fmt.Printf("      Network storage: %s\n", target.deviceName)
+				printNetworkFilesystem("        ", fs)
 			}
 		}
 
@@ -1765,312 +2322,229 @@ func runDiskBenchmarks(targetDir, testSize, testProfile string, sysInfo *SystemI
 			continue
 		}
 
-		// Filter scenarios based on the selected profile
-		var selectedScenarios []struct {
-			name        string
-			rw          string
-			bs          string
-			iodepth     int
-			numjobs     int
-			rwmixread   int
-			description string
-			category    string
-		}
-
-		switch testProfile {
-		case "quick":
-			// Quick profile - just the essential tests
-			for _, scenario := range fioScenarios {
-				if scenario.name == "4K_RandRead_QD64" || scenario.name == "1M_SeqRead_QD32" {
-					selectedScenarios = append(selectedScenarios, scenario)
-				}
-			}
-		case "thorough":
-			// Thorough profile - all tests
-			selectedScenarios = fioScenarios
-		case "iops":
-			// IOPS-focused profile
-			for _, scenario := range fioScenarios {
-				if scenario.category == "standard" || scenario.category == "iops_scaling" {
-					selectedScenarios = append(selectedScenarios, scenario)
-				}
+		// Precondition before the scenario matrix so the scenarios also measure the drive in
+		// steady state rather than fresh out of box
+		var steadyState FioSteadyStateResult
+		if fioSteadyState {
+			if isDirectDeviceTest {
+				fmt.Println("      Skipping steady-state preconditioning: auto-detected raw devices are read-only (use --raw-device with --destroy-data)")
+			} else {
+				steadyState = runFioSteadyState(testFilePath, testSize, primaryFioEngine,
+					append([]string{"--filename=" + testFilePath, "--direct=1"}, network.FioArgs...))
 			}
-		case "throughput":
-			// Throughput-focused profile
-			for _, scenario := range fioScenarios {
-				if scenario.category == "standard" || scenario.category == "throughput" {
-					selectedScenarios = append(selectedScenarios, scenario)
+		}
+
+		// Filter scenarios based on the selected profile
+		selectedScenarios := selectFioScenarios(testProfile, customScenarios)
+
+		// Auto-detected raw devices may hold data, so they only ever get read tests
+		accessMode := "filesystem"
+		if isDirectDeviceTest {
+			accessMode = "raw read-only"
+			var readOnly []fioScenario
+			for _, scenario := range selectedScenarios {
+				if !fioScenarioWrites(scenario) {
+					readOnly = append(readOnly, scenario)
 				}
 			}
-		case "latency":
-			// Latency-focused profile
-			for _, scenario := range fioScenarios {
-				if scenario.category == "standard" || scenario.category == "latency" {
-					selectedScenarios = append(selectedScenarios, scenario)
-				}
+			if skipped := len(selectedScenarios) - len(readOnly); skipped > 0 {
+				fmt.Printf("      Skipping %d write test(s) on %s: raw writes need --raw-device with --destroy-data\n", skipped, target.devicePath)
 			}
-		case "all":
-			// All tests
-			selectedScenarios = fioScenarios
-		default: // "standard" or any other value
-			// Standard profile - just the standard tests
-			for _, scenario := range fioScenarios {
-				if scenario.category == "standard" {
-					selectedScenarios = append(selectedScenarios, scenario)
-				}
+			selectedScenarios = readOnly
+		} else if isRawDeviceTest {
+			accessMode = "raw destructive"
+		} else if network.Kind == "FUSE" {
+			accessMode = "FUSE filesystem"
+		} else if network.Kind != "" {
+			accessMode = "network filesystem"
+		}
+
+		var unsupported []string
+		for _, scenario := range selectedScenarios {
+			if len(fioScenarioEngines(scenario, engines)) == 0 {
+				unsupported = append(unsupported, scenario.name)
 			}
 		}
+		if len(unsupported) > 0 {
+			fmt.Printf("      Skipping %d test(s) the %s engine cannot run: %s\n", len(unsupported), nativeDiskEngine, strings.Join(unsupported, ", "))
+		}
 
 		fmt.Printf("      Using FIO test profile: %s (%d tests)\n", testProfile, len(selectedScenarios))
 		fmt.Printf("      FIO test file: %s, Size: %s\n", testFilePath, testSize)
-		fmt.Println("      ---------------------------------------------------------------------------------")
-		fmt.Printf("      %-32s | %-10s | %-18s | %-18s\n", "Test Type", "IOPS", "Bandwidth (MB/s)", "Avg Latency")
-		fmt.Println("      ---------------------------------------------------------------------------------")
+		fmt.Println("      ------------------------------------------------------------------------------------------------")
+		fmt.Printf("      %-32s | %-10s | %-18s | %-12s | %-12s\n", "Test Type", "IOPS", "Bandwidth (MB/s)", "Avg Latency", "p99 Latency")
+		fmt.Println("      ------------------------------------------------------------------------------------------------")
+
+		// Record what the target sits on, so results on RAID or encrypted volumes are not mistaken for a single disk
+		var storageStack string
+		physicalDisks := topology.targetDisks(target.mountPoint, target.devicePath)
+		if isDirectDeviceTest || isRawDeviceTest {
+			if name := topology.deviceName(target.devicePath); name != "" {
+				storageStack = topology.describeStack(name)
+			}
+		} else if m, ok := topology.mountFor(target.mountPoint); ok {
+			storageStack = topology.mountStack(m)
+		}
+		if storageStack != "" {
+			fmt.Printf("      Storage stack: %s\n", storageStack)
+		}
 
 		// Initialize device result
 		deviceResult := FioDeviceResult{
 			DevicePath:     target.devicePath,
 			DeviceModel:    target.deviceName,
 			MountPoint:     target.mountPoint,
+			AccessMode:     accessMode,
+			StorageStack:   storageStack,
+			PhysicalDisks:  physicalDisks,
+			Network:        network,
+			QueueSettings:  targetQueueSettings(topology, target.mountPoint, target.devicePath),
 			TestFileSize:   testSize,
 			TestResults:    make([]FioTestResult, 0, len(selectedScenarios)),
 			TestsCompleted: true,
+			SteadyState:    steadyState,
 		}
 
 		// Create a progress bar if enabled
-		totalTests := len(selectedScenarios)
+		totalTests := 0
+		for _, scenario := range selectedScenarios {
+			totalTests += len(fioScenarioEngines(scenario, engines))
+		}
 		completedTests := 0
 
 		for _, scenario := range selectedScenarios {
-			// Show progress
-			if showProgress {
-				completedTests++
-				progressPercent := float64(completedTests) / float64(totalTests) * 100
-				progressBar := fmt.Sprintf("[%-20s] %3.0f%%", strings.Repeat("=", int(float64(20)*float64(completedTests)/float64(totalTests))), progressPercent)
-				fmt.Printf("\r      %s Running: %s", progressBar, scenario.description)
-			} else {
-				fmt.Printf("      Running FIO test: %s (%s, rw=%s, bs=%s, iodepth=%d, numjobs=%d",
-					scenario.name, scenario.description, scenario.rw, scenario.bs, scenario.iodepth, scenario.numjobs)
-				if scenario.rwmixread > 0 {
-					fmt.Printf(", rwmixread=%d", scenario.rwmixread)
-				}
-				fmt.Println(")")
-			}
-
-			// Build FIO command
-			fioArgs := []string{
-				fmt.Sprintf("--name=%s", scenario.name),
-				fmt.Sprintf("--filename=%s", testFilePath),
-				"--ioengine=libaio",
-				"--direct=1",
-				fmt.Sprintf("--rw=%s", scenario.rw),
-				fmt.Sprintf("--bs=%s", scenario.bs),
-				fmt.Sprintf("--iodepth=%d", scenario.iodepth),
-				fmt.Sprintf("--numjobs=%d", scenario.numjobs),
-				fmt.Sprintf("--size=%s", testSize),
-				"--runtime=60",
-				"--group_reporting",
-				"--output-format=json",
-			}
-
-			// For direct device tests, add special flags
-			if isDirectDeviceTest {
-				// For direct device tests, we need to be careful
-				if strings.Contains(scenario.rw, "write") {
-					// For write tests on direct devices, use a small size and add safety flags
-					fioArgs = append(fioArgs,
-						"--size=64M",    // Smaller size for safety
-						"--offset=1G",   // Start 1GB into the device to avoid partition tables
-						"--verify=0",    // No verification for direct device tests
-						"--fsync=1",     // Ensure data is synced
-						"--end_fsync=1") // Final fsync at end of test
+			for _, engine := range fioScenarioEngines(scenario, engines) {
+				// Show progress
+				if showProgress {
+					completedTests++
+					progressPercent := float64(completedTests) / float64(totalTests) * 100
+					progressBar := fmt.Sprintf("[%-20s] %3.0f%%", strings.Repeat("=", int(float64(20)*float64(completedTests)/float64(totalTests))), progressPercent)
+					fmt.Printf("\r      %s Running: %s [%s]", progressBar, scenario.description, engine.label)
 				} else {
-					// For read-only tests, we can use the full test size
-					fioArgs = append(fioArgs,
-						"--readonly", // Read-only mode for safety
-						"--verify=0") // No verification for direct device tests
+					fmt.Printf("      Running FIO test: %s (%s, engine=%s, rw=%s, bs=%s, iodepth=%d, numjobs=%d",
+						scenario.name, scenario.description, engine.label, scenario.rw, scenario.bs, scenario.iodepth, scenario.numjobs)
+					if fioMixedRW(scenario.rw) {
+						fmt.Printf(", rwmixread=%d", scenario.rwmixread)
+					}
+					fmt.Println(")")
 				}
-			}
-
-			if scenario.rwmixread > 0 {
-				fioArgs = append(fioArgs, fmt.Sprintf("--rwmixread=%d", scenario.rwmixread))
-			}
 
-			// Run FIO command
-			output, err := runCommand("fio", fioArgs...)
-			if err != nil {
-				// Clear progress bar if it was shown
-				if showProgress {
-					fmt.Print("\r" + strings.Repeat(" ", 80) + "\r") // Clear the line
+				// Run the scenario with fio, or with the built-in engine
+				result := newFioTestResult(scenario, engine)
+				var err error
+				if engine.name == nativeDiskEngine {
+					err = runNativeDiskTest(&result, scenario, testFilePath, testSizeBytes, isDirectDeviceTest)
+				} else {
+					var job map[string]interface{}
+					args := fioScenarioArgs(scenario, engine, testFilePath, testSize, isDirectDeviceTest, network.FioArgs)
+					if job, err = runFioJob(args); err == nil {
+						setFioJobResult(&result, job)
+					}
 				}
+				if err != nil {
+					// Clear progress bar if it was shown
+					if showProgress {
+						fmt.Print("\r" + strings.Repeat(" ", 80) + "\r") // Clear the line
+					}
 
-				fmt.Printf("        Error running FIO test '%s': %v\n", scenario.name, err)
-				deviceResult.TestsCompleted = false
-
-				// Add failed test result
-				deviceResult.TestResults = append(deviceResult.TestResults, FioTestResult{
-					TestName:    scenario.name,
-					ReadWrite:   scenario.rw,
-					BlockSize:   scenario.bs,
-					IODepth:     scenario.iodepth,
-					NumJobs:     scenario.numjobs,
-					RWMixRead:   scenario.rwmixread,
-					IOPS:        -1, // Use negative values to indicate failure
-					BandwidthMB: -1,
-					LatencyUs:   -1,
-					LatencyUnit: "us",
-				})
-
-				fmt.Printf("      %-32s | %-10s | %-18s | %-18s\n", scenario.name, "FAIL", "FAIL", "FAIL")
-				continue
-			}
+					fmt.Printf("        Error running FIO test '%s' (%s): %v\n", scenario.name, engine.label, err)
+					deviceResult.TestsCompleted = false
 
-			// Parse FIO JSON output
-			var result FioTestResult
-			result.TestName = scenario.name
-			result.ReadWrite = scenario.rw
-			result.BlockSize = scenario.bs
-			result.IODepth = scenario.iodepth
-			result.NumJobs = scenario.numjobs
-			result.RWMixRead = scenario.rwmixread
-			result.LatencyUnit = "us" // Default
+					// Add failed test result, using negative values to indicate failure
+					result.IOPS, result.BandwidthMB, result.LatencyUs = -1, -1, -1
+					deviceResult.TestResults = append(deviceResult.TestResults, result)
 
-			// Parse JSON
-			var fioData map[string]interface{}
-			if err := json.Unmarshal([]byte(output), &fioData); err != nil {
-				// Clear progress bar if it was shown
-				if showProgress {
-					fmt.Print("\r" + strings.Repeat(" ", 80) + "\r") // Clear the line
+					fmt.Printf("      %-32s | %-10s | %-18s | %-12s | %-12s\n", fioTestLabel(result), "FAIL", "FAIL", "FAIL", "FAIL")
+					continue
 				}
 
-				fmt.Printf("        Error parsing FIO JSON output: %v\n", err)
-				deviceResult.TestsCompleted = false
-				continue
-			}
+				// Add result to device results
+				deviceResult.TestResults = append(deviceResult.TestResults, result)
 
-			// Extract results from JSON
-			jobs, ok := fioData["jobs"].([]interface{})
-			if !ok || len(jobs) == 0 {
 				// Clear progress bar if it was shown
 				if showProgress {
 					fmt.Print("\r" + strings.Repeat(" ", 80) + "\r") // Clear the line
 				}
-
-				fmt.Printf("        Error: Invalid or empty jobs array in FIO output\n")
-				deviceResult.TestsCompleted = false
-				continue
+				fmt.Printf("      %-32s | %-10.0f | %-18.2f | %-12s | %-12s\n",
+					fioTestLabel(result), result.IOPS, result.BandwidthMB, formatLatencyNs(result.LatencyUs*1000), formatLatencyNs(fioCompletionP99Ns(result)))
+				printFioDirectionRows("      ", result)
+				printFioSyncLatency("      ", result)
 			}
+		}
 
-			job0 := jobs[0].(map[string]interface{})
+		fmt.Println("      ------------------------------------------------------------------------------------------------")
 
-			// Extract IOPS
-			if scenario.rw == "read" || scenario.rw == "randread" {
-				if read, ok := job0["read"].(map[string]interface{}); ok {
-					if iops, ok := read["iops"].(float64); ok {
-						result.IOPS = iops
-					}
-				}
-			} else if scenario.rw == "write" || scenario.rw == "randwrite" {
-				if write, ok := job0["write"].(map[string]interface{}); ok {
-					if iops, ok := write["iops"].(float64); ok {
-						result.IOPS = iops
-					}
-				}
-			} else if scenario.rw == "randrw" {
-				// For mixed workloads, sum read and write IOPS
-				var readIOPS, writeIOPS float64
-				if read, ok := job0["read"].(map[string]interface{}); ok {
-					if iops, ok := read["iops"].(float64); ok {
-						readIOPS = iops
-					}
-				}
-				if write, ok := job0["write"].(map[string]interface{}); ok {
-					if iops, ok := write["iops"].(float64); ok {
-						writeIOPS = iops
-					}
-				}
-				result.IOPS = readIOPS + writeIOPS
+		// Buffered pass on the same file, compared with the O_DIRECT results above
+		if fioBuffered {
+			deviceResult.BufferedWarning = fioBufferedSizeWarning(testSizeBytes)
+			if deviceResult.BufferedWarning != "" {
+				fmt.Printf("      %sWarning: %s%s\n", colorYellow, deviceResult.BufferedWarning, colorReset)
 			}
+			deviceResult.Buffered = runFioBuffered(selectedScenarios, primaryFioEngine, testFilePath, testSize, isDirectDeviceTest, network.FioArgs, deviceResult.TestResults)
+			printFioBuffered("      ", deviceResult)
+		}
 
-			// Extract bandwidth (MB/s)
-			if scenario.rw == "read" || scenario.rw == "randread" {
-				if read, ok := job0["read"].(map[string]interface{}); ok {
-					if bw, ok := read["bw"].(float64); ok {
-						result.BandwidthMB = bw / 1024 // Convert KiB/s to MiB/s
-					}
-				}
-			} else if scenario.rw == "write" || scenario.rw == "randwrite" {
-				if write, ok := job0["write"].(map[string]interface{}); ok {
-					if bw, ok := write["bw"].(float64); ok {
-						result.BandwidthMB = bw / 1024 // Convert KiB/s to MiB/s
-					}
+		// Rerun one scenario under each scheduler the disks offer
+		if fioSchedSweep != "" {
+			var disks []string
+			for _, disk := range physicalDisks {
+				if dev, ok := topology.devices[disk]; ok && dev.kind == "disk" {
+					disks = append(disks, disk)
 				}
-			} else if scenario.rw == "randrw" {
-				// For mixed workloads, sum read and write bandwidth
-				var readBW, writeBW float64
-				if read, ok := job0["read"].(map[string]interface{}); ok {
-					if bw, ok := read["bw"].(float64); ok {
-						readBW = bw
-					}
-				}
-				if write, ok := job0["write"].(map[string]interface{}); ok {
-					if bw, ok := write["bw"].(float64); ok {
-						writeBW = bw
-					}
-				}
-				result.BandwidthMB = (readBW + writeBW) / 1024 // Convert KiB/s to MiB/s
 			}
-
-			// Extract latency (us)
-			// First try clat (completion latency) which is most relevant
-			if scenario.rw == "read" || scenario.rw == "randread" {
-				if read, ok := job0["read"].(map[string]interface{}); ok {
-					if clat, ok := read["clat_ns"].(map[string]interface{}); ok {
-						if mean, ok := clat["mean"].(float64); ok {
-							result.LatencyUs = mean / 1000 // Convert ns to us
-						}
-					}
-				}
-			} else if scenario.rw == "write" || scenario.rw == "randwrite" {
-				if write, ok := job0["write"].(map[string]interface{}); ok {
-					if clat, ok := write["clat_ns"].(map[string]interface{}); ok {
-						if mean, ok := clat["mean"].(float64); ok {
-							result.LatencyUs = mean / 1000 // Convert ns to us
-						}
-					}
+			switch {
+			case isDirectDeviceTest && fioScenarioWrites(sweepScenario):
+				fmt.Printf("      Skipping scheduler sweep: %s writes and %s is read-only\n", sweepScenario.name, target.devicePath)
+			case len(disks) == 0:
+				fmt.Printf("      Skipping scheduler sweep: no physical disks found under %s\n", target.mountPoint)
+			default:
+				deviceResult.SchedulerSweep = runFioSchedulerSweep(topology, sweepScenario, primaryFioEngine, disks, testFilePath, testSize, isDirectDeviceTest, network.FioArgs)
+				if best := deviceResult.SchedulerSweep.Best; best != "" {
+					fmt.Printf("      Best scheduler for %s: %s%s%s\n", sweepScenario.name, colorGreen, best, colorReset)
 				}
 			}
+		}
 
-			// If latency is too large, convert to ms for better readability
-			if result.LatencyUs >= 1000 {
-				result.LatencyUs = result.LatencyUs / 1000
-				result.LatencyUnit = "ms"
+		// IOPS vs latency curve over increasing queue depth
+		if fioQDSweep {
+			if isDirectDeviceTest && fioQDSweepRW != "randread" {
+				fmt.Printf("      Skipping queue-depth sweep: %s writes and %s is read-only\n", fioQDSweepRW, target.devicePath)
+			} else {
+				deviceResult.QDSweep = runFioQDSweep(fioQDSweepRW, "4k", fioQDSweepJobs, fioQDSweepRuntime, primaryFioEngine, testFilePath, testSize, isDirectDeviceTest, network.FioArgs)
 			}
+		}
 
-			// Add result to device results
-			deviceResult.TestResults = append(deviceResult.TestResults, result)
-
-			// Print result
-			var latencyStr string
-			if result.LatencyUnit == "ms" {
-				latencyStr = fmt.Sprintf("%.2f ms", result.LatencyUs)
+		// Random I/O across the read/write mix range
+		if fioMixSweep {
+			if isDirectDeviceTest {
+				fmt.Printf("      Skipping read/write mix sweep: %s is read-only\n", target.devicePath)
 			} else {
-				latencyStr = fmt.Sprintf("%.2f us", result.LatencyUs)
+				deviceResult.MixSweep = runFioMixSweep(fioMixSweepRatios, fioMixSweepBS, fioMixSweepQD, fioMixSweepRuntime, primaryFioEngine, testFilePath, testSize, network.FioArgs)
 			}
+		}
 
-			// Clear progress bar if it was shown
-			if showProgress {
-				fmt.Print("\r" + strings.Repeat(" ", 80) + "\r") // Clear the line
-			}
+		// Recorded trace remapped onto the test file or device
+		if replayTrace != nil {
+			deviceResult.Replay = runFioReplay(replayTrace, fioReplaySpeed, fioReplayRemap, primaryFioEngine, testFilePath, testSizeBytes, isDirectDeviceTest, network.FioArgs)
+		}
 
-			fmt.Printf("      %-32s | %-10.0f | %-18.2f | %-18s\n",
-				scenario.name, result.IOPS, result.BandwidthMB, latencyStr)
+		// Discard last: it rewrites and then erases the whole test area
+		if fioDiscard && isRawDeviceTest {
+			deviceResult.Discard = runFioDiscardTest(topology, testFilePath, testSizeBytes, testSize, fioDiscardRuntime, primaryFioEngine)
 		}
 
-		fmt.Println("      ---------------------------------------------------------------------------------")
+		// Small-file metadata benchmark next to the FIO test file; device nodes have no filesystem
+		// Network filesystems always get it: their metadata round trips are what users notice first
+		if (diskMetadata || network.Kind != "") && !isDirectDeviceTest && !isRawDeviceTest {
+			if !diskMetadata {
+				fmt.Println("      Running the metadata latency benchmark on network storage")
+			}
+			deviceResult.Metadata = runMetadataBenchmark(filepath.Dir(testFilePath), diskMetadataFiles, diskMetadataThreads)
+		}
 
-		// Clean up test file if it's not a direct device test
-		if !isDirectDeviceTest && testFilePath != "" {
+		// Clean up test file if it's not a device node. The parallel run reuses the files;
+		// they are removed with their temporary directories when this function returns.
+		if !isDirectDeviceTest && !isRawDeviceTest && !fioParallel && testFilePath != "" {
 			if _, err := os.Stat(testFilePath); err == nil {
 				fmt.Printf("      Cleaning up FIO test file: %s\n", testFilePath)
 				if err := os.Remove(testFilePath); err != nil {
@@ -2083,6 +2557,30 @@ func runDiskBenchmarks(targetDir, testSize, testProfile string, sysInfo *SystemI
 
 		// Add device result to system info
 		sysInfo.FioResults = append(sysInfo.FioResults, deviceResult)
+		parallelTargets = append(parallelTargets, fioParallelTarget{
+			devicePath:   target.devicePath,
+			deviceName:   target.deviceName,
+			mountPoint:   target.mountPoint,
+			testFilePath: testFilePath,
+			testSize:     testSize,
+			readOnly:     isDirectDeviceTest,
+			fioArgs:      network.FioArgs,
+			soloIndex:    len(sysInfo.FioResults) - 1,
+		})
+	}
+
+	if fioParallel {
+		if len(parallelTargets) < 2 {
+			fmt.Println("    Skipping parallel FIO benchmarks: at least two targets are needed")
+		} else {
+			sysInfo.FioParallelResults = runFioParallel(parallelTargets, selectFioScenarios(testProfile, customScenarios), primaryFioEngine, sysInfo.FioResults)
+		}
+	}
+
+	if len(sysInfo.DriveHealth) > 0 {
+		finishDriveHealth(sysInfo.DriveHealth)
+		fmt.Println("\n    Drive health after the tests:")
+		printDriveHealth("      ", sysInfo.DriveHealth)
 	}
 
 	return nil
@@ -4028,6 +4526,14 @@ func min(a, b int) int {
 	return b
 }
 
+// max returns the larger of two integers
+func max(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
 // calculateDistance calculates the distance between two points on Earth using the Haversine formula
 func calculateDistance(lat1, lon1, lat2, lon2 float64) int {
 	const earthRadius = 6371 // Earth radius in kilometers
@@ -4119,6 +4625,17 @@ func exportResultsToHTML(sysInfo SystemInfo, filePath string) error {
             color: #2ecc71;
             font-weight: bold;
         }
+        .warning {
+            color: #e67e22;
+            font-weight: bold;
+        }
+        .error-banner {
+            border: 2px solid #c0392b;
+            background-color: #fdecea;
+        }
+        .error-banner h2 {
+            color: #c0392b;
+        }
         .footer {
             text-align: center;
             margin-top: 30px;
@@ -4134,7 +4651,7 @@ func exportResultsToHTML(sysInfo SystemInfo, filePath string) error {
         <p>Version: ` + sysInfo.HyprBenchVersion + `</p>
         <p>Date: ` + sysInfo.TestDate + `</p>
         <p>Hostname: ` + sysInfo.Hostname + `</p>
-    </div>
+    </div>` + hardwareErrorsHTML(sysInfo.HardwareErrors) + `
 
     <div class="section">
         <h2>System Information</h2>
@@ -4152,7 +4669,39 @@ func exportResultsToHTML(sysInfo SystemInfo, filePath string) error {
             <tr><td>OS</td><td>` + sysInfo.OSName + ` ` + sysInfo.OSVersion + `</td></tr>
             <tr><td>Kernel</td><td>` + sysInfo.KernelVersion + `</td></tr>
         </table>
+    </div>` + pcieLinksHTML(sysInfo.PCIeLinks)
+
+	// Add DIMM inventory if available
+	if len(sysInfo.DIMMs) > 0 {
+		html += `
+    <div class="section">
+        <h2>Memory Modules</h2>`
+		for _, warning := range sysInfo.MemoryWarnings {
+			html += `
+        <p class="warning">Warning: ` + htmlpkg.EscapeString(warning) + `</p>`
+		}
+		html += `
+        <table>
+            <tr><th>Slot</th><th>Size</th><th>Type</th><th>Configured Speed</th><th>Rated Speed</th><th>Rank</th><th>ECC</th><th>Manufacturer</th><th>Part Number</th></tr>`
+		for _, dimm := range sysInfo.DIMMs {
+			if !dimm.Populated {
+				html += `
+            <tr><td>` + htmlpkg.EscapeString(dimm.Locator) + `</td><td colspan="8">Empty</td></tr>`
+				continue
+			}
+			ecc := "No"
+			if dimm.ECC {
+				ecc = "Yes"
+			}
+			html += `
+            <tr><td>` + htmlpkg.EscapeString(dimm.Locator) + `</td><td>` + htmlpkg.EscapeString(dimm.Size) + `</td><td>` + htmlpkg.EscapeString(dimm.Type) + `</td><td>` +
+				fmt.Sprintf("%d MT/s", dimm.ConfiguredSpeedMTs) + `</td><td>` + fmt.Sprintf("%d MT/s", dimm.RatedSpeedMTs) + `</td><td>` +
+				fmt.Sprintf("%d", dimm.Rank) + `</td><td>` + ecc + `</td><td>` + htmlpkg.EscapeString(dimm.Manufacturer) + `</td><td>` + htmlpkg.EscapeString(dimm.PartNumber) + `</td></tr>`
+		}
+		html += `
+        </table>
     </div>`
+	}
 
 	// Add CPU Benchmark Results if available
 	if sysInfo.SysbenchSingleThreadScore != "" || sysInfo.SysbenchMultiThreadScore != "" {
@@ -4183,19 +4732,120 @@ func exportResultsToHTML(sysInfo SystemInfo, filePath string) error {
     </div>`
 	}
 
+	// Add NUMA memory matrix if available
+	if sysInfo.NumaResults.Mode != "" {
+		numa := sysInfo.NumaResults
+		html += `
+    <div class="section">
+        <h2>NUMA Memory Matrix</h2>
+        <p>Mode: ` + numa.Mode + `, Placement: ` + numa.Method + `, Buffer per cell: ` + humanReadableBytes(numa.TestSizeBytes) + `</p>`
+		html += numaMatrixHTML("Bandwidth (MB/s)", numa.Nodes, numa.BandwidthMBs, "%.0f")
+		html += numaMatrixHTML("Latency (ns)", numa.Nodes, numa.LatencyNs, "%.1f")
+		if numa.ErrorMessage != "" {
+			html += `
+        <p>Error: ` + htmlpkg.EscapeString(numa.ErrorMessage) + `</p>`
+		}
+		html += `
+    </div>`
+	}
+
+	// Add huge page impact results if available
+	if len(sysInfo.HugePageResults.Modes) > 0 {
+		hp := sysInfo.HugePageResults
+		html += `
+    <div class="section">
+        <h2>Huge Page Impact</h2>
+        <p>THP enabled: ` + hp.THPEnabled + `, THP defrag: ` + hp.THPDefrag + `, khugepaged defrag: ` + hp.KhugepagedDefrag +
+			`, hugetlb page size: ` + fmt.Sprintf("%d kB", hp.HugePageSizeKB) + `, buffer per mode: ` + humanReadableBytes(hp.TestSizeBytes) + `</p>
+        <table>
+            <tr><th>Mode</th><th>Bandwidth (MB/s)</th><th>Bandwidth Speedup</th><th>Latency (ns)</th><th>Latency Speedup</th><th>Huge-backed</th></tr>`
+		for _, mode := range hp.Modes {
+			if !mode.Available {
+				html += `
+            <tr><td>` + mode.Mode + `</td><td colspan="5">Skipped: ` + htmlpkg.EscapeString(mode.SkipReason) + `</td></tr>`
+				continue
+			}
+			html += `
+            <tr><td>` + mode.Mode + `</td><td class="highlight">` + fmt.Sprintf("%.2f", mode.BandwidthMBs) + `</td><td>` +
+				fmt.Sprintf("x%.2f", mode.BandwidthSpeedup) + `</td><td class="highlight">` + fmt.Sprintf("%.1f", mode.LatencyNs) + `</td><td>` +
+				fmt.Sprintf("x%.2f", mode.LatencySpeedup) + `</td><td>` + humanReadableBytes(mode.HugeBackedBytes) + `</td></tr>`
+		}
+		html += `
+        </table>
+    </div>`
+	}
+
+	// Add memory integrity test results if available
+	if mt := sysInfo.MemoryTestResults; mt.Passes > 0 {
+		result := `<span class="highlight">PASS</span>`
+		if !mt.Passed {
+			result = `<span class="warning">FAIL</span>`
+		}
+		edac := "N/A (no EDAC driver loaded)"
+		if mt.EDACBefore.Available {
+			edac = fmt.Sprintf("+%d corrected, +%d uncorrected", mt.CorrectedErrors, mt.UncorrectedErrors)
+		}
+		html += `
+    <div class="section">
+        <h2>Memory Integrity Test</h2>
+        <table>
+            <tr><th>Result</th><td>` + result + `</td></tr>
+            <tr><th>Tested</th><td>` + humanReadableBytes(mt.TestSizeBytes) + fmt.Sprintf(", %d/%d passes, %.0fs", mt.PassesCompleted, mt.Passes, mt.DurationSeconds) + `</td></tr>
+            <tr><th>Patterns</th><td>` + strings.Join(mt.Patterns, ", ") + `</td></tr>
+            <tr><th>Mismatches</th><td>` + fmt.Sprintf("%d", mt.MismatchCount) + `</td></tr>
+            <tr><th>EDAC Errors</th><td>` + edac + `</td></tr>
+        </table>`
+		for _, delta := range mt.EDACDIMMDeltas {
+			html += `
+        <p class="warning">` + htmlpkg.EscapeString(delta) + `</p>`
+		}
+		if len(mt.Mismatches) > 0 {
+			html += `
+        <h3>Mismatches</h3>
+        <table>
+            <tr><th>Pass</th><th>Pattern</th><th>Virtual Address</th><th>Physical Address</th><th>Expected</th><th>Actual</th></tr>`
+			for _, m := range mt.Mismatches {
+				phys := "N/A"
+				if m.PhysicalAddress != 0 {
+					phys = fmt.Sprintf("0x%012x", m.PhysicalAddress)
+				}
+				html += `
+            <tr><td>` + fmt.Sprintf("%d", m.Pass) + `</td><td>` + m.Pattern + `</td><td>` + fmt.Sprintf("0x%012x", m.VirtualAddress) +
+					`</td><td>` + phys + `</td><td>` + fmt.Sprintf("0x%016x", m.Expected) + `</td><td class="warning">` + fmt.Sprintf("0x%016x", m.Actual) + `</td></tr>`
+			}
+			html += `
+        </table>`
+		}
+		html += `
+    </div>`
+	}
+
 	// Add Disk I/O Benchmark Results if available
 	if len(sysInfo.FioResults) > 0 {
 		html += `
     <div class="section">
-        <h2>Disk I/O Benchmark Results (FIO)</h2>`
+        <h2>Disk I/O Benchmark Results (FIO)</h2>` + fioEngineSupportHTML(sysInfo.FioEngines)
 
 		for _, device := range sysInfo.FioResults {
 			html += `
-        <h3>Device: ` + device.DeviceModel + `</h3>
-        <p>Mount Point: ` + device.MountPoint + `</p>
+        <h3>Device: ` + htmlpkg.EscapeString(device.DeviceModel) + `</h3>
+        <p>Mount Point: ` + htmlpkg.EscapeString(device.MountPoint) + `</p>
+        <p>Access Mode: ` + device.AccessMode + `</p>`
+			if device.StorageStack != "" {
+				html += `
+        <p>Storage Stack: ` + htmlpkg.EscapeString(device.StorageStack) + `</p>`
+			}
+			for _, disk := range device.PhysicalDisks {
+				if link, ok := pcieLinkFor(sysInfo.PCIeLinks, disk); ok {
+					html += `
+        <p>PCIe Link (` + disk + `): ` + pcieLinkStateHTML(link) + `</p>`
+				}
+			}
+			html += networkFilesystemHTML(device.Network)
+			html += `
         <p>Test File Size: ` + device.TestFileSize + `</p>
         <table>
-            <tr><th>Test</th><th>IOPS</th><th>Bandwidth (MB/s)</th><th>Latency</th></tr>`
+            <tr><th>Test</th><th>Parameters</th><th>IOPS</th><th>Bandwidth (MB/s)</th><th>Avg Latency</th><th>p50</th><th>p90</th><th>p99</th><th>p99.9</th><th>p99.99</th><th>Max</th></tr>`
 
 			for _, test := range device.TestResults {
 				iopsStr := "N/A"
@@ -4208,37 +4858,91 @@ func exportResultsToHTML(sysInfo SystemInfo, filePath string) error {
 					bwStr = fmt.Sprintf("%.2f", test.BandwidthMB)
 				}
 
-				latencyStr := "N/A"
-				if test.LatencyUs >= 0 {
-					if test.LatencyUnit == "ms" {
-						latencyStr = fmt.Sprintf("%.2f ms", test.LatencyUs)
-					} else {
-						latencyStr = fmt.Sprintf("%.2f us", test.LatencyUs)
-					}
-				}
-
 				html += `
             <tr>
-                <td>` + test.TestName + `</td>
+                <td>` + htmlpkg.EscapeString(fioTestLabel(test)) + `</td>
+                <td>` + fioTestParameters(test) + `</td>
                 <td class="highlight">` + iopsStr + `</td>
                 <td class="highlight">` + bwStr + `</td>
-                <td>` + latencyStr + `</td>
+                <td>` + formatLatencyNs(test.LatencyUs*1000) + `</td>` + fioPercentileCellsHTML(test) + `
             </tr>`
+
+				// Per-direction rows for mixed workloads
+				if directions := fioActiveDirections(test); len(directions) > 1 {
+					for _, dir := range directions {
+						html += `
+            <tr>
+                <td>&nbsp;&nbsp;&#8627; ` + dir.name + `</td>
+                <td></td>
+                <td>` + fmt.Sprintf("%.0f", dir.result.IOPS) + `</td>
+                <td>` + fmt.Sprintf("%.2f", dir.result.BandwidthMB) + `</td>
+                <td>` + formatLatencyNs(dir.result.Latency.Completion.MeanNs) + `</td>` +
+							fioPercentileCellsHTML(FioTestResult{Read: dir.result}) + `
+            </tr>`
+					}
+				}
 			}
 
 			html += `
-        </table>`
+        </table>` + fioEngineComparisonHTML(device, sysInfo.FioEngines) + fioSyncHTML(device) + fioBufferedHTML(device) + blockQueueHTML(device) + fioQDSweepHTML(device.QDSweep) + fioMixSweepHTML(device.MixSweep) + fioReplayHTML(device.Replay) + fioDiscardHTML(device.Discard) + metadataHTML(device.Metadata)
+
+			// Latency distribution charts
+			if chart := fioPercentileChartSVG(fioLatencySeriesForDevice(device)); chart != "" {
+				html += `
+        <h4>Completion Latency Distribution</h4>` + chart
+				for _, test := range device.TestResults {
+					for _, dir := range fioActiveDirections(test) {
+						latency := dir.result.Latency
+						completion, submission := latency.Completion, latency.Submission
+						if len(completion.Histogram) == 0 {
+							continue
+						}
+						html += `
+        <details>
+            <summary>` + htmlpkg.EscapeString(fioTestLabel(test)) + ` (` + dir.name + `) latency histogram</summary>
+            <p>Submission: mean ` + formatLatencyNs(submission.MeanNs) + `, max ` + formatLatencyNs(submission.MaxNs) +
+							` | Completion: mean ` + formatLatencyNs(completion.MeanNs) + `, stddev ` + formatLatencyNs(completion.StdDevNs) +
+							` | Total: mean ` + formatLatencyNs(latency.Total.MeanNs) + `, max ` + formatLatencyNs(latency.Total.MaxNs) + `</p>
+            ` + fioHistogramSVG(completion.Histogram) + `
+        </details>`
+					}
+				}
+			}
+
+			if ss := device.SteadyState; len(ss.Rounds) > 0 || ss.ErrorMessage != "" {
+				state := `<span class="highlight">Reached at round ` + fmt.Sprintf("%d", ss.SteadyStateRound) + `</span>`
+				if !ss.SteadyState {
+					state = `<span class="warning">Not reached</span>`
+				}
+				html += `
+        <h4>Steady State</h4>
+        <p>Workload: ` + ss.Workload + fmt.Sprintf(", engine %s, %d x %ds rounds after %d sequential fill passes (%.0fs)", ss.Engine, len(ss.Rounds), ss.RoundSeconds, ss.PreconditionPasses, ss.PreconditionSeconds) + `</p>
+        <table>
+            <tr><th>State</th><td>` + state + `</td></tr>
+            <tr><th>Window IOPS</th><td class="highlight">` + fmt.Sprintf("%.0f", ss.SteadyIOPS) + `</td></tr>
+            <tr><th>Window Bandwidth</th><td>` + fmt.Sprintf("%.2f MB/s", ss.SteadyBandwidthMB) + `</td></tr>
+            <tr><th>Window p99 Latency</th><td>` + formatLatencyNs(ss.SteadyP99LatencyNs) + `</td></tr>
+            <tr><th>Window Range / Slope</th><td>` + fmt.Sprintf("%.1f%% / %.1f%% (limits %.0f%% / %.0f%%)", ss.WindowRangePercent, ss.WindowSlopePercent, steadyStateMaxRangeRatio*100, steadyStateMaxSlopeRatio*100) + `</td></tr>
+        </table>` + fioSteadyStateSVG(ss)
+				if ss.ErrorMessage != "" {
+					html += `
+        <p class="warning">` + htmlpkg.EscapeString(ss.ErrorMessage) + `</p>`
+				}
+			}
 		}
 
-		html += `
+		html += driveHealthHTML(sysInfo.DriveHealth) + `
     </div>`
 	}
 
+	// Add parallel multi-device results if available
+	html += fioParallelHTML(sysInfo.FioParallelResults)
+
 	// Add Network Benchmark Results if available
 	if sysInfo.SpeedtestResults.TestCompleted || len(sysInfo.Iperf3Results) > 0 || len(sysInfo.NetblastResults) > 0 {
 		html += `
     <div class="section">
-        <h2>Network Benchmark Results</h2>`
+        <h2>Network Benchmark Results</h2>` + networkAdapterLinksHTML(sysInfo.PCIeLinks)
 
 		if sysInfo.SpeedtestResults.TestCompleted {
 			html += `
@@ -4444,6 +5148,133 @@ func exportResultsToHTML(sysInfo SystemInfo, filePath string) error {
 	return nil
 }
 
+// fioPercentileCellsHTML renders the p50..max completion latency cells of a test. For tests with
+// both reads and writes, read and write values are shown together as "read / write".
+func fioPercentileCellsHTML(test FioTestResult) string {
+	read, write := test.Read.Latency.Completion, test.Write.Latency.Completion
+	cells := ""
+	for _, pick := range []func(FioLatencyStats) float64{
+		func(s FioLatencyStats) float64 { return s.P50Ns },
+		func(s FioLatencyStats) float64 { return s.P90Ns },
+		func(s FioLatencyStats) float64 { return s.P99Ns },
+		func(s FioLatencyStats) float64 { return s.P999Ns },
+		func(s FioLatencyStats) float64 { return s.P9999Ns },
+		func(s FioLatencyStats) float64 { return s.MaxNs },
+	} {
+		value := "N/A"
+		switch {
+		case read.Samples > 0 && write.Samples > 0:
+			value = formatLatencyNs(pick(read)) + " / " + formatLatencyNs(pick(write))
+		case read.Samples > 0:
+			value = formatLatencyNs(pick(read))
+		case write.Samples > 0:
+			value = formatLatencyNs(pick(write))
+		}
+		cells += `<td>` + value + `</td>`
+	}
+	return cells
+}
+
+// printFioDirectionRows prints read/write sub-rows below a console table row for mixed workloads
+func printFioDirectionRows(indent string, test FioTestResult) {
+	directions := fioActiveDirections(test)
+	if len(directions) < 2 {
+		return
+	}
+	for _, dir := range directions {
+		fmt.Printf("%s  %-30s | %-10.0f | %-18.2f | %-12s | %-12s\n", indent, "- "+dir.name, dir.result.IOPS, dir.result.BandwidthMB,
+			formatLatencyNs(dir.result.Latency.Completion.MeanNs), formatLatencyNs(dir.result.Latency.Completion.P99Ns))
+	}
+}
+
+// fioCompletionP99Ns returns the worst 99th percentile completion latency over the directions of a test
+func fioCompletionP99Ns(test FioTestResult) float64 {
+	if test.LatencyUs < 0 {
+		return -1
+	}
+	p99 := 0.0
+	for _, dir := range fioActiveDirections(test) {
+		p99 = math.Max(p99, dir.result.Latency.Completion.P99Ns)
+	}
+	return p99
+}
+
+// fioTestParameters summarizes the fio job options of a test for reports
+func fioTestParameters(test FioTestResult) string {
+	params := fmt.Sprintf("%s, bs=%s, QD=%d, jobs=%d", test.ReadWrite, test.BlockSize, test.IODepth, test.NumJobs)
+	if fioMixedRW(test.ReadWrite) {
+		params += fmt.Sprintf(", read=%d%%", test.RWMixRead)
+	}
+	if test.RuntimeSec > 0 && test.RuntimeSec != fioDefaultRuntime {
+		params += fmt.Sprintf(", runtime=%ds", test.RuntimeSec)
+	}
+	if len(test.ExtraOptions) > 0 {
+		params += ", " + strings.Join(test.ExtraOptions, " ")
+	}
+	return params
+}
+
+// hardwareErrorsHTML renders the hardware error banner shown at the top of the HTML report
+func hardwareErrorsHTML(hw HardwareErrorReport) string {
+	if !hw.ErrorsDetected {
+		return ""
+	}
+	out := `
+
+    <div class="section error-banner">
+        <h2>Hardware Errors Detected During This Run</h2>
+        <p>The results below were measured while the hardware reported errors and should not be treated as a clean pass.</p>
+        <table>
+            <tr><th>Source</th><th>New Errors</th></tr>
+            <tr><td>EDAC Corrected</td><td>` + fmt.Sprintf("%d", hw.NewCorrected) + `</td></tr>
+            <tr><td>EDAC Uncorrected</td><td>` + fmt.Sprintf("%d", hw.NewUncorrected) + `</td></tr>
+            <tr><td>Machine Checks</td><td>` + fmt.Sprintf("%d", hw.NewMCEs) + `</td></tr>
+            <tr><td>Kernel Log Messages</td><td>` + fmt.Sprintf("%d", len(hw.KernelLogErrors)) + `</td></tr>
+        </table>`
+	for _, delta := range hw.EDACDIMMDeltas {
+		out += `
+        <p class="warning">` + htmlpkg.EscapeString(delta) + `</p>`
+	}
+	if len(hw.KernelLogErrors) > 0 {
+		out += `
+        <pre>` + htmlpkg.EscapeString(strings.Join(hw.KernelLogErrors, "\n")) + `</pre>`
+	}
+	out += `
+    </div>`
+	return out
+}
+
+// numaMatrixHTML renders one CPU-node x memory-node table for the HTML report
+func numaMatrixHTML(title string, nodes []int, matrix [][]float64, format string) string {
+	html := `
+        <h3>` + title + `</h3>
+        <table>
+            <tr><th>CPU Node / Memory Node</th>`
+	for _, node := range nodes {
+		html += `<th>Node ` + fmt.Sprintf("%d", node) + `</th>`
+	}
+	html += `</tr>`
+	for i, node := range nodes {
+		html += `
+            <tr><td>Node ` + fmt.Sprintf("%d", node) + `</td>`
+		for j := range nodes {
+			value := "N/A"
+			class := ""
+			if i < len(matrix) && j < len(matrix[i]) && matrix[i][j] >= 0 {
+				value = fmt.Sprintf(format, matrix[i][j])
+				if i == j {
+					class = ` class="highlight"`
+				}
+			}
+			html += `<td` + class + `>` + value + `</td>`
+		}
+		html += `</tr>`
+	}
+	html += `
+        </table>`
+	return html
+}
+
 func runPublicRefBenchmarks(sysInfo *SystemInfo) error {
 	fmt.Println("  Running Public Reference Benchmarks (UnixBench via Phoronix Test Suite)...")
 
@@ -4668,7 +5499,6 @@ func checkRoot() {
 func checkDependencies(attemptInstall bool) error {
 	requiredCommands := map[string]string{
 		"sysbench":    "sysbench",
-		"fio":         "fio",
 		"git":         "git",
 		"php":         "php-cli",
 		"php-xml":     "php-xml", // Special handling below
@@ -4736,6 +5566,18 @@ func checkDependencies(attemptInstall bool) error {
 		}
 	}
 
+	// fio is preferred for disk benchmarks, but the built-in engine can stand in for it
+	if _, err := exec.LookPath("fio"); err == nil {
+		fmt.Printf("    - Found: fio\n")
+	} else if attemptInstall && os.Geteuid() == 0 {
+		fmt.Printf("    - Missing: fio. Attempting to install...\n")
+		if installErr := attemptInstallPackage("fio"); installErr != nil {
+			fmt.Printf("      Failed to auto-install fio: %v. Disk benchmarks will use the built-in %s engine.\n", installErr, nativeDiskEngine)
+		}
+	} else {
+		fmt.Printf("    - Warning: fio not found. Disk benchmarks will use the built-in %s engine, whose results are not comparable with fio. Consider installing 'fio'.\n", nativeDiskEngine)
+	}
+
 	// Check for at least one speedtest tool
 	speedToolFound := false
 	var foundSpeedToolCmd string
@@ -4964,6 +5806,52 @@ func humanReadableBytes(b uint64) string {
 	return fmt.Sprintf("%.1f %ciB", float64(b)/float64(div), "KMGTPE"[exp])
 }
 
+// Helper to parse sizes like 512M, 4G or 1T into bytes (binary units; a plain number is bytes)
+func parseSizeBytes(s string) (uint64, error) {
+	s = strings.TrimSpace(strings.ToUpper(s))
+	s = strings.TrimSuffix(strings.TrimSuffix(s, "B"), "I") // Accept 4G, 4GB and 4GiB
+	multiplier := uint64(1)
+	if s != "" {
+		switch s[len(s)-1] {
+		case 'K':
+			multiplier = 1024
+		case 'M':
+			multiplier = 1024 * 1024
+		case 'G':
+			multiplier = 1024 * 1024 * 1024
+		case 'T':
+			multiplier = 1024 * 1024 * 1024 * 1024
+		}
+		if multiplier > 1 {
+			s = s[:len(s)-1]
+		}
+	}
+	value, err := strconv.ParseFloat(s, 64)
+	if err != nil || value < 0 {
+		return 0, fmt.Errorf("invalid size '%s'", s)
+	}
+	return uint64(value * float64(multiplier)), nil
+}
+
+// Helper to read a single-value sysfs/procfs file, returns "" if it cannot be read
+func readSysfsValue(path string) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+// Helper to extract the bracketed active choice from sysfs values like "always [madvise] never"
+func activeSysfsChoice(value string) string {
+	start := strings.Index(value, "[")
+	end := strings.Index(value, "]")
+	if start >= 0 && end > start {
+		return value[start+1 : end]
+	}
+	return value
+}
+
 // Helper to parse string to int, returns error if not a valid number
 func parseIntStrict(s string) (int, error) {
 	// Remove common units and trim spaces first
@@ -5106,351 +5994,98 @@ type MountPointInfo struct {
 	DeviceName string
 	MountPoint string
 	FSType     string
-	Size       uint64 // Size in bytes
-	Available  uint64 // Available space in bytes
+	Size       uint64   // Size in bytes
+	Available  uint64   // Available space in bytes
+	Disks      []string // Physical disks under the filesystem (kernel names)
 }
 
-// findBootDisk finds the disk that contains the root filesystem
-func findBootDisk() string {
-	// Get the device that contains the root filesystem
-	output, err := runCommand("findmnt", "-no", "SOURCE", "/")
-	if err != nil {
-		fmt.Printf("    Error finding root filesystem device: %v\n", err)
-		return ""
+// findBootDisks finds the physical disks that hold the root filesystem (several for RAID, btrfs or ZFS roots)
+func findBootDisks(topology *storageTopology) []string {
+	rootMount, ok := topology.mountFor("/")
+	if !ok {
+		return nil
 	}
-
-	rootDevice := strings.TrimSpace(output)
-	if rootDevice == "" {
-		return ""
+	var disks []string
+	for _, disk := range topology.mountDisks(rootMount) {
+		disks = append(disks, "/dev/"+disk)
 	}
-
-	// If it's an LVM volume, find the physical device
-	if strings.Contains(rootDevice, "mapper") {
-		// Get the volume group
-		vgOutput, err := runCommand("lvs", "--noheadings", "-o", "vg_name", rootDevice)
-		if err != nil {
-			return ""
-		}
-
-		volumeGroup := strings.TrimSpace(vgOutput)
-		if volumeGroup == "" {
-			return ""
-		}
-
-		// Get the physical volume(s) for this volume group
-		pvOutput, err := runCommand("pvs", "--noheadings", "-o", "pv_name", "-S", fmt.Sprintf("vg_name=%s", volumeGroup))
-		if err != nil {
-			return ""
-		}
-
-		// Just return the first physical volume
-		scanner := bufio.NewScanner(strings.NewReader(pvOutput))
-		if scanner.Scan() {
-			physicalVolume := strings.TrimSpace(scanner.Text())
-
-			// Get the disk that contains this partition
-			if strings.Contains(physicalVolume, "nvme") {
-				// For NVMe, the format is like /dev/nvme0n1p1, we want /dev/nvme0n1
-				re := regexp.MustCompile(`(/dev/nvme\d+n\d+)p?\d*`)
-				matches := re.FindStringSubmatch(physicalVolume)
-				if len(matches) > 1 {
-					return matches[1]
-				}
-			} else if strings.Contains(physicalVolume, "sd") {
-				// For SATA/SAS, the format is like /dev/sda1, we want /dev/sda
-				re := regexp.MustCompile(`(/dev/sd[a-z]+)\d*`)
-				matches := re.FindStringSubmatch(physicalVolume)
-				if len(matches) > 1 {
-					return matches[1]
-				}
-			}
-
-			return physicalVolume
-		}
-	} else {
-		// It's a regular partition, extract the disk
-		if strings.Contains(rootDevice, "nvme") {
-			// For NVMe, the format is like /dev/nvme0n1p1, we want /dev/nvme0n1
-			re := regexp.MustCompile(`(/dev/nvme\d+n\d+)p?\d*`)
-			matches := re.FindStringSubmatch(rootDevice)
-			if len(matches) > 1 {
-				return matches[1]
-			}
-		} else if strings.Contains(rootDevice, "sd") {
-			// For SATA/SAS, the format is like /dev/sda1, we want /dev/sda
-			re := regexp.MustCompile(`(/dev/sd[a-z]+)\d*`)
-			matches := re.FindStringSubmatch(rootDevice)
-			if len(matches) > 1 {
-				return matches[1]
-			}
-		}
-	}
-
-	return ""
+	return disks
 }
 
 // isSafeForDirectTesting checks if a device is safe for direct testing
-func isSafeForDirectTesting(devicePath string) (bool, string) {
+func isSafeForDirectTesting(topology *storageTopology, devicePath string) (bool, string) {
 	// Check if the device exists
-	if _, err := os.Stat(devicePath); os.IsNotExist(err) {
+	name := topology.deviceName(devicePath)
+	if name == "" {
 		return false, "Device does not exist"
 	}
 
-	// Check if the device is mounted
-	output, err := runCommand("lsblk", "-pno", "NAME,MOUNTPOINT", devicePath)
-	if err != nil {
-		return false, fmt.Sprintf("Error checking if device is mounted: %v", err)
-	}
-
-	// Parse the output to check if any partition is mounted
-	scanner := bufio.NewScanner(strings.NewReader(output))
-	for scanner.Scan() {
-		line := scanner.Text()
-		if line == "" {
-			continue
-		}
-
-		fields := strings.Fields(line)
-		if len(fields) >= 2 && fields[1] != "" && fields[1] != "null" {
-			// This device or one of its partitions is mounted
-			return false, fmt.Sprintf("Device or partition is mounted at %s", fields[1])
-		}
-	}
-
-	// Check if the device is part of an LVM setup
-	pvOutput, err := runCommand("pvs", "--noheadings", "-o", "pv_name,vg_name")
-	if err == nil {
-		// Parse PVs to check if this device is a physical volume
-		pvScanner := bufio.NewScanner(strings.NewReader(pvOutput))
-		for pvScanner.Scan() {
-			line := pvScanner.Text()
-			fields := strings.Fields(line)
-			if len(fields) >= 2 && strings.Contains(fields[0], devicePath) {
-				// This device is part of an LVM setup
-				return false, fmt.Sprintf("Device is part of volume group %s", fields[1])
-			}
-		}
+	// Anything stacked on the device or its partitions (mounts, LVM, LUKS, md, ZFS, swap) makes it unsafe
+	if users := topology.deviceUsers(name); len(users) > 0 {
+		return false, "Device is in use: " + strings.Join(users, ", ")
 	}
 
 	// Check if the device is a boot disk
-	bootDisk := findBootDisk()
-	if bootDisk == devicePath {
-		return false, "Device is the boot disk"
+	for _, bootDisk := range findBootDisks(topology) {
+		if bootDisk == "/dev/"+name {
+			return false, "Device is the boot disk"
+		}
 	}
 
 	// All checks passed, device is safe for direct testing
 	return true, ""
 }
 
-// findBestMountPoint finds the best mount point for a device
-func findBestMountPoint(devicePath string) MountPointInfo {
-	// Get all mount points for this device
-	mountPoints := findMountPointsForDevice(devicePath)
-	if len(mountPoints) == 0 {
-		return MountPointInfo{}
-	}
-
-	// If there's only one mount point, return it
-	if len(mountPoints) == 1 {
-		return mountPoints[0]
-	}
-
-	// Find the mount point with the most available space
+// findBestMountPoint finds the mount point with the most free space on a device, excluding the root filesystem
+func findBestMountPoint(topology *storageTopology, devicePath string) MountPointInfo {
 	var bestMountPoint MountPointInfo
-	var maxAvailable uint64
-
-	for _, mp := range mountPoints {
+	for _, mp := range findMountPointsForDevice(topology, devicePath) {
 		// Skip root filesystem
 		if mp.MountPoint == "/" {
 			continue
 		}
-
-		// Skip special filesystems
-		if mp.FSType == "tmpfs" || mp.FSType == "devtmpfs" || mp.FSType == "sysfs" || mp.FSType == "proc" {
-			continue
-		}
-
-		// Get available space
-		output, err := runCommand("df", "--output=avail", "-B1", mp.MountPoint)
-		if err != nil {
-			continue
-		}
-
-		// Parse available space
-		scanner := bufio.NewScanner(strings.NewReader(output))
-		scanner.Scan() // Skip header
-		if scanner.Scan() {
-			availStr := strings.TrimSpace(scanner.Text())
-			available, err := strconv.ParseUint(availStr, 10, 64)
-			if err != nil {
-				continue
-			}
-
-			if available > maxAvailable {
-				maxAvailable = available
-				bestMountPoint = mp
-				bestMountPoint.Available = available
-			}
+		if mp.Available > bestMountPoint.Available {
+			bestMountPoint = mp
 		}
 	}
-
 	return bestMountPoint
 }
 
-// findMountPointsForDevice finds all mount points for a given device
-// This handles both direct mounts and mounts of partitions/LVs
-func findMountPointsForDevice(devicePath string) []MountPointInfo {
+// findMountPointsForDevice finds all mounted filesystems that live on a disk, whether directly,
+// on a partition, or through LVM, LUKS, md RAID, btrfs or ZFS
+func findMountPointsForDevice(topology *storageTopology, devicePath string) []MountPointInfo {
 	var results []MountPointInfo
 
-	// First check if the device itself is mounted
-	output, err := runCommand("lsblk", "-pno", "NAME,MOUNTPOINT,FSTYPE,SIZE", devicePath)
-	if err != nil {
-		fmt.Printf("      Error checking mount points for %s: %v\n", devicePath, err)
+	name := topology.deviceName(devicePath)
+	if name == "" {
 		return results
 	}
-
-	// Parse the output to find mount points
-	scanner := bufio.NewScanner(strings.NewReader(output))
-	for scanner.Scan() {
-		line := scanner.Text()
-		if line == "" {
-			continue
-		}
-
-		fields := strings.Fields(line)
-		if len(fields) >= 2 {
-			devPath := fields[0]
-			mountPoint := ""
-			if len(fields) >= 2 {
-				mountPoint = fields[1]
-			}
-			fsType := ""
-			if len(fields) >= 3 {
-				fsType = fields[2]
-			}
-
-			// Skip non-mounted devices
-			if mountPoint == "" || mountPoint == "null" {
-				continue
-			}
-
-			// Parse size if available
-			var size uint64
-			if len(fields) >= 4 {
-				sizeStr := fields[3]
-				// Convert size string to bytes
-				if strings.HasSuffix(sizeStr, "G") {
-					sizeGB, err := strconv.ParseFloat(sizeStr[:len(sizeStr)-1], 64)
-					if err == nil {
-						size = uint64(sizeGB * 1024 * 1024 * 1024)
-					}
-				} else if strings.HasSuffix(sizeStr, "M") {
-					sizeMB, err := strconv.ParseFloat(sizeStr[:len(sizeStr)-1], 64)
-					if err == nil {
-						size = uint64(sizeMB * 1024 * 1024)
-					}
-				} else if strings.HasSuffix(sizeStr, "K") {
-					sizeKB, err := strconv.ParseFloat(sizeStr[:len(sizeStr)-1], 64)
-					if err == nil {
-						size = uint64(sizeKB * 1024)
-					}
-				} else {
-					size, _ = strconv.ParseUint(sizeStr, 10, 64)
-				}
+	for _, m := range topology.mounts {
+		disks := topology.mountDisks(m)
+		onDevice := false
+		for _, disk := range disks {
+			if disk == name {
+				onDevice = true
+				break
 			}
-
-			// Add to results
-			results = append(results, MountPointInfo{
-				DevicePath: devPath,
-				DeviceName: devPath,
-				MountPoint: mountPoint,
-				FSType:     fsType,
-				Size:       size,
-			})
 		}
-	}
-
-	// If no direct mounts found, check for LVM volumes that might be using this device
-	if len(results) == 0 {
-		// Try to find if this device is part of an LVM setup
-		pvOutput, err := runCommand("pvs", "--noheadings", "-o", "pv_name,vg_name")
-		if err == nil {
-			// Parse PVs to find the volume group
-			var volumeGroup string
-			pvScanner := bufio.NewScanner(strings.NewReader(pvOutput))
-			for pvScanner.Scan() {
-				line := pvScanner.Text()
-				fields := strings.Fields(line)
-				if len(fields) >= 2 && strings.Contains(fields[0], devicePath) {
-					volumeGroup = fields[1]
-					break
-				}
-			}
-
-			// If we found a volume group, check for logical volumes
-			if volumeGroup != "" {
-				lvOutput, err := runCommand("lvs", "--noheadings", "-o", "lv_name,lv_path,lv_size", volumeGroup)
-				if err == nil {
-					// For each logical volume, check if it's mounted
-					lvScanner := bufio.NewScanner(strings.NewReader(lvOutput))
-					for lvScanner.Scan() {
-						line := lvScanner.Text()
-						fields := strings.Fields(line)
-						if len(fields) >= 2 {
-							lvName := fields[0]
-							lvPath := fields[1]
-
-							// Parse size if available
-							var size uint64
-							if len(fields) >= 3 {
-								sizeStr := fields[2]
-								// Convert size string to bytes
-								if strings.HasSuffix(sizeStr, "g") {
-									sizeGB, err := strconv.ParseFloat(sizeStr[:len(sizeStr)-1], 64)
-									if err == nil {
-										size = uint64(sizeGB * 1024 * 1024 * 1024)
-									}
-								} else if strings.HasSuffix(sizeStr, "m") {
-									sizeMB, err := strconv.ParseFloat(sizeStr[:len(sizeStr)-1], 64)
-									if err == nil {
-										size = uint64(sizeMB * 1024 * 1024)
-									}
-								} else if strings.HasSuffix(sizeStr, "k") {
-									sizeKB, err := strconv.ParseFloat(sizeStr[:len(sizeStr)-1], 64)
-									if err == nil {
-										size = uint64(sizeKB * 1024)
-									}
-								} else {
-									size, _ = strconv.ParseUint(sizeStr, 10, 64)
-								}
-							}
+		if !onDevice {
+			continue
+		}
 
-							// Check if this LV is mounted
-							mountOutput, err := runCommand("findmnt", "-no", "TARGET,FSTYPE", lvPath)
-							if err == nil && mountOutput != "" {
-								mountFields := strings.Fields(mountOutput)
-								if len(mountFields) >= 1 {
-									mountPoint := mountFields[0]
-									fsType := ""
-									if len(mountFields) >= 2 {
-										fsType = mountFields[1]
-									}
-
-									// Add to results
-									results = append(results, MountPointInfo{
-										DevicePath: lvPath,
-										DeviceName: fmt.Sprintf("%s/%s", volumeGroup, lvName),
-										MountPoint: mountPoint,
-										FSType:     fsType,
-										Size:       size,
-									})
-								}
-							}
-						}
-					}
-				}
-			}
+		size, available, err := filesystemSpace(m.mountPoint)
+		if err != nil {
+			continue
 		}
+		results = append(results, MountPointInfo{
+			DevicePath: m.source,
+			DeviceName: topology.mountStack(m),
+			MountPoint: m.mountPoint,
+			FSType:     m.fsType,
+			Size:       size,
+			Available:  available,
+			Disks:      disks,
+		})
 	}
 
 	return results
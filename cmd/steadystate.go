@@ -0,0 +1,322 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Steady-state criteria from the SNIA Solid State Storage Performance Test Specification:
+// over a window of rounds the data excursion must stay within 20% of the window average
+// and the excursion of the best-fit line within 10% of it.
+const (
+	steadyStateWindow         = 5
+	steadyStateMaxRangeRatio  = 0.20
+	steadyStateMaxSlopeRatio  = 0.10
+	steadyStatePreconditionBS = "128k"
+	steadyStateWorkloadBS     = "4k"
+	steadyStateIODepth        = 32
+	steadyStateNumJobs        = 4
+)
+
+// FioSteadyStateResult holds the preconditioning and steady-state convergence data for one target
+type FioSteadyStateResult struct {
+	Workload            string                // Workload measured in each round
+	Engine              string                // fio engine label (e.g., libaio, io_uring+fixedbufs)
+	PreconditionPasses  int                   // Sequential fill passes over the target
+	PreconditionSeconds float64               // Time spent on the sequential fill
+	RoundSeconds        int                   // Duration of each round
+	Rounds              []FioSteadyStateRound // Per-round measurements, in order
+	WindowRounds        int                   // Number of rounds in the steady-state window
+	SteadyState         bool                  // Whether the criteria were met
+	SteadyStateRound    int                   // Last round of the window where steady state was reached
+	SteadyIOPS          float64               // Average IOPS over the steady-state window
+	SteadyBandwidthMB   float64               // Average bandwidth over the window
+	SteadyP99LatencyNs  float64               // Average p99 completion latency over the window
+	WindowRangePercent  float64               // Max-min of the final window, as a percentage of its average
+	WindowSlopePercent  float64               // Best-fit line excursion of the final window, as a percentage of its average
+	ErrorMessage        string                // Error message if preconditioning or a round failed
+}
+
+// FioSteadyStateRound holds one measurement round
+type FioSteadyStateRound struct {
+	Round         int       // 1-based round number
+	IOPS          float64   // Average write IOPS over the round
+	BandwidthMB   float64   // Average write bandwidth over the round
+	MeanLatencyNs float64   // Mean completion latency
+	P99LatencyNs  float64   // 99th percentile completion latency
+	PerSecondIOPS []float64 // IOPS logged by fio every second (all jobs summed)
+}
+
+// runFioSteadyState preconditions testFilePath with sequential fills, then runs random write rounds
+// until the IOPS converge or the round limit is reached
+func runFioSteadyState(testFilePath, testSize string, engine fioEngine, baseArgs []string) FioSteadyStateResult {
+	baseArgs = append(append([]string{}, baseArgs...), fioEngineArgs(engine)...)
+	result := FioSteadyStateResult{
+		Workload: fmt.Sprintf("%s randwrite, QD=%d, %d jobs", strings.ToUpper(steadyStateWorkloadBS),
+			steadyStateIODepth, steadyStateNumJobs),
+		Engine:             engine.label,
+		PreconditionPasses: 2,
+		RoundSeconds:       fioSteadyStateRound,
+		WindowRounds:       steadyStateWindow,
+	}
+
+	if fioSteadyStateRound < 1 || fioSteadyStateMaxRounds < steadyStateWindow {
+		result.ErrorMessage = fmt.Sprintf("Rounds must be at least 1s long and at least %d rounds are needed", steadyStateWindow)
+		fmt.Printf("      %s\n", result.ErrorMessage)
+		return result
+	}
+
+	fmt.Printf("      Preconditioning: %d sequential %s fill passes over %s...\n",
+		result.PreconditionPasses, steadyStatePreconditionBS, testSize)
+	start := time.Now()
+	preconditionArgs := append(append([]string{}, baseArgs...),
+		"--name=precondition_seq_fill",
+		"--rw=write",
+		"--bs="+steadyStatePreconditionBS,
+		"--iodepth=32",
+		"--numjobs=1",
+		"--size="+testSize,
+		fmt.Sprintf("--loops=%d", result.PreconditionPasses),
+		"--output-format=json",
+	)
+	if _, err := runFioJob(preconditionArgs); err != nil {
+		result.ErrorMessage = fmt.Sprintf("Sequential preconditioning failed: %v", err)
+		fmt.Printf("        %s\n", result.ErrorMessage)
+		return result
+	}
+	result.PreconditionSeconds = time.Since(start).Seconds()
+	fmt.Printf("        Sequential fill finished in %s\n", time.Duration(result.PreconditionSeconds*float64(time.Second)).Round(time.Second))
+
+	logDir, err := os.MkdirTemp("", "hyprbench_steady_")
+	if err != nil {
+		result.ErrorMessage = fmt.Sprintf("Could not create IOPS log directory: %v", err)
+		return result
+	}
+	defer os.RemoveAll(logDir)
+
+	fmt.Printf("      Steady-state rounds: %s (engine=%s), %ds each, up to %d rounds\n", result.Workload, result.Engine, fioSteadyStateRound, fioSteadyStateMaxRounds)
+	for round := 1; round <= fioSteadyStateMaxRounds; round++ {
+		logPrefix := filepath.Join(logDir, fmt.Sprintf("round%d", round))
+		roundArgs := append(append([]string{}, baseArgs...),
+			fmt.Sprintf("--name=steady_state_round_%d", round),
+			"--rw=randwrite",
+			"--bs="+steadyStateWorkloadBS,
+			fmt.Sprintf("--iodepth=%d", steadyStateIODepth),
+			fmt.Sprintf("--numjobs=%d", steadyStateNumJobs),
+			"--size="+testSize,
+			"--time_based",
+			fmt.Sprintf("--runtime=%d", fioSteadyStateRound),
+			"--norandommap",
+			"--randrepeat=0",
+			"--group_reporting",
+			"--write_iops_log="+logPrefix,
+			"--log_avg_msec=1000",
+			"--output-format=json",
+		)
+		job, err := runFioJob(roundArgs)
+		if err != nil {
+			result.ErrorMessage = fmt.Sprintf("Round %d failed: %v", round, err)
+			fmt.Printf("        %s\n", result.ErrorMessage)
+			break
+		}
+
+		var write FioDirectionResult
+		if stats, ok := job["write"].(map[string]interface{}); ok {
+			write = parseFioDirection(stats)
+		}
+		roundResult := FioSteadyStateRound{
+			Round:         round,
+			IOPS:          write.IOPS,
+			BandwidthMB:   write.BandwidthMB,
+			MeanLatencyNs: write.Latency.Completion.MeanNs,
+			P99LatencyNs:  write.Latency.Completion.P99Ns,
+			PerSecondIOPS: readFioIOPSLogs(logPrefix),
+		}
+		result.Rounds = append(result.Rounds, roundResult)
+
+		rangePct, slopePct, reached := steadyStateWindowCheck(result.Rounds)
+		status := ""
+		if len(result.Rounds) >= steadyStateWindow {
+			status = fmt.Sprintf(" (window range %.1f%%, slope %.1f%%)", rangePct, slopePct)
+		}
+		fmt.Printf("        Round %2d: %.0f IOPS, p99 %s%s\n", round, roundResult.IOPS, formatLatencyNs(roundResult.P99LatencyNs), status)
+
+		if len(result.Rounds) >= steadyStateWindow {
+			result.WindowRangePercent, result.WindowSlopePercent = rangePct, slopePct
+		}
+		if reached {
+			result.SteadyState = true
+			result.SteadyStateRound = round
+			break
+		}
+	}
+
+	// Report the last window whether or not it converged, so the numbers are still comparable
+	window := result.Rounds
+	if len(window) > steadyStateWindow {
+		window = window[len(window)-steadyStateWindow:]
+	}
+	for _, r := range window {
+		result.SteadyIOPS += r.IOPS / float64(len(window))
+		result.SteadyBandwidthMB += r.BandwidthMB / float64(len(window))
+		result.SteadyP99LatencyNs += r.P99LatencyNs / float64(len(window))
+	}
+
+	if result.SteadyState {
+		fmt.Printf("      %sSteady state reached at round %d: %.0f IOPS, %.2f MB/s, p99 %s%s\n", colorGreen,
+			result.SteadyStateRound, result.SteadyIOPS, result.SteadyBandwidthMB, formatLatencyNs(result.SteadyP99LatencyNs), colorReset)
+	} else if len(result.Rounds) > 0 {
+		fmt.Printf("      %sSteady state NOT reached after %d rounds; last-window average %.0f IOPS%s\n", colorYellow,
+			len(result.Rounds), result.SteadyIOPS, colorReset)
+	}
+	return result
+}
+
+// steadyStateWindowCheck evaluates the SNIA criteria over the last steadyStateWindow rounds.
+// It returns the range and best-fit excursion as percentages of the window average.
+func steadyStateWindowCheck(rounds []FioSteadyStateRound) (rangePct, slopePct float64, reached bool) {
+	if len(rounds) < steadyStateWindow {
+		return 0, 0, false
+	}
+	window := rounds[len(rounds)-steadyStateWindow:]
+
+	var sumX, sumY, sumXY, sumXX float64
+	minY, maxY := math.MaxFloat64, 0.0
+	for i, r := range window {
+		x := float64(i)
+		sumX += x
+		sumY += r.IOPS
+		sumXY += x * r.IOPS
+		sumXX += x * x
+		minY = math.Min(minY, r.IOPS)
+		maxY = math.Max(maxY, r.IOPS)
+	}
+	n := float64(len(window))
+	avg := sumY / n
+	if avg <= 0 {
+		return 0, 0, false
+	}
+	slope := (n*sumXY - sumX*sumY) / (n*sumXX - sumX*sumX)
+
+	rangePct = (maxY - minY) / avg * 100
+	slopePct = math.Abs(slope) * (n - 1) / avg * 100
+	reached = rangePct <= steadyStateMaxRangeRatio*100 && slopePct <= steadyStateMaxSlopeRatio*100
+	return rangePct, slopePct, reached
+}
+
+// readFioIOPSLogs sums the per-job IOPS logs written by --write_iops_log into one value per interval.
+// fio writes one file per job (prefix_iops.1.log, prefix_iops.2.log, ...) with "msec, value, ..." lines.
+func readFioIOPSLogs(prefix string) []float64 {
+	files, _ := filepath.Glob(prefix + "_iops*.log")
+	sums := make(map[int64]float64)
+	for _, path := range files {
+		file, err := os.Open(path)
+		if err != nil {
+			continue
+		}
+		scanner := bufio.NewScanner(file)
+		for scanner.Scan() {
+			fields := strings.Split(scanner.Text(), ",")
+			if len(fields) < 2 {
+				continue
+			}
+			msec, err1 := strconv.ParseInt(strings.TrimSpace(fields[0]), 10, 64)
+			value, err2 := strconv.ParseFloat(strings.TrimSpace(fields[1]), 64)
+			if err1 != nil || err2 != nil {
+				continue
+			}
+			// Jobs log at slightly different times; bucket to the nearest second
+			sums[(msec+500)/1000] += value
+		}
+		file.Close()
+	}
+
+	seconds := make([]int64, 0, len(sums))
+	for second := range sums {
+		seconds = append(seconds, second)
+	}
+	sort.Slice(seconds, func(i, j int) bool { return seconds[i] < seconds[j] })
+	samples := make([]float64, 0, len(seconds))
+	for _, second := range seconds {
+		samples = append(samples, sums[second])
+	}
+	return samples
+}
+
+// fioSteadyStateSVG plots the per-second IOPS of every round with the round averages and
+// highlights the steady-state window
+func fioSteadyStateSVG(ss FioSteadyStateResult) string {
+	if len(ss.Rounds) == 0 {
+		return ""
+	}
+	const width, height = 760.0, 260.0
+	const left, right, top, bottom = 70.0, 20.0, 20.0, 40.0
+	plotW, plotH := width-left-right, height-top-bottom
+
+	maxIOPS := 0.0
+	for _, r := range ss.Rounds {
+		maxIOPS = math.Max(maxIOPS, r.IOPS)
+		for _, v := range r.PerSecondIOPS {
+			maxIOPS = math.Max(maxIOPS, v)
+		}
+	}
+	if maxIOPS == 0 {
+		return ""
+	}
+	maxIOPS *= 1.1
+	roundW := plotW / float64(len(ss.Rounds))
+	y := func(v float64) float64 { return top + plotH - v/maxIOPS*plotH }
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `
+        <svg width="%.0f" height="%.0f" xmlns="http://www.w3.org/2000/svg" style="font-family: Arial, sans-serif; font-size: 11px;">`, width, height)
+
+	// Steady-state window
+	windowEnd := len(ss.Rounds)
+	if ss.SteadyState {
+		windowEnd = ss.SteadyStateRound
+	}
+	if windowStart := windowEnd - steadyStateWindow; windowStart >= 0 {
+		fill := "#fdebd0"
+		if ss.SteadyState {
+			fill = "#d5f5e3"
+		}
+		fmt.Fprintf(&b, `<rect x="%.1f" y="%.1f" width="%.1f" height="%.1f" fill="%s"/>`,
+			left+float64(windowStart)*roundW, top, float64(steadyStateWindow)*roundW, plotH, fill)
+	}
+
+	// Axes
+	for i := 0; i <= 4; i++ {
+		v := maxIOPS * float64(i) / 4
+		fmt.Fprintf(&b, `<line x1="%.1f" y1="%.1f" x2="%.1f" y2="%.1f" stroke="#ddd"/><text x="%.1f" y="%.1f" text-anchor="end">%.0f</text>`,
+			left, y(v), left+plotW, y(v), left-6, y(v)+4, v)
+	}
+	for i, r := range ss.Rounds {
+		cx := left + (float64(i)+0.5)*roundW
+		fmt.Fprintf(&b, `<text x="%.1f" y="%.1f" text-anchor="middle">%d</text>`, cx, top+plotH+16, r.Round)
+	}
+	fmt.Fprintf(&b, `<text x="%.1f" y="%.1f" text-anchor="middle">Round (per-second IOPS and round average)</text>`, left+plotW/2, top+plotH+32)
+
+	// Per-second samples and round averages
+	var samplePoints []string
+	for i, r := range ss.Rounds {
+		for j, v := range r.PerSecondIOPS {
+			sx := left + (float64(i)+float64(j)/float64(len(r.PerSecondIOPS)))*roundW
+			samplePoints = append(samplePoints, fmt.Sprintf("%.1f,%.1f", sx, y(v)))
+		}
+		fmt.Fprintf(&b, `<line x1="%.1f" y1="%.1f" x2="%.1f" y2="%.1f" stroke="#e74c3c" stroke-width="2"><title>Round %d: %.0f IOPS</title></line>`,
+			left+float64(i)*roundW, y(r.IOPS), left+float64(i+1)*roundW, y(r.IOPS), r.Round, r.IOPS)
+	}
+	if len(samplePoints) > 0 {
+		fmt.Fprintf(&b, `<polyline fill="none" stroke="#3498db" stroke-width="1" points="%s"/>`, strings.Join(samplePoints, " "))
+	}
+	b.WriteString(`</svg>`)
+	return b.String()
+}
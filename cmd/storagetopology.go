@@ -0,0 +1,443 @@
+package cmd
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// blockDevice is one node of the kernel block device graph in /sys/class/block
+type blockDevice struct {
+	name       string   // Kernel name (e.g., nvme0n1p2, dm-0, md127)
+	devNum     string   // "major:minor"
+	kind       string   // disk, partition, lvm, crypt, multipath, dm, md, loop or zram
+	label      string   // device-mapper name or RAID level (e.g., vg0-root, luks-1234, raid1)
+	parent     string   // Whole disk of a partition
+	partitions []string // Partitions of a disk
+	slaves     []string // Devices this one is built on (dm, md)
+	holders    []string // Devices built on this one
+}
+
+// mountEntry is one line of /proc/self/mountinfo
+type mountEntry struct {
+	devNum     string // "major:minor" of the filesystem (0:N for btrfs, ZFS and network filesystems)
+	mountPoint string
+	fsType     string
+	source     string // Mount source (e.g., /dev/mapper/vg0-root, tank/data)
+	options    string // Per-mount options followed by the superblock options (e.g., rw,relatime,vers=4.2,rsize=1048576)
+}
+
+// pseudoFilesystems are mount table filesystem types with no storage behind them to benchmark
+var pseudoFilesystems = map[string]bool{
+	"tmpfs": true, "devtmpfs": true, "ramfs": true, "sysfs": true, "proc": true, "devpts": true,
+	"cgroup": true, "cgroup2": true, "mqueue": true, "debugfs": true, "tracefs": true, "securityfs": true,
+	"pstore": true, "bpf": true, "configfs": true, "fusectl": true, "hugetlbfs": true, "autofs": true,
+	"binfmt_misc": true, "efivarfs": true, "nsfs": true, "rpc_pipefs": true, "squashfs": true,
+}
+
+// storageTopology resolves mounts to block devices and block devices down to physical disks
+type storageTopology struct {
+	devices  map[string]*blockDevice // By kernel name
+	byDevNum map[string]string       // "major:minor" to kernel name
+	mounts   []mountEntry            // In mount order
+	swaps    []string                // Kernel names of active swap devices
+	zfsPools map[string][]string     // Pool name to member kernel names (needs zpool)
+}
+
+// loadStorageTopology reads the block device graph from sysfs and the mount table from procfs.
+// Missing pieces (e.g., no zpool command) leave the corresponding parts empty.
+func loadStorageTopology() *storageTopology {
+	t := &storageTopology{
+		devices:  make(map[string]*blockDevice),
+		byDevNum: make(map[string]string),
+		zfsPools: make(map[string][]string),
+	}
+
+	entries, _ := os.ReadDir("/sys/class/block")
+	for _, entry := range entries {
+		name := entry.Name()
+		sysDir := filepath.Join("/sys/class/block", name)
+		dev := &blockDevice{name: name, devNum: readSysfsValue(filepath.Join(sysDir, "dev")), kind: "disk"}
+		switch {
+		case fileExists(filepath.Join(sysDir, "partition")):
+			dev.kind = "partition"
+			// /sys/class/block/nvme0n1p1 links into the parent disk's directory
+			if resolved, err := filepath.EvalSymlinks(sysDir); err == nil {
+				dev.parent = filepath.Base(filepath.Dir(resolved))
+			}
+		case fileExists(filepath.Join(sysDir, "dm")):
+			dev.label = readSysfsValue(filepath.Join(sysDir, "dm/name"))
+			uuid := readSysfsValue(filepath.Join(sysDir, "dm/uuid"))
+			switch {
+			case strings.HasPrefix(uuid, "LVM-"):
+				dev.kind = "lvm"
+			case strings.HasPrefix(uuid, "CRYPT-"):
+				dev.kind = "crypt"
+			case strings.HasPrefix(uuid, "mpath-"):
+				dev.kind = "multipath"
+			default:
+				dev.kind = "dm"
+			}
+		case fileExists(filepath.Join(sysDir, "md")):
+			dev.kind = "md"
+			dev.label = readSysfsValue(filepath.Join(sysDir, "md/level"))
+		case strings.HasPrefix(name, "loop"):
+			dev.kind = "loop"
+		case strings.HasPrefix(name, "zram"):
+			dev.kind = "zram"
+		}
+		dev.slaves = readDirNames(filepath.Join(sysDir, "slaves"))
+		dev.holders = readDirNames(filepath.Join(sysDir, "holders"))
+		t.devices[name] = dev
+		if dev.devNum != "" {
+			t.byDevNum[dev.devNum] = name
+		}
+	}
+	for _, dev := range t.devices {
+		if parent, ok := t.devices[dev.parent]; ok && dev.kind == "partition" {
+			parent.partitions = append(parent.partitions, dev.name)
+		}
+	}
+	for _, dev := range t.devices {
+		sort.Strings(dev.partitions)
+	}
+
+	t.mounts = readMountInfo("/proc/self/mountinfo")
+
+	if f, err := os.Open("/proc/swaps"); err == nil {
+		scanner := bufio.NewScanner(f)
+		scanner.Scan() // Skip header
+		for scanner.Scan() {
+			if fields := strings.Fields(scanner.Text()); len(fields) > 0 {
+				if name := t.deviceName(fields[0]); name != "" {
+					t.swaps = append(t.swaps, name)
+				}
+			}
+		}
+		f.Close()
+	}
+
+	// ZFS vdevs are not visible in sysfs; ask zpool when any pool could exist
+	if output, err := runCommand("zpool", "status", "-LP"); err == nil {
+		var pool string
+		scanner := bufio.NewScanner(strings.NewReader(output))
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if p, ok := strings.CutPrefix(line, "pool:"); ok {
+				pool = strings.TrimSpace(p)
+				continue
+			}
+			if fields := strings.Fields(line); pool != "" && len(fields) > 0 && strings.HasPrefix(fields[0], "/dev/") {
+				if name := t.deviceName(fields[0]); name != "" {
+					t.zfsPools[pool] = append(t.zfsPools[pool], name)
+				}
+			}
+		}
+	}
+	return t
+}
+
+// readMountInfo parses a mountinfo file
+func readMountInfo(path string) []mountEntry {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	var mounts []mountEntry
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		// 36 35 98:0 /mnt1 /mnt2 rw,noatime master:1 - ext3 /dev/root rw,errors=continue
+		left, right, ok := strings.Cut(scanner.Text(), " - ")
+		if !ok {
+			continue
+		}
+		fields, fsFields := strings.Fields(left), strings.Fields(right)
+		if len(fields) < 6 || len(fsFields) < 2 {
+			continue
+		}
+		entry := mountEntry{
+			devNum:     fields[2],
+			mountPoint: unescapeMountPath(fields[4]),
+			fsType:     fsFields[0],
+			source:     unescapeMountPath(fsFields[1]),
+		}
+		// Like /proc/mounts: the options of this mount, then those of the filesystem it shows
+		options := strings.Split(fields[5], ",")
+		if len(fsFields) > 2 {
+			for _, option := range strings.Split(fsFields[2], ",") {
+				if !slices.Contains(options, option) {
+					options = append(options, option)
+				}
+			}
+		}
+		entry.options = strings.Join(options, ",")
+		mounts = append(mounts, entry)
+	}
+	return mounts
+}
+
+// unescapeMountPath decodes the octal escapes (\040 for space) the kernel uses in mount tables
+func unescapeMountPath(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+3 < len(s) {
+			if code, err := strconv.ParseUint(s[i+1:i+4], 8, 8); err == nil {
+				b.WriteByte(byte(code))
+				i += 3
+				continue
+			}
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+// readDirNames lists a directory, returning nil if it does not exist
+func readDirNames(dir string) []string {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	return names
+}
+
+// deviceName maps a device path (/dev/sda1, /dev/mapper/vg0-root, /dev/disk/by-id/...) to its kernel name
+func (t *storageTopology) deviceName(path string) string {
+	resolved, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		resolved = path
+	}
+	name := filepath.Base(resolved)
+	if _, ok := t.devices[name]; ok {
+		return name
+	}
+	return ""
+}
+
+// physicalDisks follows partitions, device-mapper (LVM, LUKS, multipath) and md RAID down to whole disks
+func (t *storageTopology) physicalDisks(name string) []string {
+	seen := make(map[string]bool)
+	var disks []string
+	var walk func(string)
+	walk = func(name string) {
+		dev, ok := t.devices[name]
+		if !ok || seen[name] {
+			return
+		}
+		seen[name] = true
+		switch {
+		case dev.kind == "partition":
+			walk(dev.parent)
+		case len(dev.slaves) > 0:
+			for _, slave := range dev.slaves {
+				walk(slave)
+			}
+		default:
+			disks = append(disks, name)
+		}
+	}
+	walk(name)
+	sort.Strings(disks)
+	return disks
+}
+
+// describeStack renders the layers between a device and its disks, e.g. "vg0-data (lvm) on md0 (raid1) on nvme0n1p2 + nvme1n1p2"
+func (t *storageTopology) describeStack(name string) string {
+	dev, ok := t.devices[name]
+	if !ok {
+		return name
+	}
+	desc := name
+	switch dev.kind {
+	case "lvm", "crypt", "multipath", "dm":
+		desc = dev.label + " (" + dev.kind + ")"
+	case "md":
+		desc = name + " (" + dev.label + ")"
+	}
+	if len(dev.slaves) == 0 {
+		return desc
+	}
+	below := make([]string, 0, len(dev.slaves))
+	for _, slave := range dev.slaves {
+		below = append(below, t.describeStack(slave))
+	}
+	return desc + " on " + strings.Join(below, " + ")
+}
+
+// mountFor returns the mount that contains path (the longest matching mount point, latest mount wins)
+func (t *storageTopology) mountFor(path string) (mountEntry, bool) {
+	path = filepath.Clean(path)
+	var best mountEntry
+	found := false
+	for _, m := range t.mounts {
+		if path == m.mountPoint || m.mountPoint == "/" || strings.HasPrefix(path, m.mountPoint+"/") {
+			if !found || len(m.mountPoint) >= len(best.mountPoint) {
+				best, found = m, true
+			}
+		}
+	}
+	return best, found
+}
+
+// mountDevices returns the block devices backing a mount. btrfs and ZFS report an anonymous
+// device number, so their members come from /sys/fs/btrfs and zpool instead.
+func (t *storageTopology) mountDevices(m mountEntry) []string {
+	switch m.fsType {
+	case "zfs":
+		pool, _, _ := strings.Cut(m.source, "/")
+		return t.zfsPools[pool]
+	case "btrfs":
+		name := t.deviceName(m.source)
+		if name == "" {
+			return nil
+		}
+		filesystems, _ := os.ReadDir("/sys/fs/btrfs")
+		for _, fs := range filesystems {
+			members := readDirNames(filepath.Join("/sys/fs/btrfs", fs.Name(), "devices"))
+			for _, member := range members {
+				if member == name {
+					return members
+				}
+			}
+		}
+		return []string{name}
+	}
+	if name, ok := t.byDevNum[m.devNum]; ok {
+		return []string{name}
+	}
+	if strings.HasPrefix(m.source, "/dev/") {
+		if name := t.deviceName(m.source); name != "" {
+			return []string{name}
+		}
+	}
+	return nil
+}
+
+// mountDisks returns the physical disks under a mount
+func (t *storageTopology) mountDisks(m mountEntry) []string {
+	seen := make(map[string]bool)
+	var disks []string
+	for _, name := range t.mountDevices(m) {
+		for _, disk := range t.physicalDisks(name) {
+			if !seen[disk] {
+				seen[disk] = true
+				disks = append(disks, disk)
+			}
+		}
+	}
+	sort.Strings(disks)
+	return disks
+}
+
+// mountStack describes the storage under a mount, e.g. "ext4 on vg0-data (lvm) on sda2"
+func (t *storageTopology) mountStack(m mountEntry) string {
+	devices := t.mountDevices(m)
+	if len(devices) == 0 {
+		return m.fsType + " on " + m.source
+	}
+	layers := make([]string, 0, len(devices))
+	for _, name := range devices {
+		layers = append(layers, t.describeStack(name))
+	}
+	prefix := m.fsType
+	if m.fsType == "zfs" {
+		prefix = "zfs " + m.source
+	}
+	return prefix + " on " + strings.Join(layers, " + ")
+}
+
+// targetDisks returns the physical disks behind a benchmark target: a mounted directory, or a device
+// node for the "direct" and "raw" targets
+func (t *storageTopology) targetDisks(mountPoint, devicePath string) []string {
+	if mountPoint == "direct" || mountPoint == "raw" {
+		if name := t.deviceName(devicePath); name != "" {
+			return t.physicalDisks(name)
+		}
+		return nil
+	}
+	if m, ok := t.mountFor(mountPoint); ok {
+		return t.mountDisks(m)
+	}
+	return nil
+}
+
+// stackedOn returns the device, its partitions and everything built on top of them
+func (t *storageTopology) stackedOn(name string) map[string]bool {
+	above := make(map[string]bool)
+	var walk func(string)
+	walk = func(name string) {
+		dev, ok := t.devices[name]
+		if !ok || above[name] {
+			return
+		}
+		above[name] = true
+		for _, partition := range dev.partitions {
+			walk(partition)
+		}
+		for _, holder := range dev.holders {
+			walk(holder)
+		}
+	}
+	walk(name)
+	return above
+}
+
+// deviceUsers lists everything that uses a device or anything stacked on it: holders (LVM, LUKS, md),
+// mounts (including btrfs and ZFS members), ZFS pools and swap. An empty list means it is unused.
+func (t *storageTopology) deviceUsers(name string) []string {
+	above := t.stackedOn(name)
+	var users []string
+	for member := range above {
+		dev := t.devices[member]
+		for _, holder := range dev.holders {
+			users = append(users, "held by "+t.describeStack(holder))
+		}
+	}
+	for _, m := range t.mounts {
+		for _, device := range t.mountDevices(m) {
+			if above[device] {
+				users = append(users, "mounted at "+m.mountPoint+" ("+m.fsType+")")
+				break
+			}
+		}
+	}
+	for pool, members := range t.zfsPools {
+		for _, member := range members {
+			if above[member] {
+				users = append(users, "member of ZFS pool "+pool)
+				break
+			}
+		}
+	}
+	for _, swap := range t.swaps {
+		if above[swap] {
+			users = append(users, "used as swap ("+swap+")")
+		}
+	}
+	sort.Strings(users)
+	return dedupeStrings(users)
+}
+
+// dedupeStrings removes adjacent duplicates from a sorted slice
+func dedupeStrings(values []string) []string {
+	var out []string
+	for i, v := range values {
+		if i == 0 || v != values[i-1] {
+			out = append(out, v)
+		}
+	}
+	return out
+}
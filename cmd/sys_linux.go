@@ -0,0 +1,114 @@
+//go:build linux
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// mpolBind is MPOL_BIND from linux/mempolicy.h
+const mpolBind = 2
+
+// blkDiscard is BLKDISCARD (_IO(0x12, 119)) from linux/fs.h
+const blkDiscard = 0x1277
+
+// maxMaskBits is the size of the CPU and node masks passed to the kernel
+const maxMaskBits = 1024
+
+// mmapAnonymous maps a private anonymous region so its placement can be controlled before first touch
+func mmapAnonymous(size int) ([]byte, error) {
+	return syscall.Mmap(-1, 0, size, syscall.PROT_READ|syscall.PROT_WRITE, syscall.MAP_PRIVATE|syscall.MAP_ANONYMOUS)
+}
+
+// munmapBuffer releases a region returned by mmapAnonymous
+func munmapBuffer(b []byte) error {
+	return syscall.Munmap(b)
+}
+
+// mbindNode binds the pages of b to a single NUMA node. It must be called before the pages are touched.
+func mbindNode(b []byte, node int) error {
+	if len(b) == 0 {
+		return fmt.Errorf("empty buffer")
+	}
+	if node < 0 || node >= maxMaskBits {
+		return fmt.Errorf("node %d out of range", node)
+	}
+	var mask [maxMaskBits / 64]uint64
+	mask[node/64] |= 1 << (uint(node) % 64)
+	_, _, errno := syscall.Syscall6(syscall.SYS_MBIND,
+		uintptr(unsafe.Pointer(&b[0])), uintptr(len(b)),
+		mpolBind, uintptr(unsafe.Pointer(&mask[0])), maxMaskBits, 0)
+	if errno != 0 {
+		return fmt.Errorf("mbind: %w", errno)
+	}
+	return nil
+}
+
+// setThreadAffinity pins the calling OS thread to the given CPUs.
+// The caller must hold runtime.LockOSThread for the pinning to be meaningful.
+func setThreadAffinity(cpus []int) error {
+	var set [maxMaskBits / 64]uint64
+	for _, cpu := range cpus {
+		if cpu >= 0 && cpu < maxMaskBits {
+			set[cpu/64] |= 1 << (uint(cpu) % 64)
+		}
+	}
+	_, _, errno := syscall.RawSyscall(syscall.SYS_SCHED_SETAFFINITY, 0, uintptr(len(set)*8), uintptr(unsafe.Pointer(&set[0])))
+	if errno != 0 {
+		return fmt.Errorf("sched_setaffinity: %w", errno)
+	}
+	return nil
+}
+
+// mmapHugeTLB maps an anonymous region backed by pre-reserved hugetlb pages.
+// size must be a multiple of the default huge page size.
+func mmapHugeTLB(size int) ([]byte, error) {
+	return syscall.Mmap(-1, 0, size, syscall.PROT_READ|syscall.PROT_WRITE, syscall.MAP_PRIVATE|syscall.MAP_ANONYMOUS|syscall.MAP_HUGETLB)
+}
+
+// madviseTHP enables or disables transparent huge pages for the region
+func madviseTHP(b []byte, enable bool) error {
+	advice := syscall.MADV_NOHUGEPAGE
+	if enable {
+		advice = syscall.MADV_HUGEPAGE
+	}
+	return syscall.Madvise(b, advice)
+}
+
+// mlockBuffer locks the region into RAM so the memory test checks physical memory rather than swap
+func mlockBuffer(b []byte) error {
+	return syscall.Mlock(b)
+}
+
+// filesystemSpace returns the total and available bytes of the filesystem containing path
+func filesystemSpace(path string) (total, available uint64, err error) {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(path, &st); err != nil {
+		return 0, 0, err
+	}
+	return st.Blocks * uint64(st.Bsize), st.Bavail * uint64(st.Bsize), nil
+}
+
+// dropPageCache writes back dirty pages and drops the page cache, dentries and inodes (requires root)
+func dropPageCache() error {
+	syscall.Sync()
+	return os.WriteFile("/proc/sys/vm/drop_caches", []byte("3"), 0)
+}
+
+// openDirect opens a file or block device with O_DIRECT, bypassing the page cache
+func openDirect(path string, flag int, perm os.FileMode) (*os.File, error) {
+	return os.OpenFile(path, flag|syscall.O_DIRECT, perm)
+}
+
+// discardRange discards length bytes at offset of a block device opened for writing, like blkdiscard
+func discardRange(file *os.File, offset, length uint64) error {
+	r := [2]uint64{offset, length}
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, file.Fd(), blkDiscard, uintptr(unsafe.Pointer(&r[0])))
+	if errno != 0 {
+		return fmt.Errorf("BLKDISCARD: %w", errno)
+	}
+	return nil
+}
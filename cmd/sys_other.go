@@ -0,0 +1,63 @@
+//go:build !linux
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+)
+
+// mmapAnonymous falls back to a regular Go allocation on non-Linux systems
+func mmapAnonymous(size int) ([]byte, error) {
+	return make([]byte, size), nil
+}
+
+// munmapBuffer is a no-op for buffers allocated by the fallback mmapAnonymous
+func munmapBuffer(b []byte) error {
+	return nil
+}
+
+// mbindNode is only supported on Linux
+func mbindNode(b []byte, node int) error {
+	return fmt.Errorf("mbind is not supported on this platform")
+}
+
+// setThreadAffinity is only supported on Linux
+func setThreadAffinity(cpus []int) error {
+	return fmt.Errorf("sched_setaffinity is not supported on this platform")
+}
+
+// mmapHugeTLB is only supported on Linux
+func mmapHugeTLB(size int) ([]byte, error) {
+	return nil, fmt.Errorf("hugetlb pages are not supported on this platform")
+}
+
+// madviseTHP is only supported on Linux
+func madviseTHP(b []byte, enable bool) error {
+	return fmt.Errorf("transparent huge pages are not supported on this platform")
+}
+
+// mlockBuffer is only supported on Linux
+func mlockBuffer(b []byte) error {
+	return fmt.Errorf("mlock is not supported on this platform")
+}
+
+// filesystemSpace is only supported on Linux
+func filesystemSpace(path string) (total, available uint64, err error) {
+	return 0, 0, fmt.Errorf("statfs is not supported on this platform")
+}
+
+// dropPageCache is only supported on Linux
+func dropPageCache() error {
+	return fmt.Errorf("dropping the page cache is not supported on this platform")
+}
+
+// openDirect is only supported on Linux
+func openDirect(path string, flag int, perm os.FileMode) (*os.File, error) {
+	return nil, fmt.Errorf("O_DIRECT is not supported on this platform")
+}
+
+// discardRange is only supported on Linux
+func discardRange(file *os.File, offset, length uint64) error {
+	return fmt.Errorf("BLKDISCARD is not supported on this platform")
+}
@@ -280,6 +280,11 @@ const indexTemplate = `<!DOCTYPE html>
             color: #2ecc71;
             font-weight: bold;
         }
+        .error-banner {
+            border: 2px solid #c0392b;
+            background-color: #fdecea;
+            color: #c0392b;
+        }
         .footer {
             text-align: center;
             margin-top: 30px;
@@ -319,6 +324,15 @@ const indexTemplate = `<!DOCTYPE html>
         <p>Date: {{.TestDate}}</p>
         <p>Hostname: {{.Hostname}}</p>
     </div>
+{{if .HardwareErrors.ErrorsDetected}}
+    <div class="section error-banner">
+        <h2>Hardware Errors Detected During This Run</h2>
+        <p>EDAC: +{{.HardwareErrors.NewCorrected}} corrected, +{{.HardwareErrors.NewUncorrected}} uncorrected. Machine checks: +{{.HardwareErrors.NewMCEs}}.</p>
+        {{range .HardwareErrors.EDACDIMMDeltas}}<p>{{.}}</p>{{end}}
+        {{if .HardwareErrors.KernelLogErrors}}<pre>{{range .HardwareErrors.KernelLogErrors}}{{.}}
+{{end}}</pre>{{end}}
+    </div>
+{{end}}
 
     <div class="tabs">
         <div class="tab active" onclick="openTab(event, 'system')">System Info</div>
@@ -2,7 +2,10 @@ module github.com/hypr-technologies/hypr-bench
 
 go 1.22 // Changed from 1.24.0 to 1.22
 
-require github.com/spf13/cobra v1.8.1
+require (
+	github.com/spf13/cobra v1.8.1
+	gopkg.in/yaml.v3 v3.0.1
+)
 
 require (
 	github.com/inconshreveable/mousetrap v1.1.0 // indirect